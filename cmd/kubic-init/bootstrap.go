@@ -0,0 +1,616 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	kubiccluster "github.com/kubic-project/kubic-init/pkg/cluster"
+	"github.com/kubic-project/kubic-init/pkg/cni"
+	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/kubeadm"
+	"github.com/kubic-project/kubic-init/pkg/lb"
+	"github.com/kubic-project/kubic-init/pkg/loader"
+	"github.com/kubic-project/kubic-init/pkg/manager"
+	"github.com/kubic-project/kubic-init/pkg/phases"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+)
+
+func init() {
+	// forget about the bootstrap progress when resetting a node
+	reset.Register(reset.Step{
+		Name:        "bootstrap-state",
+		Description: "forget the bootstrap progress",
+		Paths: func(cfg *kubiccfg.KubicInitConfiguration) []string {
+			return append(recordedStateFiles(), kubiccfg.DefaultKubicBootstrapStateFile,
+				kubiccfg.DefaultKubicBootstrapStateFilesRecord)
+		},
+	})
+}
+
+// recordedStateFiles returns the custom state files used in previous bootstraps
+func recordedStateFiles() []string {
+	contents, err := ioutil.ReadFile(kubiccfg.DefaultKubicBootstrapStateFilesRecord)
+	if err != nil {
+		return []string{}
+	}
+	return strings.Fields(string(contents))
+}
+
+// recordStateFile records a custom state file, so it is removed by "kubic-init reset"
+func recordStateFile(stateFile string) error {
+	if stateFile == kubiccfg.DefaultKubicBootstrapStateFile {
+		return nil
+	}
+	stateFile, err := filepath.Abs(stateFile)
+	if err != nil {
+		return err
+	}
+
+	recorded := sets.NewString(recordedStateFiles()...)
+	if recorded.Has(stateFile) {
+		return nil
+	}
+	recorded.Insert(stateFile)
+
+	record := kubiccfg.DefaultKubicBootstrapStateFilesRecord
+	if err := os.MkdirAll(filepath.Dir(record), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(record, []byte(strings.Join(recorded.List(), "\n")+"\n"), 0644)
+}
+
+// bootstrapper contains everything needed by the bootstrap phases
+type bootstrapper struct {
+	kubicCfg *kubiccfg.KubicInitConfiguration
+	out      io.Writer
+	dryRun   bool
+
+	postControlManifDir string
+	crdsDir             string
+	rbacDir             string
+
+	// the seeder discovery (only used when no seeder has been provided)
+	discoveryStateFile string
+	discoveryTimeout   time.Duration
+
+	// clients for the API server (only available in the seeder), and the
+	// recorder for the changes performed in dry-run mode
+	clients  *kubicclient.Clients
+	recorder *kubicclient.DryRunRecorder
+}
+
+// getClients returns the clients for the API server, creating them on the first use
+// Note well: we cannot create them before "kubeadm init", and that phase could have
+// been completed in a previous run
+func (b *bootstrapper) getClients() (*kubicclient.Clients, error) {
+	if b.clients != nil {
+		return b.clients, nil
+	}
+
+	if b.dryRun {
+		b.recorder = &kubicclient.DryRunRecorder{}
+		b.clients = kubicclient.NewDryRunClients(b.recorder)
+		return b.clients, nil
+	}
+
+	var err error
+	b.clients, err = kubicclient.NewClientsFromKubeconfig(kubeadmconstants.GetAdminKubeConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	return b.clients, nil
+}
+
+// discoverSeeder finds the seeder when it has not been provided in the configuration,
+// reusing the result of a previous discovery when the bootstrap is resumed. When this
+// node is the seeder and the "mdns" provider is used, it returns the mDNS responder
+// that announces it (and that must be kept running).
+func (b *bootstrapper) discoverSeeder() (*kubiccluster.MDNSResponder, error) {
+	if !b.kubicCfg.HasDiscovery() {
+		return nil, nil
+	}
+	discoveryCfg := &b.kubicCfg.ClusterFormation.Discovery
+
+	bindIP, err := b.kubicCfg.GetBindIP()
+	if err != nil {
+		return nil, err
+	}
+	id := bindIP.String()
+	address := net.JoinHostPort(id, fmt.Sprintf("%d", kubiccfg.DefaultAPIServerPort))
+
+	var responder *kubiccluster.MDNSResponder
+	if sets.NewString(discoveryCfg.Providers...).Has(kubiccfg.DiscoveryProviderMDNS) {
+		responder, err = kubiccluster.NewMDNSResponder(nil)
+		if err != nil {
+			return nil, err
+		}
+		go responder.Serve()
+	}
+
+	seeder, found, err := kubiccluster.LoadDiscoveredSeeder(b.discoveryStateFile)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		glog.V(1).Infof("[kubic] using the seeder found in a previous discovery")
+	} else {
+		var election *kubiccluster.SeederElection
+		if discoveryCfg.Election {
+			election = kubiccluster.NewSeederElection(responder, id, address)
+		}
+		discovery, err := kubiccluster.NewSeederDiscovery(discoveryCfg, election, b.discoveryTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		glog.V(1).Infof("[kubic] looking for the seeder with %s", strings.Join(discoveryCfg.Providers, ", "))
+		seeder, err = discovery.Discover()
+		if err != nil {
+			return nil, err
+		}
+		if !b.dryRun {
+			if err := kubiccluster.SaveDiscoveredSeeder(b.discoveryStateFile, seeder); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := b.kubicCfg.SetSeeder(seeder); err != nil {
+		return nil, err
+	}
+
+	if !b.kubicCfg.IsSeeder() {
+		if responder != nil {
+			responder.Close()
+		}
+		return nil, nil
+	}
+	if responder != nil {
+		responder.AnnounceSeeder(id, address)
+	}
+	return responder, nil
+}
+
+// seederPhases returns the phases for bootstrapping the seeder
+func (b *bootstrapper) seederPhases() []phases.Phase {
+	return []phases.Phase{
+		b.loadBalancerPhase(),
+		{
+			Name: "kubeadm",
+			Run: func() error {
+				glog.V(1).Infoln("[kubic] seeding the cluster from this node")
+				if b.dryRun {
+					return kubeadm.NewInit(b.kubicCfg, "--dry-run")
+				}
+				return kubeadm.NewInit(b.kubicCfg)
+			},
+		},
+		{
+			Name: "upload-config",
+			Run: func() error {
+				clients, err := b.getClients()
+				if err != nil {
+					return err
+				}
+
+				// upload the seeder configuration to a ConfigMap
+				extraLabels := map[string]string{
+					"kubic-seeder-version": fmt.Sprintf("%s", Version),
+					"kubic-seeder-build":   fmt.Sprintf("%s", Build),
+				}
+				return b.kubicCfg.ToConfigMap(clients.Kubernetes, kubiccfg.DefaultKubicInitConfigmap, extraLabels)
+			},
+		},
+		{
+			Name: "upload-certs",
+			Run: func() error {
+				if len(b.kubicCfg.ClusterFormation.ControlPlaneSecret) == 0 {
+					glog.V(1).Infoln("[kubic] no control-plane secret: other masters will not be able to join this cluster")
+					return nil
+				}
+				if b.dryRun {
+					fmt.Fprintf(b.out, "[dry-run] would upload the control-plane certificates to %s\n", kubiccluster.ControlPlaneCertsSecretName)
+					return nil
+				}
+
+				clients, err := b.getClients()
+				if err != nil {
+					return err
+				}
+
+				glog.V(1).Infoln("[kubic] uploading the control-plane certificates")
+				return kubiccluster.UploadControlPlaneCerts(clients.Kubernetes,
+					b.kubicCfg.Certificates.Directory, b.kubicCfg.ClusterFormation.ControlPlaneSecret)
+			},
+		},
+		{
+			Name: "approval-rbac",
+			Run: func() error {
+				if b.kubicCfg.ClusterFormation.AutoApprove {
+					glog.V(1).Infoln("[kubic] new nodes will be accepted automatically")
+					return nil
+				}
+
+				clients, err := b.getClients()
+				if err != nil {
+					return err
+				}
+
+				glog.V(1).Infoln("[kubic] removing the auto-approval rules for new nodes")
+				return kubiccluster.RemoveAutoApprovalRBAC(clients.Kubernetes)
+			},
+		},
+		{
+			Name: "cni",
+			Run: func() error {
+				clients, err := b.getClients()
+				if err != nil {
+					return err
+				}
+
+				glog.V(1).Infof("[kubic] deploying CNI DaemonSet with '%s' driver", b.kubicCfg.Network.Cni.Driver)
+				return cni.Deploy(b.kubicCfg, clients)
+			},
+		},
+		{
+			Name: "assets",
+			Run: func() error {
+				clients, err := b.getClients()
+				if err != nil {
+					return err
+				}
+
+				glog.V(1).Infof("[kubic] trying to load assets...")
+				return loader.InstallAllAssets(clients, b.kubicCfg, b.postControlManifDir, b.crdsDir, b.rbacDir)
+			},
+		},
+	}
+}
+
+// joinPhase returns the phase for joining the seeder with "kubeadm join"
+func (b *bootstrapper) joinPhase() phases.Phase {
+	return phases.Phase{
+		Name: "kubeadm",
+		Run: func() error {
+			glog.V(1).Infof("[kubic] joining the seeder at %s", b.kubicCfg.ClusterFormation.Seeder)
+			if b.dryRun {
+				// "kubeadm join" has no dry-run mode: just print the configuration
+				joinCfg, err := kubeadm.RenderJoinConfig(b.kubicCfg)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(b.out, "[dry-run] kubeadm join would be run with this configuration:\n%s\n", joinCfg)
+				return nil
+			}
+
+			// the seeder needs the address of this node when the approval policy has subnets
+			if err := kubiccluster.RegisterNode(b.kubicCfg); err != nil {
+				glog.V(1).Infof("[kubic] WARNING: could not register this node in the seeder: %v", err)
+			}
+
+			if err := kubeadm.NewJoin(b.kubicCfg); err != nil {
+				return err
+			}
+			glog.V(1).Infoln("[kubic] this node should have joined the cluster at this point")
+			return nil
+		},
+	}
+}
+
+// loadBalancerPhase returns the phase for starting the API servers load balancer in a master
+// Note well: it must run before kubeadm, as the load balancer is the control-plane endpoint
+func (b *bootstrapper) loadBalancerPhase() phases.Phase {
+	return phases.Phase{
+		Name: "load-balancer",
+		Run: func() error {
+			if !b.kubicCfg.HasLoadBalancer() {
+				return nil
+			}
+
+			// the initial backends are this node and the seeder: the list will be
+			// kept in sync with the control-plane nodes in the cluster by the manager
+			bindIP, err := b.kubicCfg.GetBindIP()
+			if err != nil {
+				return err
+			}
+			backends := []string{lb.APIServerBackend(bindIP.String())}
+			if !b.kubicCfg.IsSeeder() {
+				backends = append(backends, b.kubicCfg.ClusterFormation.Seeder)
+			}
+
+			if b.dryRun {
+				fmt.Fprintf(b.out, "[dry-run] would start the load balancer at %s, with backends %v\n",
+					b.kubicCfg.GetControlPlaneEndpoint(), backends)
+				return nil
+			}
+
+			glog.V(1).Infof("[kubic] starting the load balancer at %s", b.kubicCfg.GetControlPlaneEndpoint())
+			_, err = lb.Sync(b.kubicCfg, backends)
+			return err
+		},
+	}
+}
+
+// nodePhases returns the phases for bootstrapping a regular node
+func (b *bootstrapper) nodePhases() []phases.Phase {
+	return []phases.Phase{
+		b.joinPhase(),
+	}
+}
+
+// masterPhases returns the phases for bootstrapping an additional master
+func (b *bootstrapper) masterPhases() []phases.Phase {
+	return []phases.Phase{
+		{
+			Name: "download-certs",
+			Run: func() error {
+				certsDir := b.kubicCfg.Certificates.Directory
+				if b.dryRun {
+					fmt.Fprintf(b.out, "[dry-run] would download the control-plane certificates to %s\n", certsDir)
+					return nil
+				}
+
+				client, err := kubiccluster.NewSeederClient(b.kubicCfg)
+				if err != nil {
+					return err
+				}
+
+				caHashes, err := b.kubicCfg.GetCACertHashes()
+				if err != nil {
+					return err
+				}
+
+				glog.V(1).Infof("[kubic] downloading the control-plane certificates from %s", b.kubicCfg.ClusterFormation.Seeder)
+				return kubiccluster.DownloadControlPlaneCerts(client, certsDir,
+					b.kubicCfg.ClusterFormation.ControlPlaneSecret, caHashes)
+			},
+		},
+		b.loadBalancerPhase(),
+		// the stacked etcd member is added by kubeadm, with the advertise address
+		b.joinPhase(),
+	}
+}
+
+// phasesForNode returns the phases for bootstrapping this node, depending on its role
+func (b *bootstrapper) phasesForNode() []phases.Phase {
+	switch {
+	case b.kubicCfg.IsSeeder():
+		return b.seederPhases()
+	case b.kubicCfg.IsMaster():
+		return b.masterPhases()
+	default:
+		return b.nodePhases()
+	}
+}
+
+// reconcileCNI installs the CNI driver, using the CNI configuration uploaded to the cluster
+// (the driver can be changed with "kubic-init cni migrate" after the bootstrap)
+func (b *bootstrapper) reconcileCNI(clients *kubicclient.Clients) error {
+	cfg := b.kubicCfg.DeepCopy()
+	clusterCfg, err := kubiccfg.FromConfigMap(clients.Kubernetes, kubiccfg.DefaultKubicInitConfigmap)
+	if err != nil {
+		glog.V(1).Infof("[kubic] WARNING: could not read the cluster configuration (using the local configuration): %s", err)
+	} else {
+		cfg.Network.Cni = clusterCfg.Network.Cni
+	}
+	return cni.Deploy(cfg, clients)
+}
+
+// filterPhases checks all the `names` are valid bootstrap phases, returning
+// only the phases that exist in this node (ie, regular nodes have no "cni" phase)
+func (b *bootstrapper) filterPhases(names []string, runner *phases.Runner) ([]string, error) {
+	all := phases.NewRunner("", b.seederPhases()...).Names()
+	for _, name := range phases.NewRunner("", b.masterPhases()...).Names() {
+		if !sets.NewString(all...).Has(name) {
+			all = append(all, name)
+		}
+	}
+	known := sets.NewString(all...)
+	inRunner := sets.NewString(runner.Names()...)
+
+	res := []string{}
+	for _, name := range names {
+		if !known.Has(name) {
+			return nil, fmt.Errorf("unknown phase %q: must be one of %s", name, strings.Join(all, ", "))
+		}
+		if inRunner.Has(name) {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+// newCmdBootstrap returns a "kubic-init bootstrap" command.
+func newCmdBootstrap(out io.Writer) *cobra.Command {
+	var kubicCfgFile string
+	var lenientCfg bool
+	var vars = []string{}
+
+	b := &bootstrapper{
+		out:                 out,
+		postControlManifDir: kubiccfg.DefaultKubicManifestsDir,
+		crdsDir:             kubiccfg.DefaultKubicCRDDir,
+		rbacDir:             kubiccfg.DefaultKubicRBACDir,
+		discoveryStateFile:  kubiccfg.DefaultKubicDiscoveryStateFile,
+		discoveryTimeout:    kubiccfg.DefaultDiscoveryTimeout,
+	}
+
+	loadAssets := true
+	block := true
+	deployCNI := true
+
+	stateFile := kubiccfg.DefaultKubicBootstrapStateFile
+	skipPhases := []string{}
+	onlyPhases := []string{}
+
+	managerOptions := manager.Options{
+		HealthAddress:     kubiccfg.DefaultManagerHealthAddress,
+		ReconcileInterval: kubiccfg.DefaultManagerReconcileInterval,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Bootstrap the node, either as a seeder or as a regular node depending on the 'seed' config argument.",
+		Long: `Bootstrap the node, either as a seeder or as a regular node depending on the 'seed' config argument.
+
+When no seeder is provided but some discovery providers are configured, the seeder
+is discovered (or elected among the nodes started) before the bootstrap.
+
+The bootstrap is performed in phases ("load-balancer", "kubeadm", "upload-config",
+"upload-certs", "approval-rbac", "cni" and "assets" in the seeder, "download-certs",
+"load-balancer" and "kubeadm" in additional masters, and just "kubeadm"
+in regular nodes). The phases completed
+are saved in a --state-file, so a failed bootstrap will be resumed from the first phase
+that was not completed. Use "kubic-init reset" for starting from scratch (it also
+removes the custom --state-files used before).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+
+			glog.V(1).Infof("[kubic] version: %s", Version)
+			glog.V(1).Infof("[kubic] build:   %s", Build)
+			glog.V(1).Infof("[kubic] date:    %s", BuildDate)
+			glog.V(1).Infof("[kubic] branch:  %s", Branch)
+			glog.V(1).Infof("[kubic] go:      %s", GoVersion)
+
+			b.kubicCfg, err = kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(kubicCfgFile, lenientCfg)
+			kubeadmutil.CheckErr(err)
+
+			err = b.kubicCfg.SetVars(vars)
+			kubeadmutil.CheckErr(err)
+
+			err = b.kubicCfg.Validate().ToAggregate()
+			kubeadmutil.CheckErr(err)
+
+			responder, err := b.discoverSeeder()
+			kubeadmutil.CheckErr(err)
+			if responder != nil {
+				defer responder.Close()
+			}
+
+			runner := phases.NewRunner(stateFile, b.phasesForNode()...)
+
+			if !deployCNI {
+				glog.V(1).Infof("[kubic] WARNING: CNI will not be deployed")
+				skipPhases = append(skipPhases, "cni")
+			}
+			if !loadAssets {
+				glog.V(1).Infof("[kubic] WARNING: not trying to load assets")
+				skipPhases = append(skipPhases, "assets")
+			}
+			runner.Skip, err = b.filterPhases(skipPhases, runner)
+			kubeadmutil.CheckErr(err)
+			runner.Only, err = b.filterPhases(onlyPhases, runner)
+			kubeadmutil.CheckErr(err)
+
+			if b.dryRun {
+				glog.V(1).Infoln("[kubic] dry-run mode: no changes will be performed")
+				runner.StateFile = ""
+			} else {
+				err = recordStateFile(stateFile)
+				kubeadmutil.CheckErr(err)
+			}
+
+			if len(onlyPhases) > 0 && len(runner.Only) == 0 {
+				glog.V(1).Infof("[kubic] none of the phases requested can be run in this node")
+			} else {
+				err = runner.Run()
+				kubeadmutil.CheckErr(err)
+			}
+
+			if b.dryRun {
+				if b.recorder != nil {
+					b.recorder.Print(out)
+				}
+				return
+			}
+
+			if block {
+				glog.V(1).Infoln("[kubic] control plane ready... starting the manager")
+				var clients *kubicclient.Clients
+				if b.kubicCfg.IsSeeder() {
+					clients, err = b.getClients()
+					kubeadmutil.CheckErr(err)
+				}
+
+				mgr := manager.New(b.kubicCfg, clients, managerOptions)
+				if b.kubicCfg.IsMaster() && b.kubicCfg.HasLoadBalancer() {
+					lbClients, err := kubicclient.NewClientsFromKubeconfig(kubeadmconstants.GetAdminKubeConfigPath())
+					kubeadmutil.CheckErr(err)
+					mgr.AddReconciler("load-balancer", func() error {
+						return lb.Reconcile(b.kubicCfg, lbClients.Kubernetes)
+					})
+				}
+				if clients != nil {
+					if deployCNI {
+						mgr.AddReconciler("cni", func() error {
+							return b.reconcileCNI(clients)
+						})
+					}
+					if loadAssets {
+						mgr.AddReconciler("assets", func() error {
+							return loader.InstallAllAssets(clients, b.kubicCfg, b.postControlManifDir, b.crdsDir, b.rbacDir)
+						})
+					}
+				}
+
+				err = mgr.Run(manager.SetupSignalHandler())
+				kubeadmutil.CheckErr(err)
+			}
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubicCfgFile, "config", "", "path to kubic-init config file.")
+	flagSet.BoolVar(&lenientCfg, "lenient-config", false, "ignore unknown keys in the config file.")
+	flagSet.BoolVar(&block, "block", block, "run the manager after boostrapping")
+	flagSet.StringVar(&managerOptions.HealthAddress, "health-addr", managerOptions.HealthAddress, "address for the manager health (/healthz) and readiness (/readyz) endpoints")
+	flagSet.DurationVar(&managerOptions.ReconcileInterval, "reconcile-interval", managerOptions.ReconcileInterval, "interval between reconciliations of the CNI and assets in the manager")
+	flagSet.StringSliceVar(&vars, "var", []string{}, "set a configuration variable (ie, Network.Cni.Driver=cilium")
+	flagSet.BoolVar(&deployCNI, "deploy-cni", deployCNI, "deploy the CNI driver")
+	flagSet.BoolVar(&b.dryRun, "dry-run", false, "do not change anything: just print what would be done")
+
+	// phases
+	flagSet.StringVar(&stateFile, "state-file", stateFile, "file where the bootstrap progress is saved.")
+	flagSet.StringSliceVar(&skipPhases, "skip-phases", skipPhases, "do not run these bootstrap phases.")
+	flagSet.StringSliceVar(&onlyPhases, "only-phases", onlyPhases, "run only these bootstrap phases (even if they were completed).")
+
+	// seeder discovery
+	flagSet.DurationVar(&b.discoveryTimeout, "discovery-timeout", b.discoveryTimeout, "maximum time waiting for a seeder to be discovered (or elected).")
+
+	// assets
+	flagSet.BoolVar(&loadAssets, "load-assets", loadAssets, "load the CRDs, RBACs and manifests")
+	flagSet.StringVar(&b.crdsDir, "crds-dir", b.crdsDir, "load CRDs from this directory.")
+	flagSet.StringVar(&b.rbacDir, "rbac-dir", b.rbacDir, "load RBACs from this directory.")
+	flagSet.StringVar(&b.postControlManifDir, "manif-dir", b.postControlManifDir, "load manifests from this directory.")
+
+	return cmd
+}
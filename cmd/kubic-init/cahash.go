@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+
+	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/crypto"
+)
+
+// newCmdCaHash returns the "kubic-init ca-hash" command
+func newCmdCaHash(out io.Writer) *cobra.Command {
+	var caCrt string
+
+	cmd := &cobra.Command{
+		Use:   "ca-hash",
+		Short: "Print the hash(es) of the CA certificate, to be used as `certificates.caCrtHashes` when joining.",
+		Run: func(cmd *cobra.Command, args []string) {
+			contents, err := crypto.LoadCACert(caCrt)
+			kubeadmutil.CheckErr(err)
+
+			hashes, err := crypto.CACertHashes(contents)
+			kubeadmutil.CheckErr(err)
+
+			for _, hash := range hashes {
+				fmt.Fprintln(out, hash)
+			}
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&caCrt, "ca-crt", filepath.Join(kubiccfg.DefaultCertsDirectory, kubeadmconstants.CACertName),
+		"Path to the CA certificate.")
+
+	return cmd
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/cni"
+)
+
+// newCmdCni returns the "kubic-init cni" command
+func newCmdCni(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cni",
+		Short: "Manage the CNI driver in a running cluster.",
+	}
+
+	cmd.AddCommand(newCmdCniMigrate(out))
+
+	return cmd
+}
+
+// newCmdCniMigrate returns the "kubic-init cni migrate" command
+func newCmdCniMigrate(out io.Writer) *cobra.Command {
+	kubeconfigPath := kubeadmconstants.GetAdminKubeConfigPath()
+	options := cni.MigrationOptions{
+		Timeout: cni.DefaultMigrationTimeout,
+		Out:     out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Switch a running cluster to a different CNI driver.",
+		Long: fmt.Sprintf(`Switch a running cluster to a different CNI driver.
+
+The current driver (as found in the configuration uploaded to the cluster) is uninstalled,
+the old CNI configuration files are removed from all the nodes, the new driver is installed
+and then the nodes are rolled one by one (cordon, restart the pods, uncordon).
+
+The migration is aborted if the new driver is not healthy after --timeout.
+Use --dry-run for printing the steps without performing any change.
+
+Registered drivers: %s.`, strings.Join(cni.Registry.Names(), ", ")),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(options.To) == 0 {
+				kubeadmutil.CheckErr(fmt.Errorf("no driver provided with --to"))
+			}
+			_, err := cni.Registry.Get(options.To)
+			kubeadmutil.CheckErr(err)
+
+			clients, err := kubicclient.NewClientsFromKubeconfig(kubeconfigPath)
+			kubeadmutil.CheckErr(err)
+
+			err = cni.Migrate(clients, options)
+			kubeadmutil.CheckErr(err)
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&options.To, "to", "", "The new CNI driver.")
+	flagSet.StringVar(&options.Image, "image", "", "The image for the new CNI driver (default: the default image for the driver).")
+	flagSet.BoolVar(&options.DryRun, "dry-run", false, "Do not change anything: just print what would be done.")
+	flagSet.DurationVar(&options.Timeout, "timeout", options.Timeout, "Max time to wait for the new driver to be healthy.")
+	flagSet.StringVar(&kubeconfigPath, "kubeconfig", kubeconfigPath, "The kubeconfig file to use when talking to the cluster.")
+
+	return cmd
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+
+	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/kubeadm"
+)
+
+// newCmdConfig returns the "kubic-init config" command
+func newCmdConfig(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the kubic-init configuration.",
+	}
+
+	cmd.AddCommand(newCmdConfigValidate(out))
+	cmd.AddCommand(newCmdConfigMigrate(out))
+	cmd.AddCommand(newCmdConfigRenderKubeadm(out))
+
+	return cmd
+}
+
+// newCmdConfigValidate returns the "kubic-init config validate" command
+func newCmdConfigValidate(out io.Writer) *cobra.Command {
+	var kubicCfgFile string
+	var lenientCfg bool
+	var vars = []string{}
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the kubic-init configuration, printing all the errors found.",
+		Run: func(cmd *cobra.Command, args []string) {
+			kubicCfg, err := kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(kubicCfgFile, lenientCfg)
+			kubeadmutil.CheckErr(err)
+
+			err = kubicCfg.SetVars(vars)
+			kubeadmutil.CheckErr(err)
+
+			errs := kubicCfg.Validate()
+			for _, e := range errs {
+				fmt.Fprintf(out, "%s\n", e)
+			}
+			if len(errs) > 0 {
+				kubeadmutil.CheckErr(fmt.Errorf("%d error(s) found in the configuration", len(errs)))
+			}
+
+			fmt.Fprintln(out, "configuration is valid")
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubicCfgFile, "config", "", "Path to kubic-init config file.")
+	flagSet.BoolVar(&lenientCfg, "lenient-config", false, "Ignore unknown keys in the config file.")
+	flagSet.StringSliceVar(&vars, "var", []string{}, "Set a configuration variable (ie, Network.Cni.Driver=cilium")
+
+	return cmd
+}
+
+// newCmdConfigMigrate returns the "kubic-init config migrate" command
+func newCmdConfigMigrate(out io.Writer) *cobra.Command {
+	var kubicCfgFile string
+	var outputFile string
+	var lenientCfg bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite a kubic-init configuration file with the latest configuration version.",
+		Long: fmt.Sprintf(`Rewrite a kubic-init configuration file with the latest configuration version (%s).
+
+The file is rewritten in place (keeping a copy in a ".bak" file) unless an --output
+file is provided. Use "--output -" for printing the new configuration to stdout.`,
+			kubiccfg.LatestVersion),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(kubicCfgFile) == 0 {
+				kubeadmutil.CheckErr(fmt.Errorf("no configuration file provided with --config"))
+			}
+
+			b, err := ioutil.ReadFile(kubicCfgFile)
+			kubeadmutil.CheckErr(err)
+
+			// note well: do not use ConfigFileAndDefaultsToKubicInitConfig(), as it
+			// would get some values from the environment
+			kubicCfg, err := kubiccfg.BytesToKubicInitConfig(b, lenientCfg)
+			kubeadmutil.CheckErr(err)
+
+			migrated, err := kubiccfg.MarshalKubicInitConfig(kubicCfg)
+			kubeadmutil.CheckErr(err)
+
+			switch outputFile {
+			case "-":
+				out.Write(migrated)
+				return
+			case "":
+				backup := kubicCfgFile + ".bak"
+				err = ioutil.WriteFile(backup, b, 0644)
+				kubeadmutil.CheckErr(err)
+				fmt.Fprintf(out, "previous configuration saved in %s\n", backup)
+				outputFile = kubicCfgFile
+			}
+
+			mode := os.FileMode(0644)
+			if info, err := os.Stat(kubicCfgFile); err == nil {
+				mode = info.Mode()
+			}
+			err = ioutil.WriteFile(outputFile, migrated, mode)
+			kubeadmutil.CheckErr(err)
+
+			fmt.Fprintf(out, "configuration migrated to %s and saved in %s\n", kubiccfg.LatestVersion, outputFile)
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubicCfgFile, "config", "", "Path to kubic-init config file.")
+	flagSet.StringVar(&outputFile, "output", "", "Write the new configuration to this file (default: rewrite the config file).")
+	flagSet.BoolVar(&lenientCfg, "lenient-config", false, "Ignore (and drop) unknown keys in the config file.")
+
+	return cmd
+}
+
+// newCmdConfigRenderKubeadm returns the "kubic-init config render-kubeadm" command
+func newCmdConfigRenderKubeadm(out io.Writer) *cobra.Command {
+	var kubicCfgFile string
+	var outputFile string
+	var lenientCfg bool
+	var role string
+	var vars = []string{}
+
+	cmd := &cobra.Command{
+		Use:   "render-kubeadm",
+		Short: "Print the kubeadm configuration that would be generated for this node, without running kubeadm.",
+		Long: `Print the kubeadm configuration that would be generated for this node, without running kubeadm.
+
+The InitConfiguration and ClusterConfiguration are printed for the seeder, and
+a JoinConfiguration for any other node. Use --role for forcing one of them.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			kubicCfg, err := kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(kubicCfgFile, lenientCfg)
+			kubeadmutil.CheckErr(err)
+
+			err = kubicCfg.SetVars(vars)
+			kubeadmutil.CheckErr(err)
+
+			err = kubicCfg.Validate().ToAggregate()
+			kubeadmutil.CheckErr(err)
+
+			if len(role) == 0 {
+				role = "join"
+				if kubicCfg.IsSeeder() {
+					role = "init"
+				}
+			}
+
+			var rendered []byte
+			switch role {
+			case "init":
+				rendered, err = kubeadm.RenderInitConfig(kubicCfg)
+			case "join":
+				rendered, err = kubeadm.RenderJoinConfig(kubicCfg)
+			default:
+				err = fmt.Errorf("unknown role %q: must be \"init\" or \"join\"", role)
+			}
+			kubeadmutil.CheckErr(err)
+
+			if len(outputFile) == 0 || outputFile == "-" {
+				out.Write(rendered)
+				return
+			}
+
+			err = ioutil.WriteFile(outputFile, rendered, 0600)
+			kubeadmutil.CheckErr(err)
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubicCfgFile, "config", "", "Path to kubic-init config file.")
+	flagSet.BoolVar(&lenientCfg, "lenient-config", false, "Ignore unknown keys in the config file.")
+	flagSet.StringSliceVar(&vars, "var", []string{}, "Set a configuration variable (ie, Network.Cni.Driver=cilium")
+	flagSet.StringVar(&outputFile, "output", "", "Write the kubeadm configuration to this file (default: stdout).")
+	flagSet.StringVar(&role, "role", "", "Render the configuration for \"init\" or \"join\" (default: \"init\" for the seeder, \"join\" otherwise).")
+
+	return cmd
+}
@@ -21,25 +21,21 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"time"
+	"strings"
 
-	"github.com/golang/glog"
 	"github.com/renstrom/dedent"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	utilflag "k8s.io/apiserver/pkg/util/flag"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
-	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
 	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
-	kubeconfigutil "k8s.io/kubernetes/cmd/kubeadm/app/util/kubeconfig"
 
-	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
-	kubiccluster "github.com/kubic-project/kubic-init/pkg/cluster"
-	"github.com/kubic-project/kubic-init/pkg/cni"
+	_ "github.com/kubic-project/kubic-init/pkg/cni/calico"
+	_ "github.com/kubic-project/kubic-init/pkg/cni/cilium"
 	_ "github.com/kubic-project/kubic-init/pkg/cni/flannel"
+	_ "github.com/kubic-project/kubic-init/pkg/cni/multus"
 	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
-	"github.com/kubic-project/kubic-init/pkg/kubeadm"
-	"github.com/kubic-project/kubic-init/pkg/loader"
+	"github.com/kubic-project/kubic-init/pkg/reset"
 )
 
 // to be set from the build process
@@ -49,147 +45,81 @@ var BuildDate string
 var Branch string
 var GoVersion string
 
-// newCmdBootstrap returns a "kubic-init bootstrap" command.
-func newCmdBootstrap(out io.Writer) *cobra.Command {
-	kubicCfg := &kubiccfg.KubicInitConfiguration{}
-
-	var kubicCfgFile string
-	var vars = []string{}
-
-	var postControlManifDir = kubiccfg.DefaultKubicManifestsDir
-	var crdsDir = kubiccfg.DefaultKubicCRDDir
-	var rbacDir = kubiccfg.DefaultKubicRBACDir
-
-	loadAssets := true
-	block := true
-	deployCNI := true
-
-	cmd := &cobra.Command{
-		Use:   "bootstrap",
-		Short: "Bootstrap the node, either as a seeder or as a regular node depending on the 'seed' config argument.",
-		Run: func(cmd *cobra.Command, args []string) {
-			var err error
-
-			glog.V(1).Infof("[kubic] version: %s", Version)
-			glog.V(1).Infof("[kubic] build:   %s", Build)
-			glog.V(1).Infof("[kubic] date:    %s", BuildDate)
-			glog.V(1).Infof("[kubic] branch:  %s", Branch)
-			glog.V(1).Infof("[kubic] go:      %s", GoVersion)
-
-			kubicCfg, err = kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(kubicCfgFile)
-			kubeadmutil.CheckErr(err)
-
-			err = kubicCfg.SetVars(vars)
-			kubeadmutil.CheckErr(err)
-
-			if !kubicCfg.IsSeeder() {
-				glog.V(1).Infof("[kubic] joining the seeder at %s", kubicCfg.ClusterFormation.Seeder)
-				err := kubeadm.NewJoin(kubicCfg)
-				kubeadmutil.CheckErr(err)
-				glog.V(1).Infoln("[kubic] this node should have joined the cluster at this point")
-			} else {
-				glog.V(1).Infoln("[kubic] seeding the cluster from this node")
-				err := kubeadm.NewInit(kubicCfg)
-				kubeadmutil.CheckErr(err)
-
-				// create a kubernetes client
-				// create a connection to the API server and wait for it to come up
-				client, err := kubeconfigutil.ClientSetFromFile(kubeadmconstants.GetAdminKubeConfigPath())
-				kubeadmutil.CheckErr(err)
-
-				// upload the seeder configuration to a ConfigMap
-				extraLabels := map[string]string{
-					"kubic-seeder-version": fmt.Sprintf("%s", Version),
-					"kubic-seeder-build":   fmt.Sprintf("%s", Build),
-				}
-				err = kubicCfg.ToConfigMap(client, kubiccfg.DefaultKubicInitConfigmap, extraLabels)
-				kubeadmutil.CheckErr(err)
-
-				if !kubicCfg.ClusterFormation.AutoApprove {
-					glog.V(1).Infoln("[kubic] removing the auto-approval rules for new nodes")
-					err = kubiccluster.RemoveAutoApprovalRBAC(client)
-					kubeadmutil.CheckErr(err)
-				} else {
-					glog.V(1).Infoln("[kubic] new nodes will be accepted automatically")
-				}
-
-				if deployCNI {
-					glog.V(1).Infof("[kubic] deploying CNI DaemonSet with '%s' driver", kubicCfg.Network.Cni.Driver)
-					err = cni.Registry.Load(kubicCfg.Network.Cni.Driver, kubicCfg, client)
-					kubeadmutil.CheckErr(err)
-				} else {
-					glog.V(1).Infof("[kubic] WARNING: CNI will not be deployed")
-				}
-
-				if loadAssets {
-					kubeconfig, err := kubicclient.GetConfig()
-					kubeadmutil.CheckErr(err)
-
-					glog.V(1).Infof("[kubic] trying to load assets...")
-					err = loader.InstallAllAssets(kubeconfig, kubicCfg, postControlManifDir, crdsDir, rbacDir)
-					kubeadmutil.CheckErr(err)
-				} else {
-					glog.V(1).Infof("[kubic] WARNING: not trying to load assets")
-				}
-			}
-
-			if block {
-				glog.V(1).Infoln("[kubic] control plane ready... looping forever")
-				for {
-					time.Sleep(time.Second)
-				}
-			}
-		},
-	}
-
-	flagSet := cmd.PersistentFlags()
-	flagSet.StringVar(&kubicCfgFile, "config", "", "path to kubic-init config file.")
-	flagSet.BoolVar(&block, "block", block, "block after boostrapping")
-	flagSet.StringSliceVar(&vars, "var", []string{}, "set a configuration variable (ie, Network.Cni.Driver=cilium")
-	flagSet.BoolVar(&deployCNI, "deploy-cni", deployCNI, "deploy the CNI driver")
-
-	// assets
-	flagSet.BoolVar(&loadAssets, "load-assets", loadAssets, "load the CRDs, RBACs and manifests")
-	flagSet.StringVar(&crdsDir, "crds-dir", crdsDir, "load CRDs from this directory.")
-	flagSet.StringVar(&rbacDir, "rbac-dir", rbacDir, "load RBACs from this directory.")
-	flagSet.StringVar(&postControlManifDir, "manif-dir", postControlManifDir, "load manifests from this directory.")
-
-	return cmd
-}
-
 // newCmdReset returns the "kubic-init reset" command
 func newCmdReset(in io.Reader, out io.Writer) *cobra.Command {
 	kubicCfg := &kubiccfg.KubicInitConfiguration{}
 
 	var kubicCfgFile string
+	var lenientCfg bool
 	var vars = []string{}
+	options := reset.Options{
+		Out: out,
+	}
 
 	cmd := &cobra.Command{
 		Use:   "reset",
 		Short: "Run this to revert any changes made to this host by kubic-init.",
+		Long: fmt.Sprintf(`Run this to revert any changes made to this host by kubic-init.
+
+"kubeadm reset" is run and then the cleanup steps registered by the CNI drivers
+and the kubic-init subsystems that apply to the configuration. Use --keep for
+skipping some steps (ie, --keep=etcd-data for preserving the etcd data), --include
+for running some opt-in steps (ie, --include=iptables) and --dry-run for printing
+the steps without performing any change.
+
+The configuration is not validated, so nodes with an invalid configuration can
+also be reset (when it cannot be loaded, the defaults are used).
+
+Registered steps: %s.
+Opt-in steps: %s.`, strings.Join(reset.Names(), ", "), strings.Join(reset.OptInNames(), ", ")),
 		Run: func(cmd *cobra.Command, args []string) {
-			var err error
+			kubicCfg = loadResetConfig(kubicCfgFile, lenientCfg, out)
 
-			kubicCfg, err = kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(kubicCfgFile)
+			err := kubicCfg.SetVars(vars)
 			kubeadmutil.CheckErr(err)
 
-			err = kubicCfg.SetVars(vars)
-			kubeadmutil.CheckErr(err)
+			// a node must be resettable even with a broken (or outdated) configuration
+			if err := kubicCfg.Validate().ToAggregate(); err != nil {
+				fmt.Fprintf(out, "[reset] WARNING: invalid configuration (ignored): %v\n", err)
+			}
 
-			err = kubeadm.NewReset(kubicCfg)
+			err = reset.Run(kubicCfg, options)
 			kubeadmutil.CheckErr(err)
-
-			// TODO: perform any kubic-specific cleanups here
 		},
 	}
 
 	flagSet := cmd.PersistentFlags()
 	flagSet.StringVar(&kubicCfgFile, "config", "", "Path to kubic-init config file.")
+	flagSet.BoolVar(&lenientCfg, "lenient-config", false, "Ignore unknown keys in the config file.")
 	flagSet.StringSliceVar(&vars, "var", []string{}, "Set a configuration variable (ie, Network.Cni.Driver=cilium")
+	flagSet.StringSliceVar(&options.Keep, "keep", []string{}, "Cleanup steps that will not be run (ie, etcd-data).")
+	flagSet.StringSliceVar(&options.Include, "include", []string{}, "Opt-in cleanup steps that will be run (ie, iptables).")
+	flagSet.BoolVar(&options.DryRun, "dry-run", false, "Do not change anything: just print what would be done.")
 
 	return cmd
 }
 
+// loadResetConfig loads the configuration for "kubic-init reset", ignoring the unknown
+// keys when the configuration cannot be loaded otherwise, and using the defaults
+// (with a warning) when it cannot be loaded at all
+func loadResetConfig(cfgFile string, lenient bool, out io.Writer) *kubiccfg.KubicInitConfiguration {
+	kubicCfg, err := kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(cfgFile, lenient)
+	if err == nil {
+		return kubicCfg
+	}
+	if !lenient {
+		if kubicCfg, lenientErr := kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(cfgFile, true); lenientErr == nil {
+			fmt.Fprintf(out, "[reset] WARNING: ignoring the unknown keys in the configuration: %v\n", err)
+			return kubicCfg
+		}
+	}
+
+	fmt.Fprintf(out, "[reset] WARNING: could not load the configuration (using the defaults): %v\n", err)
+	kubicCfg, err = kubiccfg.ConfigFileAndDefaultsToKubicInitConfig("", true)
+	kubeadmutil.CheckErr(err)
+	return kubicCfg
+}
+
 func newCmdVersion(out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "version",
@@ -225,6 +155,11 @@ func main() {
 	cmds.ResetFlags()
 	cmds.AddCommand(newCmdBootstrap(os.Stdout))
 	cmds.AddCommand(newCmdReset(os.Stdin, os.Stdout))
+	cmds.AddCommand(newCmdConfig(os.Stdout))
+	cmds.AddCommand(newCmdCni(os.Stdout))
+	cmds.AddCommand(newCmdCaHash(os.Stdout))
+	cmds.AddCommand(newCmdNode(os.Stdout))
+	cmds.AddCommand(newCmdUpgrade(os.Stdout))
 	cmds.AddCommand(newCmdVersion(os.Stdout))
 
 	err := cmds.Execute()
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	kubiccluster "github.com/kubic-project/kubic-init/pkg/cluster"
+	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// newCmdNode returns the "kubic-init node" command
+func newCmdNode(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Manage the nodes in a running cluster.",
+	}
+
+	cmd.AddCommand(newCmdNodeListPending(out))
+	cmd.AddCommand(newCmdNodeApprove(out))
+	cmd.AddCommand(newCmdNodeReject(out))
+	cmd.AddCommand(newCmdNodeRemove(out))
+
+	return cmd
+}
+
+// newCmdNodeListPending returns the "kubic-init node list-pending" command
+func newCmdNodeListPending(out io.Writer) *cobra.Command {
+	kubeconfigPath := kubeadmconstants.GetAdminKubeConfigPath()
+
+	cmd := &cobra.Command{
+		Use:   "list-pending",
+		Short: "List the nodes waiting for approval for joining the cluster.",
+		Run: func(cmd *cobra.Command, args []string) {
+			clients, err := kubicclient.NewClientsFromKubeconfig(kubeconfigPath)
+			kubeadmutil.CheckErr(err)
+
+			pending, err := kubiccluster.GetPendingNodeCSRs(clients.Kubernetes)
+			kubeadmutil.CheckErr(err)
+
+			w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+			fmt.Fprintln(w, "NODE\tIP\tTOKEN ID\tREQUEST\tAGE")
+			for _, p := range pending {
+				ip := p.IP
+				if len(ip) == 0 {
+					ip = "<unknown>"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.NodeName, ip, p.TokenID, p.Name,
+					duration.HumanDuration(time.Since(p.Created)))
+			}
+			w.Flush()
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubeconfigPath, "kubeconfig", kubeconfigPath, "The kubeconfig file to use when talking to the cluster.")
+
+	return cmd
+}
+
+// newCmdNodeApprove returns the "kubic-init node approve" command
+func newCmdNodeApprove(out io.Writer) *cobra.Command {
+	kubeconfigPath := kubeadmconstants.GetAdminKubeConfigPath()
+
+	cmd := &cobra.Command{
+		Use:   "approve <node>",
+		Short: "Approve a node waiting for joining the cluster.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clients, err := kubicclient.NewClientsFromKubeconfig(kubeconfigPath)
+			kubeadmutil.CheckErr(err)
+
+			err = kubiccluster.ApproveNode(clients.Kubernetes, args[0], "approved with kubic-init")
+			kubeadmutil.CheckErr(err)
+
+			fmt.Fprintf(out, "node %q approved\n", args[0])
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubeconfigPath, "kubeconfig", kubeconfigPath, "The kubeconfig file to use when talking to the cluster.")
+
+	return cmd
+}
+
+// newCmdNodeReject returns the "kubic-init node reject" command
+func newCmdNodeReject(out io.Writer) *cobra.Command {
+	kubeconfigPath := kubeadmconstants.GetAdminKubeConfigPath()
+
+	cmd := &cobra.Command{
+		Use:   "reject <node>",
+		Short: "Reject a node waiting for joining the cluster.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clients, err := kubicclient.NewClientsFromKubeconfig(kubeconfigPath)
+			kubeadmutil.CheckErr(err)
+
+			err = kubiccluster.RejectNode(clients.Kubernetes, args[0], "rejected with kubic-init")
+			kubeadmutil.CheckErr(err)
+
+			fmt.Fprintf(out, "node %q rejected\n", args[0])
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubeconfigPath, "kubeconfig", kubeconfigPath, "The kubeconfig file to use when talking to the cluster.")
+
+	return cmd
+}
+
+// newCmdNodeRemove returns the "kubic-init node remove" command
+func newCmdNodeRemove(out io.Writer) *cobra.Command {
+	kubeconfigPath := kubeadmconstants.GetAdminKubeConfigPath()
+	options := kubiccluster.RemovalOptions{
+		Timeout: kubiccluster.DefaultRemovalTimeout,
+		Image:   kubiccfg.DefaultKubicInitImage,
+		Out:     out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "remove <node>",
+		Short: "Remove a node from a running cluster.",
+		Long: fmt.Sprintf(`Remove a node from a running cluster.
+
+The node is cordoned and drained (honoring the PodDisruptionBudgets), its etcd member
+is removed (for control-plane nodes) and then the Node is deleted. With --reset, the
+%s service is started in the node (with a Job) before deleting it,
+reverting the changes made by kubic-init. The reset runs in the background in
+the node: this command does not wait for it to finish.
+
+This command must be run in a control-plane node that is not the node being removed.
+Use --dry-run for printing the steps without performing any change.`, kubiccluster.ResetServiceName),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if hostname, err := os.Hostname(); err == nil && strings.ToLower(hostname) == args[0] {
+				kubeadmutil.CheckErr(fmt.Errorf("%s cannot be removed from itself: run this command in another control-plane node", args[0]))
+			}
+
+			clients, err := kubicclient.NewClientsFromKubeconfig(kubeconfigPath)
+			kubeadmutil.CheckErr(err)
+
+			err = kubiccluster.RemoveNode(clients, args[0], options)
+			kubeadmutil.CheckErr(err)
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.BoolVar(&options.Reset, "reset", false, "Reset the node after draining it.")
+	flagSet.StringVar(&options.Image, "reset-image", options.Image, "The image used for starting the reset service in the node.")
+	flagSet.BoolVar(&options.DryRun, "dry-run", false, "Do not change anything: just print what would be done.")
+	flagSet.DurationVar(&options.Timeout, "timeout", options.Timeout, "Max time to wait for draining the node and for resetting it.")
+	flagSet.StringVar(&kubeconfigPath, "kubeconfig", kubeconfigPath, "The kubeconfig file to use when talking to the cluster.")
+
+	return cmd
+}
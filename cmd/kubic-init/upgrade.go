@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/upgrade"
+)
+
+// newCmdUpgrade returns the "kubic-init upgrade" command
+func newCmdUpgrade(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade a running cluster to a new Kubernetes version.",
+	}
+
+	cmd.AddCommand(newCmdUpgradePlan(out))
+	cmd.AddCommand(newCmdUpgradeApply(out))
+	cmd.AddCommand(newCmdUpgradeNode(out))
+
+	return cmd
+}
+
+// newCmdUpgradePlan returns the "kubic-init upgrade plan" command
+func newCmdUpgradePlan(out io.Writer) *cobra.Command {
+	kubeconfigPath := kubeadmconstants.GetAdminKubeConfigPath()
+	var version string
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the current versions in the cluster and the versions it can be upgraded to.",
+		Long: `Show the current versions in the cluster and the versions it can be upgraded to.
+
+With --version, check the cluster can be upgraded to that version following the
+version skew policy (one minor version at a time for the control plane, and kubelets
+at most two minor versions behind the control plane).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clients, err := kubicclient.NewClientsFromKubeconfig(kubeconfigPath)
+			kubeadmutil.CheckErr(err)
+
+			err = upgrade.Plan(clients, version, out)
+			kubeadmutil.CheckErr(err)
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&version, "version", "", "The Kubernetes version to check (ie, v1.13.1).")
+	flagSet.StringVar(&kubeconfigPath, "kubeconfig", kubeconfigPath, "The kubeconfig file to use when talking to the cluster.")
+
+	return cmd
+}
+
+// newCmdUpgradeApply returns the "kubic-init upgrade apply" command
+func newCmdUpgradeApply(out io.Writer) *cobra.Command {
+	kubeconfigPath := kubeadmconstants.GetAdminKubeConfigPath()
+	options := upgrade.Options{
+		Timeout: upgrade.DefaultUpgradeTimeout,
+		Image:   kubiccfg.DefaultKubicInitImage,
+		Out:     out,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Upgrade a running cluster to a new Kubernetes version.",
+		Long: fmt.Sprintf(`Upgrade a running cluster to a new Kubernetes version.
+
+The control plane is upgraded in this node with "kubeadm upgrade apply" and then
+the other nodes are upgraded one by one, the masters before the workers: the node is
+cordoned and drained, "kubeadm upgrade node" is run in the node with a Job (using the
+--image, that must contain the new kubeadm), the kubelet is restarted and the node
+is uncordoned.
+
+This command must be run in the seeder. The progress is saved in %s,
+so an interrupted upgrade is resumed when run again.
+Use --dry-run for printing the steps without performing any change.`, upgrade.StateFile("<version>")),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(options.Version) == 0 {
+				kubeadmutil.CheckErr(fmt.Errorf("no version provided with --version"))
+			}
+
+			clients, err := kubicclient.NewClientsFromKubeconfig(kubeconfigPath)
+			kubeadmutil.CheckErr(err)
+
+			err = upgrade.Apply(clients, options)
+			kubeadmutil.CheckErr(err)
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&options.Version, "version", "", "The Kubernetes version to upgrade to (ie, v1.13.1).")
+	flagSet.StringVar(&options.Image, "image", options.Image, "The image used for upgrading the nodes.")
+	flagSet.BoolVar(&options.DryRun, "dry-run", false, "Do not change anything: just print what would be done.")
+	flagSet.DurationVar(&options.Timeout, "timeout", options.Timeout, "Max time for each step of the upgrade.")
+	flagSet.StringVar(&kubeconfigPath, "kubeconfig", kubeconfigPath, "The kubeconfig file to use when talking to the cluster.")
+
+	return cmd
+}
+
+// newCmdUpgradeNode returns the "kubic-init upgrade node" command
+// (run in the nodes by "kubic-init upgrade apply")
+func newCmdUpgradeNode(out io.Writer) *cobra.Command {
+	var kubicCfgFile string
+	var version string
+	var controlPlane bool
+
+	cmd := &cobra.Command{
+		Use:    "node",
+		Short:  "Upgrade this node to a new Kubernetes version (run by 'upgrade apply').",
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(version) == 0 {
+				kubeadmutil.CheckErr(fmt.Errorf("no version provided with --version"))
+			}
+
+			kubicCfg, err := kubiccfg.ConfigFileAndDefaultsToKubicInitConfig(kubicCfgFile, true)
+			kubeadmutil.CheckErr(err)
+
+			if !cmd.Flags().Changed("control-plane") {
+				controlPlane = kubicCfg.IsMaster()
+			}
+
+			err = upgrade.UpgradeLocalNode(kubicCfg, version, controlPlane)
+			kubeadmutil.CheckErr(err)
+
+			fmt.Fprintf(out, "node upgraded to %s\n", version)
+		},
+	}
+
+	flagSet := cmd.PersistentFlags()
+	flagSet.StringVar(&kubicCfgFile, "config", "", "Path to kubic-init config file.")
+	flagSet.StringVar(&version, "version", "", "The Kubernetes version to upgrade to (ie, v1.13.1).")
+	flagSet.BoolVar(&controlPlane, "control-plane", false, "Upgrade the control plane in this node (by default, when it is a master in the config file).")
+
+	return cmd
+}
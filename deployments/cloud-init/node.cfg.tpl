@@ -28,7 +28,7 @@ write_files:
     permissions: "0644"
     owner: "root"
     content: |
-      apiVersion: kubic.suse.com/v1alpha1
+      apiVersion: kubic.suse.com/v1alpha3
       kind: KubicInitConfiguration
       clusterFormation:
         seeder: ${seeder}
@@ -39,11 +39,9 @@ write_files:
     permissions: "0644"
     owner: "root"
     content: |
-      apiVersion: kubic.suse.com/v1alpha1
+      apiVersion: kubic.suse.com/v1alpha3
       kind: KubicInitConfiguration
       clusterFormation:
         token: ${token}
-      manager:
-        image: ${kubic_init_image_name}
 
 final_message: "The system is finally up, after $UPTIME seconds"
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package client
+
+import (
+	"fmt"
+
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// the ConfigMap (in the kube-public namespace) where kubeadm publishes the cluster CA
+	clusterInfoConfigMap = "cluster-info"
+
+	// the key in the cluster-info ConfigMap with the kubeconfig
+	clusterInfoKubeconfigKey = "kubeconfig"
+)
+
+// Clients is the set of clients used for talking to the API server
+type Clients struct {
+	// Kubernetes is the client for all the builtin types
+	Kubernetes clientset.Interface
+
+	// APIExtensions is the client for the CRDs
+	APIExtensions apiextensionsclientset.Interface
+
+	// Dynamic is the client for objects that are only known at runtime (ie, manifests)
+	Dynamic dynamic.Interface
+
+	// Config is the config used for creating the clients (nil in dry-run mode)
+	Config *rest.Config
+
+	// DryRun is true when the clients do not talk to a real API server
+	DryRun bool
+}
+
+// NewClientsForConfig creates all the clients for a config
+func NewClientsForConfig(config *rest.Config) (*Clients, error) {
+	kubernetesClient, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create client: %s", err)
+	}
+	apiExtensionsClient, err := apiextensionsclientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create API extensions client: %s", err)
+	}
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create dynamic client: %s", err)
+	}
+
+	return &Clients{
+		Kubernetes:    kubernetesClient,
+		APIExtensions: apiExtensionsClient,
+		Dynamic:       dynClient,
+		Config:        config,
+	}, nil
+}
+
+// NewClientsFromKubeconfig creates all the clients from a kubeconfig file
+// (ie, the admin kubeconfig generated by kubeadm)
+func NewClientsFromKubeconfig(kubeconfigPath string) (*Clients, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load kubeconfig %s: %s", kubeconfigPath, err)
+	}
+	return NewClientsForConfig(config)
+}
+
+// NewBootstrapTokenClient creates a client for the API server at `server` (ie, "seeder:6443")
+// authenticated with a bootstrap token. It can be used by nodes that have not joined
+// the cluster yet. The identity of the API server is verified with the `caCert`.
+// Note well: when no `caCert` is provided the identity of the API server is not verified,
+// so the data obtained with this client must be verified (or encrypted) by other means.
+func NewBootstrapTokenClient(server, token string, caCert []byte) (clientset.Interface, error) {
+	config := &rest.Config{
+		Host:        "https://" + server,
+		BearerToken: token,
+	}
+	if len(caCert) > 0 {
+		config.TLSClientConfig.CAData = caCert
+	} else {
+		config.TLSClientConfig.Insecure = true
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create bootstrap client: %s", err)
+	}
+	return client, nil
+}
+
+// GetClusterCA gets the CA certificate published by the API server at `server` in the
+// "cluster-info" ConfigMap (readable by anonymous users). The connection is neither
+// verified nor authenticated, so the certificate must be checked (ie, with some CA pins)
+// before trusting it.
+func GetClusterCA(server string) ([]byte, error) {
+	config := &rest.Config{
+		Host: "https://" + server,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure: true,
+		},
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create anonymous client: %s", err)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(clusterInfoConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the %s ConfigMap from %s: %s", clusterInfoConfigMap, server, err)
+	}
+	kubeconfig, err := clientcmd.Load([]byte(cm.Data[clusterInfoKubeconfigKey]))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the kubeconfig in %s: %s", clusterInfoConfigMap, err)
+	}
+	for _, cluster := range kubeconfig.Clusters {
+		if len(cluster.CertificateAuthorityData) > 0 {
+			return cluster.CertificateAuthorityData, nil
+		}
+	}
+	return nil, fmt.Errorf("no CA certificate found in the %s ConfigMap", clusterInfoConfigMap)
+}
+
+// RESTMapping gets the resource for a kind
+// Note well: the discovery information is not cached, as new resources can be
+// added (ie, with CRDs) while we are loading things.
+func (c *Clients) RESTMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	if c.DryRun {
+		// there is no discovery information available: just guess the resource
+		plural, _ := meta.UnsafeGuessKindToResource(gvk)
+		return &meta.RESTMapping{
+			Resource:         plural,
+			GroupVersionKind: gvk,
+			Scope:            meta.RESTScopeNamespace,
+		}, nil
+	}
+
+	groupResources, err := restmapper.GetAPIGroupResources(c.Kubernetes.Discovery())
+	if err != nil {
+		return nil, fmt.Errorf("could not get API group resources: %s", err)
+	}
+
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+	return mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// DryRunAction is a change that would be performed in the API server
+type DryRunAction struct {
+	Verb      string
+	Resource  string
+	Namespace string
+	Name      string
+}
+
+func (a DryRunAction) String() string {
+	name := a.Name
+	if len(a.Namespace) > 0 {
+		name = a.Namespace + "/" + a.Name
+	}
+	return fmt.Sprintf("%s %s %s", a.Verb, a.Resource, name)
+}
+
+// DryRunRecorder records (in order) all the changes performed with the dry-run clients
+type DryRunRecorder struct {
+	sync.Mutex
+
+	Actions []DryRunAction
+}
+
+// NewDryRunClients returns a set of fake clients, recording all the
+// changes (creations, updates and deletions) in the recorder
+func NewDryRunClients(recorder *DryRunRecorder) *Clients {
+	kubernetesClient := clientsetfake.NewSimpleClientset()
+	kubernetesClient.PrependReactor("*", "*", recorder.react)
+
+	apiExtensionsClient := apiextensionsfake.NewSimpleClientset()
+	apiExtensionsClient.PrependReactor("*", "*", recorder.react)
+
+	dynClient := dynamicfake.NewSimpleDynamicClient(clientsetscheme.Scheme)
+	dynClient.PrependReactor("*", "*", recorder.react)
+
+	return &Clients{
+		Kubernetes:    kubernetesClient,
+		APIExtensions: apiExtensionsClient,
+		Dynamic:       dynClient,
+		DryRun:        true,
+	}
+}
+
+// react records an action. Only deletions are considered as "handled" (as the
+// object will not exist): everything else is passed to the fake object tracker.
+// Note well: we must check the verb, as some Action interfaces (ie, Create and
+// Update, or Get and Delete) have the same methods.
+func (recorder *DryRunRecorder) react(action clienttesting.Action) (bool, runtime.Object, error) {
+	name := ""
+	switch action.GetVerb() {
+	case "create", "update":
+		if a, ok := action.(clienttesting.CreateAction); ok {
+			if accessor, err := meta.Accessor(a.GetObject()); err == nil {
+				name = accessor.GetName()
+			}
+		}
+	case "patch":
+		if a, ok := action.(clienttesting.PatchAction); ok {
+			name = a.GetName()
+		}
+	case "delete":
+		if a, ok := action.(clienttesting.DeleteAction); ok {
+			name = a.GetName()
+		}
+	default:
+		return false, nil, nil
+	}
+
+	recorder.Lock()
+	defer recorder.Unlock()
+	recorder.Actions = append(recorder.Actions, DryRunAction{
+		Verb:      action.GetVerb(),
+		Resource:  action.GetResource().GroupResource().String(),
+		Namespace: action.GetNamespace(),
+		Name:      name,
+	})
+
+	return action.GetVerb() == "delete", nil, nil
+}
+
+// Print prints the ordered list of changes recorded
+func (recorder *DryRunRecorder) Print(out io.Writer) {
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	if len(recorder.Actions) == 0 {
+		fmt.Fprintln(out, "[dry-run] no changes would be performed in the cluster")
+		return
+	}
+
+	fmt.Fprintln(out, "[dry-run] the following changes would be performed in the cluster:")
+	for i, action := range recorder.Actions {
+		fmt.Fprintf(out, "  %3d. %s\n", i+1, action)
+	}
+}
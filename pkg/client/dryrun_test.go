@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/apiclient"
+)
+
+func TestDryRunClients(t *testing.T) {
+	recorder := &DryRunRecorder{}
+	clients := NewDryRunClients(recorder)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-config",
+			Namespace: metav1.NamespaceSystem,
+		},
+	}
+	if err := apiclient.CreateOrUpdateConfigMap(clients.Kubernetes, cm); err != nil {
+		t.Fatalf("could not create ConfigMap: %v", err)
+	}
+
+	if err := clients.Kubernetes.RbacV1().ClusterRoleBindings().Delete("some-binding", &metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("deletion failed in dry-run mode: %v", err)
+	}
+
+	unstr := &unstructured.Unstructured{}
+	unstr.SetAPIVersion("apps/v1")
+	unstr.SetKind("Deployment")
+	unstr.SetName("some-deployment")
+	unstr.SetNamespace(metav1.NamespaceSystem)
+	if err := CreateOrUpdateFromUnstructured(clients, unstr); err != nil {
+		t.Fatalf("could not create Deployment: %v", err)
+	}
+
+	expected := []string{
+		"create configmaps kube-system/some-config",
+		"delete clusterrolebindings.rbac.authorization.k8s.io some-binding",
+		"create deployments.apps kube-system/some-deployment",
+	}
+	if len(recorder.Actions) != len(expected) {
+		t.Logf("actions: %v", recorder.Actions)
+		t.Fatalf("expected %d actions, got %d", len(expected), len(recorder.Actions))
+	}
+	for i, action := range recorder.Actions {
+		if action.String() != expected[i] {
+			t.Fatalf("expected %q, got %q", expected[i], action)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	recorder.Print(out)
+	if !strings.Contains(out.String(), "3. create deployments.apps kube-system/some-deployment") {
+		t.Logf("output:\n%s", out)
+		t.Fatalf("unexpected output")
+	}
+}
@@ -34,11 +34,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -89,26 +86,12 @@ func GetConfig() (*rest.Config, error) {
 	return nil, fmt.Errorf("could not locate a kubeconfig")
 }
 
-func CreateOrUpdateFromUnstructured(config *rest.Config, unstr *unstructured.Unstructured) error {
+func CreateOrUpdateFromUnstructured(clients *Clients, unstr *unstructured.Unstructured) error {
 	var err error
 	gvk := unstr.GetObjectKind().GroupVersionKind()
 	glog.V(3).Infof("[kubic] loading a %s...", gvk.Kind)
 
-	dynClient, err := dynamic.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("could not create dynamic client: %s", err)
-	}
-	discover, err := discovery.NewDiscoveryClientForConfig(config)
-	if err != nil {
-		return fmt.Errorf("could not create discovery client: %s", err)
-	}
-	groupResources, err := restmapper.GetAPIGroupResources(discover)
-	if err != nil {
-		return fmt.Errorf("could not get API group resources: %s", err)
-	}
-
-	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
-	restMapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	restMapping, err := clients.RESTMapping(gvk)
 	if err != nil {
 		return fmt.Errorf("could not get restMapping: %s", err)
 	}
@@ -123,7 +106,7 @@ func CreateOrUpdateFromUnstructured(config *rest.Config, unstr *unstructured.Uns
 		return fmt.Errorf("couldn't get namespace for unstr %s: %s", name, err)
 	}
 
-	rsc := dynClient.Resource(restMapping.Resource)
+	rsc := clients.Dynamic.Resource(restMapping.Resource)
 	if rsc == nil {
 		return fmt.Errorf("failed to get a resource interface")
 	}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// prefix for the users authenticated with a bootstrap token (followed by the token ID)
+	bootstrapUserPrefix = "system:bootstrap:"
+
+	// prefix for the common name in the node client certificates (followed by the node name)
+	nodeUserPrefix = "system:node:"
+
+	// organization in the node client certificates
+	nodesGroup = "system:nodes"
+)
+
+// the usages requested by the kubelets for their client certificates
+// (as checked by the upstream node-client approver)
+var nodeClientUsages = []certificatesv1beta1.KeyUsage{
+	certificatesv1beta1.UsageDigitalSignature,
+	certificatesv1beta1.UsageKeyEncipherment,
+	certificatesv1beta1.UsageClientAuth,
+}
+
+// Reasons used in the conditions of the certificate signing requests
+const (
+	NodeApprovedReason  = "KubicApproved"
+	NodeRejectedReason  = "KubicRejected"
+	NodeAllowlistReason = "KubicAllowlist"
+)
+
+// NodeCSR is a pending certificate signing request from a node joining the cluster
+type NodeCSR struct {
+	// Name is the name of the CertificateSigningRequest
+	Name string
+
+	// NodeName is the name of the node requesting the certificate
+	NodeName string
+
+	// TokenID is the ID of the bootstrap token used by the node
+	TokenID string
+
+	// IP is the IP address of the node (empty when it cannot be resolved)
+	IP string
+
+	// Created is the creation time of the request
+	Created time.Time
+}
+
+// parseNodeCSR returns the NodeCSR for a certificate signing request, or
+// nil if it is not a pending request for a client certificate from a joining node.
+// Requests with other usages or with any subject alternative name are not node
+// requests, so they are never approved by kubic-init.
+func parseNodeCSR(csr *certificatesv1beta1.CertificateSigningRequest) *NodeCSR {
+	if !isPendingCSR(csr) || !strings.HasPrefix(csr.Spec.Username, bootstrapUserPrefix) {
+		return nil
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil {
+		return nil
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil
+	}
+	if !isNodeClientCSR(csr, request) {
+		return nil
+	}
+
+	nodeName := strings.TrimPrefix(request.Subject.CommonName, nodeUserPrefix)
+	if len(nodeName) == 0 {
+		return nil
+	}
+	return &NodeCSR{
+		Name:     csr.Name,
+		NodeName: nodeName,
+		TokenID:  strings.TrimPrefix(csr.Spec.Username, bootstrapUserPrefix),
+		IP:       lookupNodeIP(nodeName),
+		Created:  csr.CreationTimestamp.Time,
+	}
+}
+
+// isNodeClientCSR returns true if a certificate signing request is for a node client
+// certificate: the node subject, no subject alternative names and exactly the kubelet usages
+func isNodeClientCSR(csr *certificatesv1beta1.CertificateSigningRequest, request *x509.CertificateRequest) bool {
+	if !strings.HasPrefix(request.Subject.CommonName, nodeUserPrefix) ||
+		len(request.Subject.Organization) != 1 || request.Subject.Organization[0] != nodesGroup {
+		return false
+	}
+	if len(request.DNSNames) > 0 || len(request.IPAddresses) > 0 ||
+		len(request.EmailAddresses) > 0 || len(request.URIs) > 0 {
+		return false
+	}
+	return hasExactUsages(csr, nodeClientUsages)
+}
+
+// hasExactUsages returns true if a certificate signing request has all the `usages` (and nothing else)
+func hasExactUsages(csr *certificatesv1beta1.CertificateSigningRequest, usages []certificatesv1beta1.KeyUsage) bool {
+	if len(csr.Spec.Usages) != len(usages) {
+		return false
+	}
+	requested := map[certificatesv1beta1.KeyUsage]bool{}
+	for _, usage := range csr.Spec.Usages {
+		requested[usage] = true
+	}
+	for _, usage := range usages {
+		if !requested[usage] {
+			return false
+		}
+	}
+	return true
+}
+
+// isPendingCSR returns true if a certificate signing request has not been approved or denied yet
+func isPendingCSR(csr *certificatesv1beta1.CertificateSigningRequest) bool {
+	for _, cond := range csr.Status.Conditions {
+		if cond.Type == certificatesv1beta1.CertificateApproved || cond.Type == certificatesv1beta1.CertificateDenied {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupNodeIP resolves the IP address of a node from its name
+// Note well: the node does not exist yet, so this is just a best effort
+func lookupNodeIP(nodeName string) string {
+	addrs, err := net.LookupHost(nodeName)
+	if err != nil || len(addrs) == 0 {
+		return ""
+	}
+	return addrs[0]
+}
+
+// GetPendingNodeCSRs returns the pending certificate signing requests from joining nodes,
+// sorted by creation time
+func GetPendingNodeCSRs(client clientset.Interface) ([]NodeCSR, error) {
+	csrs, err := client.CertificatesV1beta1().CertificateSigningRequests().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []NodeCSR{}
+	for i := range csrs.Items {
+		if nodeCSR := parseNodeCSR(&csrs.Items[i]); nodeCSR != nil {
+			pending = append(pending, *nodeCSR)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Created.Before(pending[j].Created)
+	})
+	return pending, nil
+}
+
+// ApproveNode approves all the pending certificate signing requests from a joining node
+func ApproveNode(client clientset.Interface, nodeName string, message string) error {
+	return decideNodeCSRs(client, nodeName, true, NodeApprovedReason, message)
+}
+
+// RejectNode denies all the pending certificate signing requests from a joining node
+func RejectNode(client clientset.Interface, nodeName string, message string) error {
+	return decideNodeCSRs(client, nodeName, false, NodeRejectedReason, message)
+}
+
+// decideNodeCSRs approves or denies all the pending certificate signing requests from a node
+func decideNodeCSRs(client clientset.Interface, nodeName string, approved bool, reason, message string) error {
+	csrs, err := client.CertificatesV1beta1().CertificateSigningRequests().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		nodeCSR := parseNodeCSR(csr)
+		if nodeCSR == nil || nodeCSR.NodeName != nodeName {
+			continue
+		}
+		if err := decideNodeCSR(client, csr, nodeCSR, approved, reason, message); err != nil {
+			return fmt.Errorf("could not update %s: %v", csr.Name, err)
+		}
+		updated++
+	}
+	if updated == 0 {
+		return fmt.Errorf("no pending requests found for node %q", nodeName)
+	}
+	return nil
+}
+
+// setCSRCondition approves or denies a certificate signing request
+func setCSRCondition(client clientset.Interface, csr *certificatesv1beta1.CertificateSigningRequest, condType certificatesv1beta1.RequestConditionType, reason, message string) error {
+	csr = csr.DeepCopy()
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+		Type:           condType,
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.Now(),
+	})
+	_, err := client.CertificatesV1beta1().CertificateSigningRequests().UpdateApproval(csr)
+	return err
+}
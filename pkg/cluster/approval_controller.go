@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/manager"
+)
+
+// name of the controller for approving new nodes
+const approvalControllerName = "node-approval"
+
+// time between full resyncs of the certificate signing requests (pending
+// requests are reported again on every resync)
+const approvalResyncPeriod = 5 * time.Minute
+
+// approvalController approves the certificate signing requests from joining
+// nodes when the auto-approval is disabled: nodes in the allowlist or that
+// satisfy the approval policy are approved automatically, and the rest must
+// be approved by the operator with "kubic-init node approve". All the
+// decisions are recorded as events for the certificate signing requests.
+type approvalController struct {
+	kubicCfg *config.KubicInitConfiguration
+	client   clientset.Interface
+	policy   *approvalPolicy
+
+	// the last decision recorded for each pending request (so
+	// we do not record the same event on every resync)
+	recorded map[string]string
+}
+
+func newApprovalController(kubicCfg *config.KubicInitConfiguration, clients *kubicclient.Clients) (manager.Controller, error) {
+	policy, err := newApprovalPolicy(&kubicCfg.ClusterFormation.Approval)
+	if err != nil {
+		return nil, err
+	}
+	return &approvalController{
+		kubicCfg: kubicCfg,
+		client:   clients.Kubernetes,
+		policy:   policy,
+		recorded: map[string]string{},
+	}, nil
+}
+
+func (c *approvalController) Name() string {
+	return approvalControllerName
+}
+
+func (c *approvalController) Run(stop <-chan struct{}) error {
+	if c.kubicCfg.ClusterFormation.AutoApprove {
+		glog.V(3).Infoln("[kubic] new nodes are approved automatically: nothing to do")
+		<-stop
+		return nil
+	}
+
+	// the subnets in the policy are checked against the addresses registered by the nodes
+	if len(c.policy.cidrs) > 0 {
+		server, err := c.serveRegistrations()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(ctx)
+		}()
+	}
+
+	lw := cache.NewListWatchFromClient(c.client.CertificatesV1beta1().RESTClient(),
+		"certificatesigningrequests", metav1.NamespaceAll, fields.Everything())
+	_, controller := cache.NewInformer(lw, &certificatesv1beta1.CertificateSigningRequest{}, approvalResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: c.handle,
+			UpdateFunc: func(_, obj interface{}) {
+				c.handle(obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				if csr, ok := obj.(*certificatesv1beta1.CertificateSigningRequest); ok {
+					delete(c.recorded, csr.Name)
+				}
+			},
+		})
+	controller.Run(stop)
+	return nil
+}
+
+// serveRegistrations starts serving the registrations of the joining nodes
+func (c *approvalController) serveRegistrations() (*http.Server, error) {
+	address := fmt.Sprintf(":%d", config.DefaultNodeRegistrationPort)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for the node registrations at %s: %v", address, err)
+	}
+
+	server := &http.Server{
+		Addr:    address,
+		Handler: newNodeRegistrationHandler(c.client),
+	}
+	go func() {
+		glog.V(1).Infof("[kubic] node registrations listening at %s", listener.Addr())
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			glog.V(1).Infof("[kubic] ERROR: node registrations failed: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+// handle processes a certificate signing request
+func (c *approvalController) handle(obj interface{}) {
+	csr, ok := obj.(*certificatesv1beta1.CertificateSigningRequest)
+	if !ok {
+		return
+	}
+	nodeCSR := parseNodeCSR(csr)
+	if nodeCSR == nil {
+		delete(c.recorded, csr.Name)
+		return
+	}
+
+	decision, err := c.policy.evaluate(c.client, nodeCSR, time.Now())
+	if err != nil {
+		glog.V(1).Infof("[kubic] ERROR: could not evaluate the approval policy for %q: %v", nodeCSR.NodeName, err)
+		return
+	}
+
+	if !decision.approved {
+		glog.V(1).Infof("[kubic] node %q (IP: %s, token: %s) is waiting for approval: %s",
+			nodeCSR.NodeName, nodeCSR.IP, nodeCSR.TokenID, decision.message)
+		if c.recorded[csr.Name] == decision.message {
+			return
+		}
+		if err := recordCSREvent(c.client, csr, corev1.EventTypeNormal, decision.reason, decision.message); err != nil {
+			glog.V(1).Infof("[kubic] WARNING: could not record an event for %s: %v", csr.Name, err)
+			return
+		}
+		c.recorded[csr.Name] = decision.message
+		return
+	}
+
+	glog.V(1).Infof("[kubic] approving node %q (IP: %s, token: %s): %s",
+		nodeCSR.NodeName, nodeCSR.IP, nodeCSR.TokenID, decision.message)
+	if err := decideNodeCSR(c.client, csr, nodeCSR, true, decision.reason, decision.message); err != nil {
+		glog.V(1).Infof("[kubic] ERROR: could not approve %s: %v", csr.Name, err)
+	}
+}
+
+func init() {
+	// self-register in the manager controllers registry
+	manager.Registry.Register(approvalControllerName, newApprovalController)
+}
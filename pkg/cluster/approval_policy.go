@@ -0,0 +1,384 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+// NodeApprovalsConfigMapName is the ConfigMap where the nodes approved with each
+// bootstrap token are recorded (for the per-token quotas)
+const NodeApprovalsConfigMapName = "kubic-node-approvals"
+
+// the component in the events recorded for the approval decisions
+const approvalEventsComponent = "kubic-init"
+
+// Reasons for the approval decisions (used in the CSR conditions and events)
+const (
+	NodePolicyReason  = "KubicPolicy"
+	NodePendingReason = "KubicPending"
+)
+
+// approvalDecision is the result of evaluating the approval policy for a node
+type approvalDecision struct {
+	approved bool
+	reason   string
+	message  string
+}
+
+// approvalPolicy decides which nodes can be approved automatically
+type approvalPolicy struct {
+	allowlist        sets.String
+	hostnames        []*regexp.Regexp
+	cidrs            []*net.IPNet
+	maxNodes         int
+	maxNodesPerToken int
+	windows          []*kubicutil.TimeWindow
+	empty            bool
+}
+
+// newApprovalPolicy creates the approval policy from the configuration
+func newApprovalPolicy(cfg *config.ApprovalConfiguration) (*approvalPolicy, error) {
+	p := &approvalPolicy{
+		allowlist:        sets.NewString(cfg.Allowlist...),
+		maxNodes:         cfg.Policy.MaxNodes,
+		maxNodesPerToken: cfg.Policy.MaxNodesPerToken,
+		empty:            cfg.Policy.IsEmpty(),
+	}
+	for _, hostname := range cfg.Policy.Hostnames {
+		// the whole hostname must match
+		re, err := regexp.Compile("^(?:" + hostname + ")$")
+		if err != nil {
+			return nil, err
+		}
+		p.hostnames = append(p.hostnames, re)
+	}
+	for _, cidr := range cfg.Policy.CIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		p.cidrs = append(p.cidrs, ipNet)
+	}
+	for _, window := range cfg.Policy.Windows {
+		w, err := kubicutil.ParseTimeWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		p.windows = append(p.windows, w)
+	}
+	return p, nil
+}
+
+// evaluate decides if a node can be approved at `now`. Nodes that cannot be
+// approved are kept pending, so the operator can still approve them.
+func (p *approvalPolicy) evaluate(client clientset.Interface, nodeCSR *NodeCSR, now time.Time) (approvalDecision, error) {
+	if p.allowlist.Has(nodeCSR.NodeName) || (len(nodeCSR.IP) > 0 && p.allowlist.Has(nodeCSR.IP)) {
+		return approvalDecision{
+			approved: true,
+			reason:   NodeAllowlistReason,
+			message:  fmt.Sprintf("node %q found in the kubic-init allowlist", nodeCSR.NodeName),
+		}, nil
+	}
+
+	pending := func(format string, args ...interface{}) (approvalDecision, error) {
+		return approvalDecision{
+			reason: NodePendingReason,
+			message: fmt.Sprintf("%s: waiting for 'kubic-init node approve %s'",
+				fmt.Sprintf(format, args...), nodeCSR.NodeName),
+		}, nil
+	}
+
+	if p.empty {
+		return pending("no approval policy")
+	}
+
+	if len(p.hostnames) > 0 && !p.matchesHostname(nodeCSR.NodeName) {
+		return pending("the node name does not match the allowed hostnames")
+	}
+
+	// the name of the node is chosen by the requester (and its IP address is resolved from
+	// that name), so the subnets are checked against the source address of the registration
+	// sent by the node, signed with the same bootstrap token used for the request
+	if len(p.cidrs) > 0 {
+		tokenID, address, err := getNodeAddress(client, nodeCSR.NodeName)
+		if err != nil {
+			return approvalDecision{}, err
+		}
+		ip := net.ParseIP(address)
+		if ip == nil || tokenID != nodeCSR.TokenID {
+			return pending("the node has not registered its address with token %s", nodeCSR.TokenID)
+		}
+		if !p.matchesCIDR(ip) {
+			return pending("the registered address %s is not in the allowed subnets", ip)
+		}
+	}
+
+	if len(p.windows) > 0 && !p.inWindow(now) {
+		return pending("out of the approval time windows")
+	}
+
+	if p.maxNodes > 0 {
+		count, err := countNodes(client)
+		if err != nil {
+			return approvalDecision{}, err
+		}
+		if count >= p.maxNodes {
+			return pending("the cluster already has the maximum number of nodes (%d)", p.maxNodes)
+		}
+	}
+
+	if p.maxNodesPerToken > 0 {
+		approved, err := getApprovedNodes(client)
+		if err != nil {
+			return approvalDecision{}, err
+		}
+		if len(approved[nodeCSR.TokenID]) >= p.maxNodesPerToken {
+			return pending("the maximum number of nodes for token %s (%d) has been reached",
+				nodeCSR.TokenID, p.maxNodesPerToken)
+		}
+	}
+
+	return approvalDecision{
+		approved: true,
+		reason:   NodePolicyReason,
+		message:  fmt.Sprintf("node %q satisfies the kubic-init approval policy", nodeCSR.NodeName),
+	}, nil
+}
+
+// countNodes returns the number of nodes in the cluster: the nodes registered and
+// the nodes approved that have not registered yet (so a burst of requests cannot
+// go over the limit)
+func countNodes(client clientset.Interface) (int, error) {
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	approved, err := getApprovedNodes(client)
+	if err != nil {
+		return 0, err
+	}
+
+	names := sets.NewString()
+	for _, node := range nodes.Items {
+		names.Insert(node.Name)
+	}
+	for _, tokenNodes := range approved {
+		names.Insert(tokenNodes...)
+	}
+	return names.Len(), nil
+}
+
+func (p *approvalPolicy) matchesHostname(name string) bool {
+	for _, re := range p.hostnames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *approvalPolicy) matchesCIDR(ip net.IP) bool {
+	for _, cidr := range p.cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *approvalPolicy) inWindow(now time.Time) bool {
+	for _, w := range p.windows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// getApprovedNodes returns the nodes approved with each bootstrap token
+func getApprovedNodes(client clientset.Interface) (map[string][]string, error) {
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(NodeApprovalsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return map[string][]string{}, nil
+		}
+		return nil, err
+	}
+
+	approved := map[string][]string{}
+	for tokenID, nodes := range cm.Data {
+		approved[tokenID] = strings.Split(nodes, ",")
+	}
+	return approved, nil
+}
+
+// recordApprovedNode records a node has been approved with a bootstrap token
+// (retrying when the ConfigMap is updated concurrently, so no approval is lost)
+func recordApprovedNode(client clientset.Interface, tokenID string, nodeName string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return tryRecordApprovedNode(client, tokenID, nodeName)
+	})
+}
+
+func tryRecordApprovedNode(client clientset.Interface, tokenID string, nodeName string) error {
+	cms := client.CoreV1().ConfigMaps(metav1.NamespaceSystem)
+	cm, err := cms.Get(NodeApprovalsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		_, err = cms.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      NodeApprovalsConfigMapName,
+				Namespace: metav1.NamespaceSystem,
+			},
+			Data: map[string]string{tokenID: nodeName},
+		})
+		if apierrors.IsAlreadyExists(err) {
+			// created concurrently: retry with an update
+			return apierrors.NewConflict(corev1.Resource("configmaps"), NodeApprovalsConfigMapName, err)
+		}
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	nodes := sets.NewString(nodeName)
+	if len(cm.Data[tokenID]) > 0 {
+		nodes.Insert(strings.Split(cm.Data[tokenID], ",")...)
+	}
+	list := nodes.List()
+	sort.Strings(list)
+	cm.Data[tokenID] = strings.Join(list, ",")
+	_, err = cms.Update(cm)
+	return err
+}
+
+// forgetApprovedNode removes a node from the nodes approved with any bootstrap token
+// (ie, when the node is removed from the cluster, releasing its slot in the quotas)
+func forgetApprovedNode(client clientset.Interface, nodeName string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return tryForgetApprovedNode(client, nodeName)
+	})
+}
+
+func tryForgetApprovedNode(client clientset.Interface, nodeName string) error {
+	cms := client.CoreV1().ConfigMaps(metav1.NamespaceSystem)
+	cm, err := cms.Get(NodeApprovalsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	changed := false
+	for tokenID, nodes := range cm.Data {
+		approved := sets.NewString(strings.Split(nodes, ",")...)
+		if !approved.Has(nodeName) {
+			continue
+		}
+		approved.Delete(nodeName)
+		if approved.Len() == 0 {
+			delete(cm.Data, tokenID)
+		} else {
+			cm.Data[tokenID] = strings.Join(approved.List(), ",")
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	_, err = cms.Update(cm)
+	return err
+}
+
+// recordCSREvent records an event for a certificate signing request
+// Note well: CSRs are not namespaced, so the events are created in the "default" namespace
+func recordCSREvent(client clientset.Interface, csr *certificatesv1beta1.CertificateSigningRequest, eventType, reason, message string) error {
+	now := metav1.Now()
+	_, err := client.CoreV1().Events(metav1.NamespaceDefault).Create(&corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", csr.Name, now.UnixNano()),
+			Namespace: metav1.NamespaceDefault,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "CertificateSigningRequest",
+			APIVersion: certificatesv1beta1.SchemeGroupVersion.String(),
+			Name:       csr.Name,
+			UID:        csr.UID,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: approvalEventsComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	})
+	return err
+}
+
+// decideNodeCSR approves or denies the certificate signing request from a node,
+// recording the decision in an event (and the approved node for the quotas). The
+// approved node is recorded before approving the request, so it is never approved
+// without counting for the quotas.
+func decideNodeCSR(client clientset.Interface, csr *certificatesv1beta1.CertificateSigningRequest, nodeCSR *NodeCSR,
+	approved bool, reason, message string) error {
+
+	condType := certificatesv1beta1.CertificateDenied
+	eventType := corev1.EventTypeWarning
+	if approved {
+		condType = certificatesv1beta1.CertificateApproved
+		eventType = corev1.EventTypeNormal
+	}
+
+	if approved {
+		if err := recordApprovedNode(client, nodeCSR.TokenID, nodeCSR.NodeName); err != nil {
+			return fmt.Errorf("could not record the approval of %q: %v", nodeCSR.NodeName, err)
+		}
+	}
+
+	if err := setCSRCondition(client, csr, condType, reason, message); err != nil {
+		return err
+	}
+	if err := recordCSREvent(client, csr, eventType, reason, message); err != nil {
+		glog.V(1).Infof("[kubic] WARNING: could not record an event for %s: %v", csr.Name, err)
+	}
+	return nil
+}
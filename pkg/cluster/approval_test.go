@@ -0,0 +1,357 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// newTestNodeCSR creates a certificate signing request like the one created by a joining kubelet
+func newTestNodeCSR(t *testing.T, name, nodeName, tokenID string) *certificatesv1beta1.CertificateSigningRequest {
+	return newTestCSR(t, name, tokenID, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   nodeUserPrefix + nodeName,
+			Organization: []string{nodesGroup},
+		},
+	}, nodeClientUsages)
+}
+
+// newTestCSR creates a certificate signing request from a `template`, sent with a bootstrap token
+func newTestCSR(t *testing.T, name, tokenID string, template *x509.CertificateRequest,
+	usages []certificatesv1beta1.KeyUsage) *certificatesv1beta1.CertificateSigningRequest {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate a key: %s", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatalf("could not create the certificate request: %s", err)
+	}
+
+	return &certificatesv1beta1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+			Request:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+			Username: bootstrapUserPrefix + tokenID,
+			Usages:   usages,
+		},
+	}
+}
+
+func isApproved(t *testing.T, client *fake.Clientset, name string) bool {
+	csr, err := client.CertificatesV1beta1().CertificateSigningRequests().Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get %s: %s", name, err)
+	}
+	for _, cond := range csr.Status.Conditions {
+		if cond.Type == certificatesv1beta1.CertificateApproved {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNodeApproval(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newTestNodeCSR(t, "csr-1", "node-1", "abcdef"),
+		newTestNodeCSR(t, "csr-2", "node-2", "abcdef"),
+	)
+
+	pending, err := GetPendingNodeCSRs(client)
+	if err != nil {
+		t.Fatalf("could not get the pending requests: %s", err)
+	}
+	t.Logf("pending: %+v", pending)
+	if len(pending) != 2 || pending[0].TokenID != "abcdef" {
+		t.Fatalf("unexpected pending requests: %+v", pending)
+	}
+
+	if err := ApproveNode(client, "node-1", "test"); err != nil {
+		t.Fatalf("could not approve node-1: %s", err)
+	}
+	if !isApproved(t, client, "csr-1") {
+		t.Fatalf("csr-1 has not been approved")
+	}
+	if err := ApproveNode(client, "node-1", "test"); err == nil {
+		t.Fatalf("no error when approving a node without pending requests")
+	}
+
+	// the controller approves the nodes in the allowlist
+	policy, err := newApprovalPolicy(&config.ApprovalConfiguration{Allowlist: []string{"node-2"}})
+	if err != nil {
+		t.Fatalf("could not create the approval policy: %s", err)
+	}
+	c := &approvalController{
+		kubicCfg: &config.KubicInitConfiguration{},
+		client:   client,
+		policy:   policy,
+		recorded: map[string]string{},
+	}
+	csr, err := client.CertificatesV1beta1().CertificateSigningRequests().Get("csr-2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get csr-2: %s", err)
+	}
+	c.handle(csr)
+	if !isApproved(t, client, "csr-2") {
+		t.Fatalf("csr-2 has not been approved by the controller")
+	}
+
+	// all the decisions are recorded as events
+	events, err := client.CoreV1().Events(metav1.NamespaceDefault).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("could not list the events: %s", err)
+	}
+	if len(events.Items) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events.Items))
+	}
+}
+
+func TestNodeApprovalOnlyClientCerts(t *testing.T) {
+	subject := pkix.Name{
+		CommonName:   nodeUserPrefix + "node-1",
+		Organization: []string{nodesGroup},
+	}
+	serverUsages := []certificatesv1beta1.KeyUsage{
+		certificatesv1beta1.UsageDigitalSignature,
+		certificatesv1beta1.UsageKeyEncipherment,
+		certificatesv1beta1.UsageServerAuth,
+	}
+	client := fake.NewSimpleClientset(
+		newTestCSR(t, "csr-dns", "abcdef", &x509.CertificateRequest{Subject: subject, DNSNames: []string{"kubernetes.default"}}, nodeClientUsages),
+		newTestCSR(t, "csr-ip", "abcdef", &x509.CertificateRequest{Subject: subject, IPAddresses: []net.IP{net.ParseIP("10.96.0.1")}}, nodeClientUsages),
+		newTestCSR(t, "csr-server", "abcdef", &x509.CertificateRequest{Subject: subject}, serverUsages),
+		newTestCSR(t, "csr-extra", "abcdef", &x509.CertificateRequest{Subject: subject},
+			append([]certificatesv1beta1.KeyUsage{certificatesv1beta1.UsageServerAuth}, nodeClientUsages...)),
+	)
+
+	pending, err := GetPendingNodeCSRs(client)
+	if err != nil {
+		t.Fatalf("could not get the pending requests: %s", err)
+	}
+	if len(pending) > 0 {
+		t.Fatalf("requests with SANs or other usages considered node requests: %+v", pending)
+	}
+
+	// neither the operator...
+	if err := ApproveNode(client, "node-1", "test"); err == nil {
+		t.Fatalf("no error when approving a node with only invalid requests")
+	}
+
+	// ... nor the controller (even for nodes in the allowlist) approve them
+	policy, err := newApprovalPolicy(&config.ApprovalConfiguration{Allowlist: []string{"node-1"}})
+	if err != nil {
+		t.Fatalf("could not create the approval policy: %s", err)
+	}
+	c := &approvalController{
+		kubicCfg: &config.KubicInitConfiguration{},
+		client:   client,
+		policy:   policy,
+		recorded: map[string]string{},
+	}
+	for _, name := range []string{"csr-dns", "csr-ip", "csr-server", "csr-extra"} {
+		csr, err := client.CertificatesV1beta1().CertificateSigningRequests().Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("could not get %s: %s", name, err)
+		}
+		c.handle(csr)
+		if isApproved(t, client, name) {
+			t.Fatalf("%s has been approved", name)
+		}
+	}
+}
+
+func TestApprovalPolicy(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	policy, err := newApprovalPolicy(&config.ApprovalConfiguration{
+		Policy: config.ApprovalPolicyConfiguration{
+			Hostnames:        []string{"worker-[0-9]+"},
+			MaxNodesPerToken: 1,
+			Windows:          []string{"Mon-Fri 08:00-18:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not create the approval policy: %s", err)
+	}
+
+	// 2018-11-19 is a Monday
+	monday := time.Date(2018, 11, 19, 10, 0, 0, 0, time.Local)
+	sunday := time.Date(2018, 11, 25, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		nodeName string
+		tokenID  string
+		now      time.Time
+		expected bool
+	}{
+		{"worker-1", "abcdef", monday, true},
+		{"master-1", "abcdef", monday, false},
+		{"worker-1x", "abcdef", monday, false},
+		{"worker-2", "ghijkl", sunday, false},
+	}
+	for _, test := range tests {
+		decision, err := policy.evaluate(client, &NodeCSR{NodeName: test.nodeName, TokenID: test.tokenID}, test.now)
+		if err != nil {
+			t.Fatalf("could not evaluate the policy for %s: %s", test.nodeName, err)
+		}
+		t.Logf("%s: %+v", test.nodeName, decision)
+		if decision.approved != test.expected {
+			t.Fatalf("%s: expected approved=%t, got %t", test.nodeName, test.expected, decision.approved)
+		}
+	}
+
+	// the token quota is checked with the nodes already approved
+	if err := recordApprovedNode(client, "abcdef", "worker-1"); err != nil {
+		t.Fatalf("could not record the approval: %s", err)
+	}
+	decision, err := policy.evaluate(client, &NodeCSR{NodeName: "worker-2", TokenID: "abcdef"}, monday)
+	if err != nil {
+		t.Fatalf("could not evaluate the policy: %s", err)
+	}
+	if decision.approved {
+		t.Fatalf("node approved after exceeding the token quota")
+	}
+}
+
+func TestApprovalPolicyMaxNodes(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master-1"}})
+
+	policy, err := newApprovalPolicy(&config.ApprovalConfiguration{
+		Policy: config.ApprovalPolicyConfiguration{MaxNodes: 3},
+	})
+	if err != nil {
+		t.Fatalf("could not create the approval policy: %s", err)
+	}
+
+	// nodes approved (but not registered yet) count for the limit,
+	// so a burst of requests cannot go over it
+	for i, nodeName := range []string{"worker-1", "worker-2", "worker-3"} {
+		decision, err := policy.evaluate(client, &NodeCSR{NodeName: nodeName, TokenID: "abcdef"}, time.Now())
+		if err != nil {
+			t.Fatalf("could not evaluate the policy for %s: %s", nodeName, err)
+		}
+		t.Logf("%s: %+v", nodeName, decision)
+		if expected := i < 2; decision.approved != expected {
+			t.Fatalf("%s: expected approved=%t, got %t", nodeName, expected, decision.approved)
+		}
+		if decision.approved {
+			if err := recordApprovedNode(client, "abcdef", nodeName); err != nil {
+				t.Fatalf("could not record the approval: %s", err)
+			}
+		}
+	}
+
+	// removed nodes release their slot
+	if err := forgetApprovedNode(client, "worker-1"); err != nil {
+		t.Fatalf("could not forget the node: %s", err)
+	}
+	decision, err := policy.evaluate(client, &NodeCSR{NodeName: "worker-3", TokenID: "abcdef"}, time.Now())
+	if err != nil {
+		t.Fatalf("could not evaluate the policy: %s", err)
+	}
+	if !decision.approved {
+		t.Fatalf("node not approved after releasing a slot: %+v", decision)
+	}
+}
+
+func TestApprovalPolicyCIDRs(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	policy, err := newApprovalPolicy(&config.ApprovalConfiguration{
+		Policy: config.ApprovalPolicyConfiguration{CIDRs: []string{"10.0.0.0/24"}},
+	})
+	if err != nil {
+		t.Fatalf("could not create the approval policy: %s", err)
+	}
+
+	for nodeName, address := range map[string]string{"worker-1": "10.0.0.5", "worker-2": "192.168.1.5"} {
+		if err := recordNodeAddress(client, nodeName, "abcdef", address); err != nil {
+			t.Fatalf("could not record the address of %s: %s", nodeName, err)
+		}
+	}
+
+	tests := []struct {
+		nodeCSR  NodeCSR
+		approved bool
+	}{
+		{NodeCSR{NodeName: "worker-1", TokenID: "abcdef"}, true},
+		// the address resolved for the name is never used
+		{NodeCSR{NodeName: "worker-2", TokenID: "abcdef", IP: "10.0.0.6"}, false},
+		{NodeCSR{NodeName: "worker-3", TokenID: "abcdef", IP: "10.0.0.7"}, false},
+		// registered with a different token
+		{NodeCSR{NodeName: "worker-1", TokenID: "fedcba"}, false},
+	}
+	for _, test := range tests {
+		nodeCSR := test.nodeCSR
+		decision, err := policy.evaluate(client, &nodeCSR, time.Now())
+		if err != nil {
+			t.Fatalf("could not evaluate the policy for %+v: %s", nodeCSR, err)
+		}
+		t.Logf("%+v: %+v", nodeCSR, decision)
+		if decision.approved != test.approved {
+			t.Fatalf("%+v: expected approved=%t, got %t", nodeCSR, test.approved, decision.approved)
+		}
+	}
+}
+
+func TestRecordApprovedNodeConflict(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: NodeApprovalsConfigMapName, Namespace: metav1.NamespaceSystem},
+		Data:       map[string]string{"abcdef": "worker-1"},
+	})
+
+	// the first update fails, as if another approval had updated the ConfigMap
+	conflicts := 0
+	client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, apierrors.NewConflict(corev1.Resource("configmaps"), NodeApprovalsConfigMapName, nil)
+	})
+
+	if err := recordApprovedNode(client, "abcdef", "worker-2"); err != nil {
+		t.Fatalf("could not record the approval: %s", err)
+	}
+	approved, err := getApprovedNodes(client)
+	if err != nil {
+		t.Fatalf("could not get the approved nodes: %s", err)
+	}
+	if len(approved["abcdef"]) != 2 {
+		t.Fatalf("approval lost after a conflict: %v", approved)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/kubernetes/kubernetes/cmd/kubeadm/app/util/apiclient"
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/pubkeypin"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/crypto"
+)
+
+const (
+	// ControlPlaneCertsSecretName is the Secret where the (encrypted) control-plane certificates are uploaded
+	ControlPlaneCertsSecretName = "kubic-control-plane-certs"
+
+	// the Role (and RoleBinding) that allows joining nodes to read the certificates
+	controlPlaneCertsRoleName = "kubic:control-plane-certs"
+
+	// the key in the Secret where the salt (for deriving the key) is stored
+	controlPlaneCertsSaltKey = "salt"
+)
+
+// controlPlaneCert is a file shared by all the masters
+type controlPlaneCert struct {
+	// name is the file name, relative to the certificates directory
+	name string
+
+	// key is true for private keys
+	key bool
+
+	// optional is true for files that are not always present (ie, when using an external etcd)
+	optional bool
+}
+
+// the files that must be shared by all the masters
+var controlPlaneCerts = []controlPlaneCert{
+	{name: kubeadmconstants.CACertName},
+	{name: kubeadmconstants.CAKeyName, key: true},
+	{name: kubeadmconstants.ServiceAccountPublicKeyName},
+	{name: kubeadmconstants.ServiceAccountPrivateKeyName, key: true},
+	{name: kubeadmconstants.FrontProxyCACertName},
+	{name: kubeadmconstants.FrontProxyCAKeyName, key: true},
+	{name: kubeadmconstants.EtcdCACertName, optional: true},
+	{name: kubeadmconstants.EtcdCAKeyName, key: true, optional: true},
+}
+
+// secretKeyForCert returns the key in the Secret for a certificate file (ie, "etcd/ca.crt" -> "etcd-ca.crt")
+func secretKeyForCert(name string) string {
+	return strings.Replace(name, "/", "-", -1)
+}
+
+// UploadControlPlaneCerts uploads the certificates shared by all the masters to a Secret,
+// encrypted with a key derived from `secret`, so new masters can download them
+// with DownloadControlPlaneCerts().
+func UploadControlPlaneCerts(client clientset.Interface, certsDir string, secret string) error {
+	key, err := crypto.NewSecretKey(secret)
+	if err != nil {
+		return err
+	}
+
+	data := map[string][]byte{
+		controlPlaneCertsSaltKey: key.Salt,
+	}
+	for _, cert := range controlPlaneCerts {
+		contents, err := ioutil.ReadFile(filepath.Join(certsDir, cert.name))
+		if os.IsNotExist(err) && cert.optional {
+			glog.V(3).Infof("[kubic] %s not found: skipping", cert.name)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("could not read %s: %v", cert.name, err)
+		}
+
+		encrypted, err := key.Encrypt(contents)
+		if err != nil {
+			return fmt.Errorf("could not encrypt %s: %v", cert.name, err)
+		}
+		data[secretKeyForCert(cert.name)] = encrypted
+	}
+
+	certsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ControlPlaneCertsSecretName,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if err := apiclient.CreateOrUpdateSecret(client, certsSecret); err != nil {
+		return fmt.Errorf("could not upload the control-plane certificates: %v", err)
+	}
+	glog.V(3).Infof("[kubic] control-plane certificates uploaded to %s", ControlPlaneCertsSecretName)
+
+	return createControlPlaneCertsRBAC(client)
+}
+
+// createControlPlaneCertsRBAC allows the nodes that are joining the cluster (authenticated
+// with the bootstrap token) to read the Secret with the control-plane certificates
+func createControlPlaneCertsRBAC(client clientset.Interface) error {
+	role := &rbac.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controlPlaneCertsRoleName,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Rules: []rbac.PolicyRule{
+			{
+				APIGroups:     []string{""},
+				Resources:     []string{"secrets"},
+				ResourceNames: []string{ControlPlaneCertsSecretName},
+				Verbs:         []string{"get"},
+			},
+		},
+	}
+	if err := apiclient.CreateOrUpdateRole(client, role); err != nil {
+		return err
+	}
+
+	roleBinding := &rbac.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controlPlaneCertsRoleName,
+			Namespace: metav1.NamespaceSystem,
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "Role",
+			Name:     controlPlaneCertsRoleName,
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind: rbac.GroupKind,
+				Name: kubeadmconstants.NodeBootstrapTokenAuthGroup,
+			},
+		},
+	}
+	return apiclient.CreateOrUpdateRoleBinding(client, roleBinding)
+}
+
+// DownloadControlPlaneCerts downloads the control-plane certificates uploaded by the seeder,
+// decrypting them with a key derived from `secret` and saving them in `certsDir`.
+// When some `caHashes` are provided, the CA certificate must match one of them.
+func DownloadControlPlaneCerts(client clientset.Interface, certsDir string, secret string, caHashes []string) error {
+	certsSecret, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Get(ControlPlaneCertsSecretName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not get the control-plane certificates (were they uploaded by the seeder?): %v", err)
+	}
+
+	key, err := crypto.NewSecretKeyWithSalt(secret, certsSecret.Data[controlPlaneCertsSaltKey])
+	if err != nil {
+		return err
+	}
+
+	// decrypt everything before writing anything
+	files := map[string][]byte{}
+	for _, cert := range controlPlaneCerts {
+		encrypted, found := certsSecret.Data[secretKeyForCert(cert.name)]
+		if !found {
+			if cert.optional {
+				continue
+			}
+			return fmt.Errorf("%s not found in the uploaded control-plane certificates", cert.name)
+		}
+		contents, err := key.Decrypt(encrypted)
+		if err != nil {
+			return fmt.Errorf("could not decrypt %s: %v", cert.name, err)
+		}
+		files[cert.name] = contents
+	}
+
+	if len(caHashes) > 0 {
+		if err := verifyCAHash(files[kubeadmconstants.CACertName], caHashes); err != nil {
+			return err
+		}
+	}
+
+	for _, cert := range controlPlaneCerts {
+		contents, found := files[cert.name]
+		if !found {
+			continue
+		}
+		path := filepath.Join(certsDir, cert.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if cert.key {
+			mode = 0600
+		}
+		if err := ioutil.WriteFile(path, contents, mode); err != nil {
+			return fmt.Errorf("could not write %s: %v", path, err)
+		}
+		glog.V(3).Infof("[kubic] %s saved", path)
+	}
+
+	return nil
+}
+
+// NewSeederClient creates a client for the seeder authenticated with the bootstrap token.
+// The identity of the seeder is verified with the CA certificate in the configuration or,
+// when only the CA pins are provided, with the CA published by the seeder once it has been
+// checked against the pins. The identity is not verified when no pins are available,
+// unless the verification is required.
+func NewSeederClient(kubicCfg *config.KubicInitConfiguration) (clientset.Interface, error) {
+	seeder := kubicCfg.ClusterFormation.Seeder
+	token := kubicCfg.ClusterFormation.Token
+
+	caHashes, err := kubicCfg.GetCACertHashes()
+	if err != nil {
+		return nil, err
+	}
+	if len(caHashes) == 0 {
+		if kubicCfg.Certificates.RequireVerification {
+			return nil, fmt.Errorf("the identity of the seeder cannot be verified: no CA certificate hashes (or CA certificate) provided")
+		}
+		glog.V(1).Infoln("WARNING: we will not verify the identity of the seeder")
+		return kubicclient.NewBootstrapTokenClient(seeder, token, nil)
+	}
+
+	var caCert []byte
+	if len(kubicCfg.Certificates.CaCrt) > 0 {
+		caCert, err = crypto.LoadCACert(kubicCfg.Certificates.CaCrt)
+	} else {
+		caCert, err = kubicclient.GetClusterCA(seeder)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyCAHash(caCert, caHashes); err != nil {
+		return nil, fmt.Errorf("could not verify the identity of the seeder %s: %v", seeder, err)
+	}
+
+	glog.V(3).Infof("[kubic] the identity of the seeder %s will be verified with the pinned CA", seeder)
+	return kubicclient.NewBootstrapTokenClient(seeder, token, caCert)
+}
+
+// verifyCAHash checks the public key of a CA certificate matches any of the hashes (ie, "sha256:...")
+func verifyCAHash(caCert []byte, caHashes []string) error {
+	certs, err := certutil.ParseCertsPEM(caCert)
+	if err != nil || len(certs) == 0 {
+		return fmt.Errorf("could not parse the CA certificate: %v", err)
+	}
+
+	pins := pubkeypin.NewSet()
+	if err := pins.Allow(caHashes...); err != nil {
+		return err
+	}
+	return pins.Check(certs[0])
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+func TestNewSeederClientVerification(t *testing.T) {
+	cfg, err := config.BytesToKubicInitConfig([]byte{}, false)
+	if err != nil {
+		t.Fatalf("could not get a default configuration: %v", err)
+	}
+	cfg.ClusterFormation.Seeder = "seeder.some.name.com:6443"
+	cfg.ClusterFormation.Token = "94dcda.c271f4ff502789ca"
+
+	// no CA pins: the seeder cannot be verified, so the client is insecure
+	if _, err := NewSeederClient(cfg); err != nil {
+		t.Fatalf("could not create an unverified client: %v", err)
+	}
+
+	// ... unless the verification is required
+	cfg.Certificates.RequireVerification = true
+	if _, err := NewSeederClient(cfg); err == nil {
+		t.Fatalf("client created without CA pins when the verification is required")
+	}
+
+	// an invalid CA certificate is rejected (before contacting the seeder)
+	cfg.Certificates.CaHashes = []string{"sha256:0000000000000000000000000000000000000000000000000000000000000000"}
+	cfg.Certificates.CaCrt = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+	if _, err := NewSeederClient(cfg); err == nil {
+		t.Fatalf("client created with an invalid CA certificate")
+	}
+}
@@ -0,0 +1,319 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+)
+
+// the service used for the DNS SRV lookups (ie, "_kubic-seeder._tcp.<domain>")
+const seederSRVService = "kubic-seeder"
+
+// time waiting for a connection when checking if a seeder is reachable
+const seederProbeTimeout = 5 * time.Second
+
+// default interval between discovery attempts
+const defaultDiscoveryInterval = 10 * time.Second
+
+func init() {
+	// forget the discovered seeder when resetting a node
+	reset.Register(reset.Step{
+		Name:        "discovery-state",
+		Description: "forget the discovered seeder",
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			return []string{config.DefaultKubicDiscoveryStateFile}
+		},
+	})
+}
+
+// SeederDiscoverer is a provider that can find the seeder of a cluster
+type SeederDiscoverer interface {
+	// Name returns the name of the provider
+	Name() string
+
+	// Discover returns the addresses (as host:port) of the seeders found (if any)
+	Discover() ([]string, error)
+}
+
+// NewSeederDiscoverer returns the discovery provider with that `name`
+func NewSeederDiscoverer(name string, cfg *config.DiscoveryConfiguration) (SeederDiscoverer, error) {
+	switch name {
+	case config.DiscoveryProviderMDNS:
+		return &mdnsDiscoverer{wait: defaultMDNSQueryWait}, nil
+	case config.DiscoveryProviderDNS:
+		return &dnsDiscoverer{domain: cfg.Domain}, nil
+	case config.DiscoveryProviderFile:
+		return &fileDiscoverer{path: cfg.File}, nil
+	default:
+		return nil, fmt.Errorf("unknown discovery provider %q", name)
+	}
+}
+
+// mdnsDiscoverer finds the seeders announced with mDNS/DNS-SD in the local network
+type mdnsDiscoverer struct {
+	wait time.Duration
+}
+
+func (d *mdnsDiscoverer) Name() string {
+	return config.DiscoveryProviderMDNS
+}
+
+func (d *mdnsDiscoverer) Discover() ([]string, error) {
+	announcements, err := mdnsQuery(mdnsSeederService, d.wait)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+	for _, announcement := range announcements {
+		addresses = append(addresses, announcement.Address)
+	}
+	return addresses, nil
+}
+
+// dnsDiscoverer finds the seeders with a DNS SRV lookup
+type dnsDiscoverer struct {
+	domain string
+}
+
+func (d *dnsDiscoverer) Name() string {
+	return config.DiscoveryProviderDNS
+}
+
+func (d *dnsDiscoverer) Discover() ([]string, error) {
+	// note well: the records are returned sorted by priority (and randomized by weight)
+	_, records, err := net.LookupSRV(seederSRVService, "tcp", d.domain)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.Err == "no such host" {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	addresses := []string{}
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		addresses = append(addresses, net.JoinHostPort(target, fmt.Sprintf("%d", record.Port)))
+	}
+	return addresses, nil
+}
+
+// fileDiscoverer reads the seeders from a file, with one address per line
+// (empty lines and lines starting with "#" are ignored)
+type fileDiscoverer struct {
+	path string
+}
+
+func (d *fileDiscoverer) Name() string {
+	return config.DiscoveryProviderFile
+}
+
+func (d *fileDiscoverer) Discover() ([]string, error) {
+	f, err := os.Open(d.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	addresses := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addresses = append(addresses, line)
+	}
+	return addresses, scanner.Err()
+}
+
+// SeederDiscovery looks for the seeder of the cluster with some providers and,
+// when no seeder can be found, elects a seeder among the nodes started
+type SeederDiscovery struct {
+	Discoverers []SeederDiscoverer
+
+	// Election enables the election of a seeder when none can be found
+	Election *SeederElection
+
+	// Timeout is the maximum time waiting for a seeder
+	Timeout time.Duration
+
+	// Interval is the time between discovery attempts
+	Interval time.Duration
+}
+
+// NewSeederDiscovery creates a seeder discovery with the providers in the configuration
+// Note well: `election` must be nil when the election has not been enabled
+func NewSeederDiscovery(cfg *config.DiscoveryConfiguration, election *SeederElection, timeout time.Duration) (*SeederDiscovery, error) {
+	d := &SeederDiscovery{
+		Election: election,
+		Timeout:  timeout,
+		Interval: defaultDiscoveryInterval,
+	}
+	for _, name := range cfg.Providers {
+		discoverer, err := NewSeederDiscoverer(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		d.Discoverers = append(d.Discoverers, discoverer)
+	}
+	return d, nil
+}
+
+// Discover returns the address of the seeder, or an empty string
+// if this node has been elected as the seeder
+func (d *SeederDiscovery) Discover() (string, error) {
+	deadline := time.Now().Add(d.Timeout)
+	for {
+		seeder, err := d.findSeeder()
+		if err != nil {
+			return "", err
+		}
+		if len(seeder) > 0 {
+			return seeder, nil
+		}
+
+		if d.Election != nil && !d.Election.Done() {
+			elected, err := d.Election.Run(d.findSeeder)
+			if err != nil {
+				return "", err
+			}
+			if elected {
+				return "", nil
+			}
+			// we lost the election: wait until the winner is announced as the seeder
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("no seeder found after %s", d.Timeout)
+		}
+		glog.V(1).Infof("[kubic] no seeder found: trying again in %s", d.Interval)
+		time.Sleep(d.Interval)
+	}
+}
+
+// findSeeder returns the first seeder found with the providers
+func (d *SeederDiscovery) findSeeder() (string, error) {
+	for _, discoverer := range d.Discoverers {
+		addresses, err := discoverer.Discover()
+		if err != nil {
+			glog.V(1).Infof("[kubic] WARNING: could not discover the seeder with %s: %s", discoverer.Name(), err)
+			continue
+		}
+		glog.V(3).Infof("[kubic] seeders found with %s: %v", discoverer.Name(), addresses)
+
+		if len(addresses) == 0 {
+			continue
+		}
+
+		// prefer a seeder we can reach, but an elected seeder could still be starting
+		// its API server (and "kubeadm join" will wait for it)
+		for _, address := range addresses {
+			if isSeederReachable(address) {
+				glog.V(1).Infof("[kubic] seeder found with %s at %s", discoverer.Name(), address)
+				return address, nil
+			}
+			glog.V(3).Infof("[kubic] seeder at %s is not reachable (yet)", address)
+		}
+		glog.V(1).Infof("[kubic] seeder found with %s at %s (not reachable yet)", discoverer.Name(), addresses[0])
+		return addresses[0], nil
+	}
+	return "", nil
+}
+
+// isSeederReachable checks if we can connect to the API server in a seeder
+func isSeederReachable(address string) bool {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, fmt.Sprintf("%d", config.DefaultAPIServerPort))
+	}
+	conn, err := net.DialTimeout("tcp", address, seederProbeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// discoveryState is the result of the seeder discovery, as persisted in the state file
+type discoveryState struct {
+	// Seeder is the address of the seeder found (empty when this node was elected)
+	Seeder string `json:"seeder"`
+
+	// LastUpdate is the time of the discovery
+	LastUpdate time.Time `json:"lastUpdate"`
+}
+
+// LoadDiscoveredSeeder loads the result of a previous discovery from a state file, so
+// a node keeps its role when the bootstrap is resumed. `found` is false if no discovery
+// has been done yet, and `seeder` is empty when this node was elected as the seeder.
+func LoadDiscoveredSeeder(stateFile string) (seeder string, found bool, err error) {
+	b, err := ioutil.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("could not read the discovery state from %s: %v", stateFile, err)
+	}
+
+	state := discoveryState{}
+	if err := yaml.Unmarshal(b, &state); err != nil {
+		return "", false, fmt.Errorf("could not parse the discovery state in %s: %v", stateFile, err)
+	}
+	return state.Seeder, true, nil
+}
+
+// SaveDiscoveredSeeder saves the result of the discovery in a state file (atomically)
+func SaveDiscoveredSeeder(stateFile string, seeder string) error {
+	b, err := yaml.Marshal(discoveryState{Seeder: seeder, LastUpdate: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0700); err != nil {
+		return err
+	}
+	tmp := stateFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("could not save the discovery state: %v", err)
+	}
+	return os.Rename(tmp, stateFile)
+}
+
+// ClearDiscoveredSeeder removes the discovery state file, so the seeder will be discovered again
+func ClearDiscoveredSeeder(stateFile string) error {
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
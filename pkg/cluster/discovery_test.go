@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileDiscoverer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubic-discovery")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "seeders")
+	contents := "# the seeders\nseeder-1.example.com:6443\n\n  10.0.0.2  \n"
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("could not write %s: %s", path, err)
+	}
+
+	d := &fileDiscoverer{path: path}
+	addresses, err := d.Discover()
+	if err != nil {
+		t.Fatalf("could not discover the seeders: %s", err)
+	}
+	t.Logf("seeders found: %v", addresses)
+
+	expected := []string{"seeder-1.example.com:6443", "10.0.0.2"}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Fatalf("expected %v, got %v", expected, addresses)
+	}
+
+	// a missing file is not an error: the seeder could be added later
+	d = &fileDiscoverer{path: filepath.Join(dir, "missing")}
+	addresses, err = d.Discover()
+	if err != nil || len(addresses) > 0 {
+		t.Fatalf("unexpected result for a missing file: %v, %v", addresses, err)
+	}
+}
+
+func TestMDNSResponse(t *testing.T) {
+	announcements := []mdnsAnnouncement{
+		{ID: "10.0.0.1", Address: "10.0.0.1:6443"},
+		{ID: "fd00::1", Address: "[fd00::1]:6443"},
+	}
+
+	packet, err := mdnsResponse(mdnsCandidateService, announcements...)
+	if err != nil {
+		t.Fatalf("could not create the mDNS response: %s", err)
+	}
+
+	parsed, err := mdnsParseResponse(mdnsCandidateService, packet)
+	if err != nil {
+		t.Fatalf("could not parse the mDNS response: %s", err)
+	}
+	t.Logf("announcements: %v", parsed)
+	if !reflect.DeepEqual(parsed, announcements) {
+		t.Fatalf("expected %v, got %v", announcements, parsed)
+	}
+
+	// announcements for other services must be ignored
+	parsed, err = mdnsParseResponse(mdnsSeederService, packet)
+	if err != nil {
+		t.Fatalf("could not parse the mDNS response: %s", err)
+	}
+	if len(parsed) > 0 {
+		t.Fatalf("unexpected announcements for %s: %v", mdnsSeederService, parsed)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// interval between evictions retries (when a PodDisruptionBudget does not allow the eviction)
+const drainPollInterval = 5 * time.Second
+
+// podsToEvict returns the pods that must be evicted before removing a node.
+// Mirror pods, pods managed by a DaemonSet (they would be re-created in the same node)
+// and completed pods are ignored.
+func podsToEvict(client clientset.Interface, name string) ([]corev1.Pod, error) {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if _, isMirror := pod.Annotations[mirrorPodAnnotation]; isMirror {
+			continue
+		}
+		if controller := metav1.GetControllerOf(&pod); controller != nil && controller.Kind == "DaemonSet" {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		res = append(res, pod)
+	}
+	return res, nil
+}
+
+// evictPod evicts a pod, retrying while the eviction would violate a PodDisruptionBudget
+func evictPod(client clientset.Interface, pod corev1.Pod, timeout time.Duration) error {
+	eviction := &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+
+	err := wait.PollImmediate(drainPollInterval, timeout, func() (bool, error) {
+		err := client.PolicyV1beta1().Evictions(pod.Namespace).Evict(eviction)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			return true, nil
+		case apierrors.IsTooManyRequests(err):
+			glog.V(3).Infof("[kubic] eviction of %s/%s not allowed by a PodDisruptionBudget: retrying", pod.Namespace, pod.Name)
+			return false, nil
+		default:
+			return false, err
+		}
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("pod %s/%s could not be evicted without violating its PodDisruptionBudget", pod.Namespace, pod.Name)
+	}
+	return err
+}
+
+// DrainNode evicts the pods running in a node (honoring their PodDisruptionBudgets)
+// and waits until they are gone. Mirror pods, DaemonSet pods and completed pods are ignored.
+// The node should be cordoned before draining it, so the pods are not scheduled there again.
+// It returns the number of pods evicted.
+func DrainNode(client clientset.Interface, name string, timeout time.Duration) (int, error) {
+	pods, err := podsToEvict(client, name)
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, pod := range pods {
+		glog.V(3).Infof("[kubic] evicting pod %s/%s in node %s", pod.Namespace, pod.Name, name)
+		if err := evictPod(client, pod, time.Until(deadline)); err != nil {
+			return 0, err
+		}
+	}
+
+	err = wait.PollImmediate(drainPollInterval, time.Until(deadline), func() (bool, error) {
+		remaining, err := podsToEvict(client, name)
+		if err != nil {
+			return false, err
+		}
+		glog.V(3).Infof("[kubic] %d pods remaining in node %s", len(remaining), name)
+		return len(remaining) == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return 0, fmt.Errorf("timeout while waiting for the pods in node %s to terminate", name)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return len(pods), nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestPod(name string, phase corev1.PodPhase, annotations map[string]string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   metav1.NamespaceDefault,
+			Annotations: annotations,
+		},
+		Spec:   corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if len(owner) > 0 {
+		isController := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner", Controller: &isController}}
+	}
+	return pod
+}
+
+func TestDrainNode(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newTestPod("web", corev1.PodRunning, nil, "ReplicaSet"),
+		newTestPod("standalone", corev1.PodRunning, nil, ""),
+		newTestPod("static", corev1.PodRunning, map[string]string{mirrorPodAnnotation: "x"}, ""),
+		newTestPod("agent", corev1.PodRunning, nil, "DaemonSet"),
+		newTestPod("finished", corev1.PodSucceeded, nil, "Job"),
+	)
+
+	// the fake clientset does not delete the pods when evicting them
+	evicted := []string{}
+	client.PrependReactor("post", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.GetAction).GetName()
+		evicted = append(evicted, name)
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), action.GetNamespace(), name)
+	})
+
+	count, err := DrainNode(client, "node-1", time.Minute)
+	if err != nil {
+		t.Fatalf("could not drain the node: %s", err)
+	}
+	sort.Strings(evicted)
+	t.Logf("evicted pods: %v", evicted)
+	if count != 2 || strings.Join(evicted, ",") != "standalone,web" {
+		t.Fatalf("unexpected pods evicted: %d %v", count, evicted)
+	}
+
+	remaining, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("could not list the pods: %s", err)
+	}
+	if len(remaining.Items) != 3 {
+		t.Fatalf("unexpected number of pods left: %d", len(remaining.Items))
+	}
+}
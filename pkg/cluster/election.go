@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// default duration of the election of a seeder
+const defaultElectionWindow = 30 * time.Second
+
+// SeederElection elects a seeder among the nodes started when no seeder can be found.
+// All the candidates are announced with mDNS during an election window, and the
+// candidate with the lowest ID (in lexicographic order) becomes the seeder. The
+// winner is then announced as the seeder, so the other nodes can join it.
+type SeederElection struct {
+	responder *MDNSResponder
+
+	// ID is the unique identifier of this node (ie, its IP address)
+	ID string
+
+	// Address is the address of the API server in this node, announced if elected
+	Address string
+
+	// Window is the duration of the election
+	Window time.Duration
+
+	done bool
+}
+
+// NewSeederElection creates a new election, announcing this node with the `responder`
+func NewSeederElection(responder *MDNSResponder, id string, address string) *SeederElection {
+	return &SeederElection{
+		responder: responder,
+		ID:        id,
+		Address:   address,
+		Window:    defaultElectionWindow,
+	}
+}
+
+// Done returns true if the election has already been run
+func (e *SeederElection) Done() bool {
+	return e.done
+}
+
+// Run runs the election, returning true if this node has been elected as the seeder.
+// `findSeeder` is used for checking if a seeder has appeared during the election.
+func (e *SeederElection) Run(findSeeder func() (string, error)) (bool, error) {
+	e.done = true
+
+	glog.V(1).Infof("[kubic] no seeder found: starting a seeder election as %q", e.ID)
+	e.responder.Announce(mdnsCandidateService, e.ID, e.Address)
+	defer e.responder.Withdraw(mdnsCandidateService)
+
+	candidates := sets.NewString(e.ID)
+	deadline := time.Now().Add(e.Window)
+	for time.Now().Before(deadline) {
+		announcements, err := mdnsQuery(mdnsCandidateService, defaultMDNSQueryWait)
+		if err != nil {
+			return false, err
+		}
+		for _, announcement := range announcements {
+			candidates.Insert(announcement.ID)
+		}
+	}
+	glog.V(3).Infof("[kubic] candidates in the seeder election: %v", candidates.List())
+
+	// a late node must not win an election that has already finished somewhere else
+	seeder, err := findSeeder()
+	if err != nil {
+		return false, err
+	}
+	if len(seeder) > 0 {
+		glog.V(1).Infof("[kubic] seeder election cancelled: seeder found at %s", seeder)
+		return false, nil
+	}
+
+	winner := candidates.List()[0]
+	if winner != e.ID {
+		glog.V(1).Infof("[kubic] %q has been elected as the seeder", winner)
+		return false, nil
+	}
+
+	glog.V(1).Infoln("[kubic] this node has been elected as the seeder")
+	e.responder.AnnounceSeeder(e.ID, e.Address)
+	return true, nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/coreos/etcd/pkg/transport"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubeadmapiv1beta1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta1"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+)
+
+// the timeout for etcd operations
+const etcdTimeout = 20 * time.Second
+
+func init() {
+	// remove the etcd data when resetting a control-plane node (use "--keep=etcd-data" for keeping it)
+	reset.Register(reset.Step{
+		Name:        "etcd-data",
+		Description: "remove the etcd data",
+		Enabled: func(cfg *config.KubicInitConfiguration) bool {
+			return cfg.IsMaster() && cfg.Etcd.LocalEtcd != nil
+		},
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			return []string{kubeadmapiv1beta1.DefaultEtcdDataDir}
+		},
+	})
+}
+
+// GetEtcdPeerURL returns the URL etcd uses for talking to its peers in a master
+func GetEtcdPeerURL(host string) string {
+	return fmt.Sprintf("https://%s", net.JoinHostPort(host, strconv.Itoa(kubeadmconstants.EtcdListenPeerPort)))
+}
+
+// GetEtcdClientURL returns the URL for the etcd clients in a master
+func GetEtcdClientURL(host string) string {
+	return fmt.Sprintf("https://%s", net.JoinHostPort(host, strconv.Itoa(kubeadmconstants.EtcdListenClientPort)))
+}
+
+// newEtcdClient creates a client for the etcd cluster at `endpoints`, using the
+// client certificate of the API server in `certsDir`
+func newEtcdClient(endpoints []string, certsDir string) (*clientv3.Client, error) {
+	tlsInfo := transport.TLSInfo{
+		CertFile:      filepath.Join(certsDir, kubeadmconstants.APIServerEtcdClientCertName),
+		KeyFile:       filepath.Join(certsDir, kubeadmconstants.APIServerEtcdClientKeyName),
+		TrustedCAFile: filepath.Join(certsDir, kubeadmconstants.EtcdCACertName),
+	}
+	tlsConfig, err := tlsInfo.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load the etcd client certificates: %v", err)
+	}
+
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: etcdTimeout,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to etcd at %v: %v", endpoints, err)
+	}
+	return cli, nil
+}
+
+// findEtcdMembers returns the etcd members that belong to a node: the members named
+// after the node (as kubeadm does) and the members with any of the `peerURLs`
+func findEtcdMembers(members []*etcdserverpb.Member, nodeName string, peerURLs []string) []*etcdserverpb.Member {
+	urls := sets.NewString(peerURLs...)
+	found := []*etcdserverpb.Member{}
+	for _, member := range members {
+		if member.Name == nodeName || urls.HasAny(member.PeerURLs...) {
+			found = append(found, member)
+		}
+	}
+	return found
+}
+
+// RemoveEtcdMember removes the etcd member(s) of node `nodeName` (with any of the `peerURLs`)
+// from the etcd cluster at `endpoints`, failing when no member is found. It uses the client
+// certificate of the API server in `certsDir` for talking to etcd.
+func RemoveEtcdMember(endpoints []string, certsDir string, nodeName string, peerURLs []string) error {
+	cli, err := newEtcdClient(endpoints, certsDir)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	members, err := cli.MemberList(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list the etcd members: %v", err)
+	}
+
+	found := findEtcdMembers(members.Members, nodeName, peerURLs)
+	if len(found) == 0 {
+		return fmt.Errorf("no etcd member found for node %s (with name %q or peer URLs %v)", nodeName, nodeName, peerURLs)
+	}
+	for _, member := range found {
+		glog.V(1).Infof("[kubic] removing %s (%v) from the etcd members", member.Name, member.PeerURLs)
+		if _, err := cli.MemberRemove(ctx, member.ID); err != nil {
+			return fmt.Errorf("could not remove the etcd member %s (%v): %v", member.Name, member.PeerURLs, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd/etcdserver/etcdserverpb"
+)
+
+func TestFindEtcdMembers(t *testing.T) {
+	members := []*etcdserverpb.Member{
+		{ID: 1, Name: "master-1", PeerURLs: []string{GetEtcdPeerURL("10.0.0.1")}},
+		{ID: 2, Name: "master-2", PeerURLs: []string{GetEtcdPeerURL("10.0.0.2")}},
+		// a member added with a different address (and not started yet)
+		{ID: 3, Name: "", PeerURLs: []string{GetEtcdPeerURL("192.168.1.2")}},
+		{ID: 4, Name: "master-3", PeerURLs: []string{GetEtcdPeerURL("10.0.0.3")}},
+	}
+
+	tests := []struct {
+		nodeName string
+		peerURLs []string
+		expected string
+	}{
+		{"master-2", []string{GetEtcdPeerURL("10.0.0.2"), GetEtcdPeerURL("192.168.1.2")}, "2,3"},
+		{"master-3", []string{GetEtcdPeerURL("172.16.0.3")}, "4"},
+		{"master-4", []string{GetEtcdPeerURL("10.0.0.4")}, ""},
+	}
+	for _, test := range tests {
+		ids := []string{}
+		for _, member := range findEtcdMembers(members, test.nodeName, test.peerURLs) {
+			ids = append(ids, strconv.FormatUint(member.ID, 10))
+		}
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != test.expected {
+			t.Fatalf("%s: expected members %q, got %v", test.nodeName, test.expected, ids)
+		}
+	}
+}
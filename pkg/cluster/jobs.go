@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+)
+
+// interval between checks of the status of the Jobs run in nodes
+const nodeJobPollInterval = 5 * time.Second
+
+// a one-shot Job pinned to a node, with access to the host
+const nodeJob = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Name }}
+  namespace: kube-system
+  labels:
+    k8s-app: {{ .App }}
+spec:
+  backoffLimit: 2
+  template:
+    metadata:
+      labels:
+        k8s-app: {{ .App }}
+    spec:
+      nodeName: {{ .Node }}
+      hostNetwork: true
+      hostPID: true
+      restartPolicy: Never
+      containers:
+      - name: {{ .App }}
+        image: {{ .Image }}
+        imagePullPolicy: IfNotPresent
+        command:
+{{- range .Command }}
+          - "{{ . }}"
+{{- end }}
+        securityContext:
+          privileged: true
+        volumeMounts:
+{{- range $i, $path := .HostPaths }}
+        - name: host-{{ $i }}
+          mountPath: {{ $path }}
+{{- end }}
+      tolerations:
+        - operator: Exists
+      volumes:
+{{- range $i, $path := .HostPaths }}
+        - name: host-{{ $i }}
+          hostPath:
+            path: {{ $path }}
+{{- end }}
+`
+
+// NodeJob is a one-shot Job run in a node (with privileges and access to the host)
+type NodeJob struct {
+	// App is the name of the Job (the name of the node is appended)
+	App string
+
+	// Node is the node where the Job is run
+	Node string
+
+	// Image is the image for the Job
+	Image string
+
+	// Command is the command run in the container
+	Command []string
+
+	// HostPaths are the paths in the host mounted (in the same path) in the container
+	HostPaths []string
+}
+
+// Name returns the name of the Job
+func (j NodeJob) Name() string {
+	return fmt.Sprintf("%s-%s", j.App, j.Node)
+}
+
+// RunNodeJob runs a Job in a node and waits until it finishes. The Job
+// is deleted once it has completed successfully.
+func RunNodeJob(client clientset.Interface, j NodeJob, timeout time.Duration) error {
+	jobBytes, err := kubeadmutil.ParseTemplate(nodeJob,
+		struct {
+			Name      string
+			App       string
+			Node      string
+			Image     string
+			Command   []string
+			HostPaths []string
+		}{
+			j.Name(),
+			j.App,
+			j.Node,
+			j.Image,
+			j.Command,
+			j.HostPaths,
+		})
+	if err != nil {
+		return fmt.Errorf("error when parsing the %s job template: %v", j.App, err)
+	}
+
+	job := &batchv1.Job{}
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), jobBytes, job); err != nil {
+		return fmt.Errorf("unable to decode the %s job: %v", j.App, err)
+	}
+
+	jobs := client.BatchV1().Jobs(metav1.NamespaceSystem)
+	foregroundDelete := metav1.DeletePropagationForeground
+	deleteOptions := &metav1.DeleteOptions{PropagationPolicy: &foregroundDelete}
+
+	// remove any leftovers from a previous (failed) run
+	if err := jobs.Delete(job.Name, deleteOptions); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	err = wait.PollImmediate(nodeJobPollInterval, timeout, func() (bool, error) {
+		_, err := jobs.Create(job)
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("could not create the %s job: %v", j.App, err)
+	}
+
+	err = wait.PollImmediate(nodeJobPollInterval, timeout, func() (bool, error) {
+		current, err := jobs.Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range current.Status.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				return false, fmt.Errorf("the %s job failed in %s: %s", j.App, j.Node, cond.Message)
+			}
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timeout while waiting for the %s job to finish in %s", j.App, j.Node)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := jobs.Delete(job.Name, deleteOptions); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mDNS/DNS-SD services announced by kubic-init
+const (
+	// the seeder of a cluster
+	mdnsSeederService = "_kubic-seeder._tcp.local."
+
+	// the candidates in a seeder election
+	mdnsCandidateService = "_kubic-candidate._tcp.local."
+)
+
+const (
+	// the mDNS multicast address (only IPv4 is supported)
+	mdnsAddress = "224.0.0.251:5353"
+
+	// TTL (in seconds) for the records in our responses
+	mdnsTTL = 120
+
+	// maximum size of a mDNS packet
+	mdnsMaxPacketSize = 9000
+
+	// default time waiting for responses after a query
+	defaultMDNSQueryWait = 3 * time.Second
+)
+
+// keys in the TXT record of an announcement
+const (
+	mdnsTXTKeyID      = "id"
+	mdnsTXTKeyAddress = "addr"
+)
+
+// mdnsAnnouncement is a node announced with mDNS
+type mdnsAnnouncement struct {
+	// ID is a unique identifier of the node
+	ID string
+
+	// Address is the address of the API server in the node (as host:port)
+	Address string
+}
+
+// mdnsInstanceName returns the DNS-SD instance name for an announcement of a `service`
+func mdnsInstanceName(service string, id string) string {
+	// the id is used as a single label: replace some characters found in IPs
+	label := strings.NewReplacer(".", "-", ":", "-").Replace(id)
+	return fmt.Sprintf("%s.%s", label, service)
+}
+
+// mdnsResponse creates the response for a `service`, with all the `announcements`
+func mdnsResponse(service string, announcements ...mdnsAnnouncement) ([]byte, error) {
+	serviceName, err := dnsmessage.NewName(service)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, Authoritative: true},
+	}
+	for _, announcement := range announcements {
+		instanceName, err := dnsmessage.NewName(mdnsInstanceName(service, announcement.ID))
+		if err != nil {
+			return nil, err
+		}
+		msg.Answers = append(msg.Answers,
+			dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: serviceName, Class: dnsmessage.ClassINET, TTL: mdnsTTL},
+				Body:   &dnsmessage.PTRResource{PTR: instanceName},
+			},
+			dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: instanceName, Class: dnsmessage.ClassINET, TTL: mdnsTTL},
+				Body: &dnsmessage.TXTResource{TXT: []string{
+					fmt.Sprintf("%s=%s", mdnsTXTKeyID, announcement.ID),
+					fmt.Sprintf("%s=%s", mdnsTXTKeyAddress, announcement.Address),
+				}},
+			})
+	}
+	return msg.Pack()
+}
+
+// mdnsParseResponse gets all the announcements of a `service` in a response
+func mdnsParseResponse(service string, packet []byte) ([]mdnsAnnouncement, error) {
+	msg := dnsmessage.Message{}
+	if err := msg.Unpack(packet); err != nil {
+		return nil, err
+	}
+	if !msg.Header.Response {
+		return []mdnsAnnouncement{}, nil
+	}
+
+	announcements := []mdnsAnnouncement{}
+	for _, answer := range append(msg.Answers, msg.Additionals...) {
+		txt, ok := answer.Body.(*dnsmessage.TXTResource)
+		if !ok || !strings.HasSuffix(answer.Header.Name.String(), "."+service) {
+			continue
+		}
+
+		announcement := mdnsAnnouncement{}
+		for _, entry := range txt.TXT {
+			kv := strings.SplitN(entry, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case mdnsTXTKeyID:
+				announcement.ID = kv[1]
+			case mdnsTXTKeyAddress:
+				announcement.Address = kv[1]
+			}
+		}
+		if len(announcement.ID) > 0 && len(announcement.Address) > 0 {
+			announcements = append(announcements, announcement)
+		}
+	}
+	return announcements, nil
+}
+
+// mdnsQuery sends a query for a `service` and collects all the announcements
+// received in the next `wait` time
+func mdnsQuery(service string, wait time.Duration) ([]mdnsAnnouncement, error) {
+	serviceName, err := dnsmessage.NewName(service)
+	if err != nil {
+		return nil, err
+	}
+	query, err := (&dnsmessage.Message{
+		Questions: []dnsmessage.Question{
+			{Name: serviceName, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET},
+		},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return nil, err
+	}
+	// note well: responders reply with unicast to queries not sent from the mDNS port
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP(query, group); err != nil {
+		return nil, fmt.Errorf("could not send the mDNS query: %v", err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(wait)); err != nil {
+		return nil, err
+	}
+
+	found := map[string]mdnsAnnouncement{}
+	buf := make([]byte, mdnsMaxPacketSize)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+
+		announcements, err := mdnsParseResponse(service, buf[:n])
+		if err != nil {
+			glog.V(5).Infof("[kubic] ignoring invalid mDNS response: %s", err)
+			continue
+		}
+		for _, announcement := range announcements {
+			found[announcement.ID] = announcement
+		}
+	}
+
+	res := []mdnsAnnouncement{}
+	for _, announcement := range found {
+		res = append(res, announcement)
+	}
+	return res, nil
+}
+
+// MDNSResponder answers the mDNS queries for the services announced by this node
+type MDNSResponder struct {
+	sync.Mutex
+
+	conn *net.UDPConn
+
+	// the announcements, by service
+	announcements map[string]mdnsAnnouncement
+}
+
+// NewMDNSResponder creates a mDNS responder, listening in the interface `iface`
+// (or in the default multicast interface when nil)
+func NewMDNSResponder(iface *net.Interface) (*MDNSResponder, error) {
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", iface, group)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for mDNS queries: %v", err)
+	}
+
+	return &MDNSResponder{
+		conn:          conn,
+		announcements: map[string]mdnsAnnouncement{},
+	}, nil
+}
+
+// Announce starts announcing this node in a `service`
+func (r *MDNSResponder) Announce(service string, id string, address string) {
+	r.Lock()
+	defer r.Unlock()
+	glog.V(3).Infof("[kubic] announcing %s in %s with mDNS", address, service)
+	r.announcements[service] = mdnsAnnouncement{ID: id, Address: address}
+}
+
+// AnnounceSeeder starts announcing this node as the seeder of the cluster
+func (r *MDNSResponder) AnnounceSeeder(id string, address string) {
+	r.Announce(mdnsSeederService, id, address)
+}
+
+// Withdraw stops announcing this node in a `service`
+func (r *MDNSResponder) Withdraw(service string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.announcements, service)
+}
+
+// Serve answers the mDNS queries until the responder is closed
+func (r *MDNSResponder) Serve() {
+	buf := make([]byte, mdnsMaxPacketSize)
+	for {
+		n, src, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			glog.V(3).Infof("[kubic] mDNS responder stopped: %s", err)
+			return
+		}
+
+		msg := dnsmessage.Message{}
+		if err := msg.Unpack(buf[:n]); err != nil || msg.Header.Response {
+			continue
+		}
+
+		for _, question := range msg.Questions {
+			if question.Type != dnsmessage.TypePTR && question.Type != dnsmessage.TypeALL {
+				continue
+			}
+			service := question.Name.String()
+
+			r.Lock()
+			announcement, found := r.announcements[service]
+			r.Unlock()
+			if !found {
+				continue
+			}
+
+			response, err := mdnsResponse(service, announcement)
+			if err != nil {
+				glog.V(1).Infof("[kubic] WARNING: could not create the mDNS response: %s", err)
+				continue
+			}
+			if _, err := r.conn.WriteToUDP(response, src); err != nil {
+				glog.V(3).Infof("[kubic] could not send the mDNS response to %s: %s", src, err)
+			}
+		}
+	}
+}
+
+// Close stops the responder
+func (r *MDNSResponder) Close() error {
+	return r.conn.Close()
+}
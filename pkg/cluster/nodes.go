@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+)
+
+// the annotation used in mirror pods (ie, static pods created by the kubelet)
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// interval between checks of the Ready condition of a node
+const nodeReadyPollInterval = 5 * time.Second
+
+// GetNodesNames returns the (sorted) names of the nodes in the cluster
+func GetNodesNames(client clientset.Interface) ([]string, error) {
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetControlPlaneAddresses returns the (sorted) internal addresses of the control-plane nodes
+func GetControlPlaneAddresses(client clientset.Interface) ([]string, error) {
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{
+		LabelSelector: kubeadmconstants.LabelNodeRoleMaster,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalIP {
+				addresses = append(addresses, address.Address)
+				break
+			}
+		}
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// IsNodeCordoned returns true if the node has been marked as unschedulable
+func IsNodeCordoned(client clientset.Interface, name string) (bool, error) {
+	node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return node.Spec.Unschedulable, nil
+}
+
+// CordonNode marks a node as (un)schedulable
+func CordonNode(client clientset.Interface, name string, unschedulable bool) error {
+	node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	glog.V(3).Infof("[kubic] setting node %s as unschedulable=%t", name, unschedulable)
+	node.Spec.Unschedulable = unschedulable
+	_, err = client.CoreV1().Nodes().Update(node)
+	return err
+}
+
+// IsNodeReady returns true if the node is in the Ready condition
+func IsNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// WaitForNodeReady waits until a node is in the Ready condition
+func WaitForNodeReady(client clientset.Interface, name string, timeout time.Duration) error {
+	err := wait.PollImmediate(nodeReadyPollInterval, timeout, func() (bool, error) {
+		node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return IsNodeReady(node), nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timeout while waiting for node %s to be ready", name)
+	}
+	return err
+}
+
+// RestartPodsInNode deletes the pods running in a node, so they are re-created
+// by their controllers. Pods in the host network and mirror pods are ignored.
+// It returns the number of pods deleted.
+func RestartPodsInNode(client clientset.Interface, name string) (int, error) {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, pod := range pods.Items {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+		if _, isMirror := pod.Annotations[mirrorPodAnnotation]; isMirror {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		glog.V(3).Infof("[kubic] deleting pod %s/%s in node %s", pod.Namespace, pod.Name, name)
+		err := client.CoreV1().Pods(pod.Namespace).Delete(pod.Name, &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return deleted, fmt.Errorf("could not delete pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
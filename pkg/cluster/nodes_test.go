@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRestartPodsInNode(t *testing.T) {
+	hostPod := newTestPod("host", corev1.PodRunning, nil, "DaemonSet")
+	hostPod.Spec.HostNetwork = true
+
+	client := fake.NewSimpleClientset(
+		newTestPod("web", corev1.PodRunning, nil, "ReplicaSet"),
+		newTestPod("agent", corev1.PodRunning, nil, "DaemonSet"),
+		newTestPod("static", corev1.PodRunning, map[string]string{mirrorPodAnnotation: "x"}, ""),
+		newTestPod("finished", corev1.PodSucceeded, nil, "Job"),
+		hostPod,
+	)
+
+	count, err := RestartPodsInNode(client, "node-1")
+	if err != nil {
+		t.Fatalf("could not restart the pods: %s", err)
+	}
+	if count != 2 {
+		t.Fatalf("unexpected number of pods restarted: %d", count)
+	}
+
+	remaining, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("could not list the pods: %s", err)
+	}
+	names := map[string]bool{}
+	for _, pod := range remaining.Items {
+		names[pod.Name] = true
+	}
+	if names["web"] || names["agent"] || !names["static"] || !names["finished"] || !names["host"] {
+		t.Fatalf("unexpected pods remaining: %v", names)
+	}
+}
+
+func TestCordonNode(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+
+	for _, unschedulable := range []bool{true, true, false} {
+		if err := CordonNode(client, "node-1", unschedulable); err != nil {
+			t.Fatalf("could not cordon the node: %s", err)
+		}
+		cordoned, err := IsNodeCordoned(client, "node-1")
+		if err != nil {
+			t.Fatalf("could not get the node: %s", err)
+		}
+		if cordoned != unschedulable {
+			t.Fatalf("unexpected unschedulable=%t", cordoned)
+		}
+	}
+
+	// the node is not updated when it already is in the desired state
+	updates := 0
+	for _, action := range client.Actions() {
+		if action.Matches("update", "nodes") {
+			updates++
+		}
+	}
+	if updates != 2 {
+		t.Fatalf("unexpected number of updates: %d", updates)
+	}
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// NodeAddressesConfigMapName is the ConfigMap where the addresses registered by
+// the joining nodes are recorded (for the subnets in the approval policy)
+const NodeAddressesConfigMapName = "kubic-node-addresses"
+
+// the path for the registrations of the joining nodes
+const nodeRegistrationPath = "/register"
+
+// maximum difference between the time in a registration and the local time
+const nodeRegistrationMaxSkew = 5 * time.Minute
+
+// time waiting for the seeder when registering a node
+const nodeRegistrationTimeout = 10 * time.Second
+
+// The bootstrap tokens are stored in "kube-system/bootstrap-token-<id>" Secrets
+const (
+	bootstrapTokenSecretPrefix  = "bootstrap-token-"
+	bootstrapTokenSecretKey     = "token-secret"
+	bootstrapTokenUsageKey      = "usage-bootstrap-authentication"
+	bootstrapTokenExpirationKey = "expiration"
+)
+
+// nodeRegistration is the registration sent by a joining node, signed with its bootstrap
+// token. The token secret is never sent: the signature proves the node knows it.
+type nodeRegistration struct {
+	Node      string `json:"node"`
+	TokenID   string `json:"tokenID"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
+// signNodeRegistration returns the signature of a registration with the secret of a bootstrap token
+func signNodeRegistration(tokenSecret string, node string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	fmt.Fprintf(mac, "%s\n%d", node, timestamp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RegisterNode registers this node in the seeder before joining the cluster, so the
+// seeder can check the address of the node against the subnets of the approval policy
+func RegisterNode(kubicCfg *config.KubicInitConfiguration) error {
+	tokenID, tokenSecret, err := splitBootstrapToken(kubicCfg.ClusterFormation.Token)
+	if err != nil {
+		return err
+	}
+	// the node name used by kubeadm
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	node := strings.ToLower(strings.TrimSpace(hostname))
+
+	host, _, err := net.SplitHostPort(kubicCfg.ClusterFormation.Seeder)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(host, strconv.Itoa(config.DefaultNodeRegistrationPort)), nodeRegistrationPath)
+
+	timestamp := time.Now().Unix()
+	body, err := json.Marshal(nodeRegistration{
+		Node:      node,
+		TokenID:   tokenID,
+		Timestamp: timestamp,
+		Signature: signNodeRegistration(tokenSecret, node, timestamp),
+	})
+	if err != nil {
+		return err
+	}
+
+	glog.V(3).Infof("[kubic] registering node %q at %s", node, url)
+	client := &http.Client{Timeout: nodeRegistrationTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("registration rejected by %s: %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// splitBootstrapToken returns the ID and the secret of a bootstrap token ("<id>.<secret>")
+func splitBootstrapToken(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf("invalid bootstrap token")
+	}
+	return parts[0], parts[1], nil
+}
+
+// newNodeRegistrationHandler returns the handler for the registrations of the joining nodes.
+// The address of a node is the source address of the connection (never something sent
+// by the node), and it is only recorded when the registration is signed with a valid
+// bootstrap token.
+func newNodeRegistrationHandler(client clientset.Interface) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(nodeRegistrationPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		reg := nodeRegistration{}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&reg); err != nil {
+			http.Error(w, fmt.Sprintf("invalid registration: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(reg.Node) == 0 || len(reg.TokenID) == 0 || len(reg.Signature) == 0 {
+			http.Error(w, "invalid registration: missing node, token or signature", http.StatusBadRequest)
+			return
+		}
+		address, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "unknown source address", http.StatusBadRequest)
+			return
+		}
+
+		if err := verifyNodeRegistration(client, reg, time.Now()); err != nil {
+			glog.V(1).Infof("[kubic] WARNING: registration of %q from %s rejected: %v", reg.Node, address, err)
+			http.Error(w, "registration rejected", http.StatusForbidden)
+			return
+		}
+
+		if err := recordNodeAddress(client, reg.Node, reg.TokenID, address); err != nil {
+			glog.V(1).Infof("[kubic] ERROR: could not record the address of %q: %v", reg.Node, err)
+			http.Error(w, "could not record the registration", http.StatusInternalServerError)
+			return
+		}
+		glog.V(1).Infof("[kubic] node %q registered from %s (token: %s)", reg.Node, address, reg.TokenID)
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// verifyNodeRegistration checks a registration is recent and signed with a
+// bootstrap token that can be used for joining the cluster at `now`
+func verifyNodeRegistration(client clientset.Interface, reg nodeRegistration, now time.Time) error {
+	skew := now.Sub(time.Unix(reg.Timestamp, 0))
+	if skew > nodeRegistrationMaxSkew || skew < -nodeRegistrationMaxSkew {
+		return fmt.Errorf("the registration time differs in %s with the local time", skew)
+	}
+
+	secret, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Get(bootstrapTokenSecretPrefix+reg.TokenID, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("unknown token %s", reg.TokenID)
+		}
+		return err
+	}
+	if string(secret.Data[bootstrapTokenUsageKey]) != "true" {
+		return fmt.Errorf("token %s cannot be used for authentication", reg.TokenID)
+	}
+	if expiration := string(secret.Data[bootstrapTokenExpirationKey]); len(expiration) > 0 {
+		t, err := time.Parse(time.RFC3339, expiration)
+		if err != nil || now.After(t) {
+			return fmt.Errorf("token %s has expired", reg.TokenID)
+		}
+	}
+
+	expected := signNodeRegistration(string(secret.Data[bootstrapTokenSecretKey]), reg.Node, reg.Timestamp)
+	if !hmac.Equal([]byte(expected), []byte(reg.Signature)) {
+		return fmt.Errorf("invalid signature for token %s", reg.TokenID)
+	}
+	return nil
+}
+
+// getNodeAddress returns the bootstrap token and the address registered by a node
+func getNodeAddress(client clientset.Interface, nodeName string) (string, string, error) {
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(NodeAddressesConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	parts := strings.SplitN(cm.Data[nodeName], ",", 2)
+	if len(parts) != 2 {
+		return "", "", nil
+	}
+	return parts[0], parts[1], nil
+}
+
+// recordNodeAddress records the address registered by a node with a bootstrap token
+// (replacing any previous registration of the node)
+func recordNodeAddress(client clientset.Interface, nodeName, tokenID, address string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return updateNodeAddresses(client, func(data map[string]string) {
+			data[nodeName] = tokenID + "," + address
+		})
+	})
+}
+
+// forgetNodeAddress removes the address registered by a node (ie, when the node is removed from the cluster)
+func forgetNodeAddress(client clientset.Interface, nodeName string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return updateNodeAddresses(client, func(data map[string]string) {
+			delete(data, nodeName)
+		})
+	})
+}
+
+// updateNodeAddresses updates the registered addresses with `update`, creating the ConfigMap if necessary
+func updateNodeAddresses(client clientset.Interface, update func(data map[string]string)) error {
+	cms := client.CoreV1().ConfigMaps(metav1.NamespaceSystem)
+	cm, err := cms.Get(NodeAddressesConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		data := map[string]string{}
+		update(data)
+		if len(data) == 0 {
+			return nil
+		}
+		_, err = cms.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      NodeAddressesConfigMapName,
+				Namespace: metav1.NamespaceSystem,
+			},
+			Data: data,
+		})
+		if apierrors.IsAlreadyExists(err) {
+			// created concurrently: retry with an update
+			return apierrors.NewConflict(corev1.Resource("configmaps"), NodeAddressesConfigMapName, err)
+		}
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	update(cm.Data)
+	_, err = cms.Update(cm)
+	return err
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestBootstrapToken creates the Secret for a bootstrap token
+func newTestBootstrapToken(tokenID, tokenSecret string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootstrapTokenSecretPrefix + tokenID,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Data: map[string][]byte{
+			"token-id":              []byte(tokenID),
+			bootstrapTokenSecretKey: []byte(tokenSecret),
+			bootstrapTokenUsageKey:  []byte("true"),
+		},
+	}
+}
+
+func TestNodeRegistration(t *testing.T) {
+	client := fake.NewSimpleClientset(newTestBootstrapToken("abcdef", "0123456789abcdef"))
+	handler := newNodeRegistrationHandler(client)
+
+	register := func(reg nodeRegistration, remoteAddr string) int {
+		body, err := json.Marshal(reg)
+		if err != nil {
+			t.Fatalf("could not encode the registration: %s", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, nodeRegistrationPath, bytes.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		t.Logf("registration of %q from %s: %d %s", reg.Node, remoteAddr, rec.Code, rec.Body.String())
+		return rec.Code
+	}
+	signed := func(node, tokenID, tokenSecret string, when time.Time) nodeRegistration {
+		return nodeRegistration{
+			Node:      node,
+			TokenID:   tokenID,
+			Timestamp: when.Unix(),
+			Signature: signNodeRegistration(tokenSecret, node, when.Unix()),
+		}
+	}
+
+	now := time.Now()
+	if code := register(signed("worker-1", "abcdef", "0123456789abcdef", now), "10.0.0.5:34567"); code != http.StatusOK {
+		t.Fatalf("valid registration rejected: %d", code)
+	}
+
+	// the address recorded is the source address of the connection
+	tokenID, address, err := getNodeAddress(client, "worker-1")
+	if err != nil {
+		t.Fatalf("could not get the registered address: %s", err)
+	}
+	if tokenID != "abcdef" || address != "10.0.0.5" {
+		t.Fatalf("unexpected registration: token=%q address=%q", tokenID, address)
+	}
+
+	rejected := map[string]nodeRegistration{
+		"wrong secret":   signed("worker-2", "abcdef", "fedcba9876543210", now),
+		"unknown token":  signed("worker-2", "fedcba", "0123456789abcdef", now),
+		"old timestamp":  signed("worker-2", "abcdef", "0123456789abcdef", now.Add(-time.Hour)),
+		"signature copy": {Node: "worker-2", TokenID: "abcdef", Timestamp: now.Unix(), Signature: signNodeRegistration("0123456789abcdef", "worker-1", now.Unix())},
+	}
+	for name, reg := range rejected {
+		if code := register(reg, "10.0.0.6:34567"); code != http.StatusForbidden {
+			t.Fatalf("%s: registration not rejected: %d", name, code)
+		}
+	}
+	if _, address, _ := getNodeAddress(client, "worker-2"); len(address) > 0 {
+		t.Fatalf("address recorded for a rejected registration: %s", address)
+	}
+
+	// removed nodes forget their address
+	if err := forgetNodeAddress(client, "worker-1"); err != nil {
+		t.Fatalf("could not forget the address: %s", err)
+	}
+	if _, address, _ := getNodeAddress(client, "worker-1"); len(address) > 0 {
+		t.Fatalf("address not forgotten: %s", address)
+	}
+}
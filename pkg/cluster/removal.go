@@ -0,0 +1,272 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+const (
+	// ResetServiceName is the systemd service that resets a node (see the "init" directory)
+	ResetServiceName = "kubic-init-reset.service"
+
+	// DefaultRemovalTimeout is the default max time for draining a node and for resetting it
+	DefaultRemovalTimeout = 5 * time.Minute
+
+	// the annotation set in the node once its etcd member has been removed
+	// (so the removal can be retried after a failure in a later step)
+	etcdMemberRemovedAnnotation = "kubic-init/etcd-member-removed"
+)
+
+// RemovalOptions are the options for removing a node from the cluster
+type RemovalOptions struct {
+	// Reset is true when the node should be reset (with the reset service) after being drained
+	Reset bool
+
+	// Image is the image used for starting the reset service in the node
+	Image string
+
+	// DryRun is true when only the steps to perform should be printed
+	DryRun bool
+
+	// Timeout is the max time for draining the node and for resetting it
+	Timeout time.Duration
+
+	// Out is the output for the dry-run mode and the progress messages
+	Out io.Writer
+}
+
+// removal is a node removal in progress
+type removal struct {
+	RemovalOptions
+
+	clients *kubicclient.Clients
+	cfg     *config.KubicInitConfiguration
+	node    *corev1.Node
+}
+
+// RemoveNode removes a node from a running cluster: the node is cordoned and drained
+// (honoring the PodDisruptionBudgets), its etcd member is removed (for control-plane
+// nodes), it is optionally reset and, finally, the Node object is deleted.
+// This must be run in a control-plane node, as the etcd client certificates are needed
+// for removing etcd members.
+func RemoveNode(clients *kubicclient.Clients, name string, options RemovalOptions) error {
+	if options.Timeout == 0 {
+		options.Timeout = DefaultRemovalTimeout
+	}
+	if len(options.Image) == 0 {
+		options.Image = config.DefaultKubicInitImage
+	}
+
+	node, err := clients.Kubernetes.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not get node %s: %v", name, err)
+	}
+
+	cfg, err := config.FromConfigMap(clients.Kubernetes, config.DefaultKubicInitConfigmap)
+	if err != nil {
+		return fmt.Errorf("could not read the cluster configuration: %v", err)
+	}
+
+	r := removal{
+		RemovalOptions: options,
+		clients:        clients,
+		cfg:            cfg,
+		node:           node,
+	}
+	return r.run()
+}
+
+// isControlPlane returns true if the node being removed is a control-plane node
+func (r *removal) isControlPlane() bool {
+	_, isMaster := r.node.Labels[kubeadmconstants.LabelNodeRoleMaster]
+	return isMaster
+}
+
+// nodeAddress returns the internal address of the node being removed
+func (r *removal) nodeAddress() (string, error) {
+	for _, address := range r.node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address, nil
+		}
+	}
+	return "", fmt.Errorf("no internal address found for node %s", r.node.Name)
+}
+
+// etcdPeerURLs returns the peer URLs the etcd member in the node being removed could be
+// using, one for each address of the node
+func (r *removal) etcdPeerURLs() []string {
+	urls := []string{}
+	for _, address := range r.node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP || address.Type == corev1.NodeExternalIP {
+			urls = append(urls, GetEtcdPeerURL(address.Address))
+		}
+	}
+	return urls
+}
+
+// removeEtcdMember removes the etcd member of the node being removed, annotating the node
+// once it has been removed
+func (r *removal) removeEtcdMember(endpoints []string) error {
+	if _, removed := r.node.Annotations[etcdMemberRemovedAnnotation]; removed {
+		glog.V(1).Infof("[kubic] the etcd member of %s has already been removed", r.node.Name)
+		return nil
+	}
+
+	if err := RemoveEtcdMember(endpoints, r.cfg.Certificates.Directory, r.node.Name, r.etcdPeerURLs()); err != nil {
+		return err
+	}
+
+	node, err := r.clients.Kubernetes.CoreV1().Nodes().Get(r.node.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[etcdMemberRemovedAnnotation] = "true"
+	_, err = r.clients.Kubernetes.CoreV1().Nodes().Update(node)
+	return err
+}
+
+func (r *removal) run() error {
+	name := r.node.Name
+
+	var etcdEndpoints []string
+	if r.isControlPlane() {
+		masters, err := GetControlPlaneAddresses(r.clients.Kubernetes)
+		if err != nil {
+			return err
+		}
+		address, err := r.nodeAddress()
+		if err != nil {
+			return err
+		}
+		for _, master := range masters {
+			if master != address {
+				etcdEndpoints = append(etcdEndpoints, GetEtcdClientURL(master))
+			}
+		}
+		if len(etcdEndpoints) == 0 {
+			return fmt.Errorf("%s is the last control-plane node: it cannot be removed", name)
+		}
+	}
+
+	r.printf("removing node %s", name)
+
+	if err := r.step(fmt.Sprintf("cordon node %s", name), func() error {
+		return CordonNode(r.clients.Kubernetes, name, true)
+	}); err != nil {
+		return err
+	}
+
+	if err := r.step(fmt.Sprintf("drain node %s", name), func() error {
+		evicted, err := DrainNode(r.clients.Kubernetes, name, r.Timeout)
+		if err != nil {
+			return err
+		}
+		r.printf("%d pods evicted from %s", evicted, name)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(etcdEndpoints) > 0 {
+		if r.cfg.Etcd.LocalEtcd == nil {
+			glog.V(1).Infoln("[kubic] not using a local etcd: no etcd member to remove")
+		} else if err := r.step(fmt.Sprintf("remove the etcd member of %s", name), func() error {
+			return r.removeEtcdMember(etcdEndpoints)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if r.Reset {
+		if err := r.step(fmt.Sprintf("reset node %s (starting %s with a Job)", name, ResetServiceName), r.reset); err != nil {
+			return err
+		}
+	}
+
+	if err := r.step(fmt.Sprintf("delete node %s", name), func() error {
+		err := r.clients.Kubernetes.CoreV1().Nodes().Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := forgetApprovedNode(r.clients.Kubernetes, name); err != nil {
+			return err
+		}
+		return forgetNodeAddress(r.clients.Kubernetes, name)
+	}); err != nil {
+		return err
+	}
+
+	r.printf("node %s removed", name)
+	return nil
+}
+
+// step runs a step of the removal (or just prints it in dry-run mode)
+func (r *removal) step(descr string, f func() error) error {
+	if r.DryRun {
+		fmt.Fprintf(r.Out, "[dry-run] would %s\n", descr)
+		return nil
+	}
+
+	r.printf("%s...", descr)
+	if err := f(); err != nil {
+		return fmt.Errorf("removal aborted: could not %s: %v", descr, err)
+	}
+	return nil
+}
+
+func (r *removal) printf(format string, args ...interface{}) {
+	glog.V(1).Infof("[kubic] "+format, args...)
+	if r.Out != nil && !r.DryRun {
+		fmt.Fprintf(r.Out, "[node-remove] "+format+"\n", args...)
+	}
+}
+
+// reset starts the reset service in the node with a Job, waiting until the Job
+// has queued the service (but not until the reset finishes).
+// Note well: "kubic-init reset" cannot run in this Job, as it would remove
+// all the containers in the node (including itself), so the Job just starts
+// the reset service in the host without waiting for it ("--no-block"): the
+// reset stops the kubelet, so nothing in the node could report its result.
+func (r *removal) reset() error {
+	err := RunNodeJob(r.clients.Kubernetes, NodeJob{
+		App:       "kubic-reset",
+		Node:      r.node.Name,
+		Image:     r.Image,
+		Command:   []string{"systemctl", "start", "--no-block", ResetServiceName},
+		HostPaths: []string{"/var/run/dbus", "/usr/lib/systemd", "/run/systemd"},
+	}, r.Timeout)
+	if err != nil {
+		return fmt.Errorf("%v (is %s installed in %s?)", err, ResetServiceName, r.node.Name)
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package calico
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientset "k8s.io/client-go/kubernetes"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/apiclient"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/cni"
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/loader"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+const (
+	// CalicoClusterRoleName sets the name for the calico ClusterRole
+	CalicoClusterRoleName = "kubic:calico"
+
+	// the PSP cluster role
+	CalicoClusterRoleNamePSP = "kubic:psp:calico"
+
+	// CalicoServiceAccountName describes the name of the ServiceAccount for the calico addon
+	CalicoServiceAccountName = "kubic-calico"
+
+	// Default health port for Calico
+	CalicoHealthPort = 9099
+)
+
+// encapsulations supported
+const (
+	EncapsulationIPIP = "ipip"
+
+	EncapsulationVXLAN = "vxlan"
+
+	EncapsulationNone = "none"
+)
+
+var (
+	serviceAccount = v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CalicoServiceAccountName,
+			Namespace: metav1.NamespaceSystem,
+		},
+	}
+
+	clusterRole = rbac.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CalicoClusterRoleName,
+		},
+		Rules: []rbac.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "nodes", "namespaces", "serviceaccounts", "endpoints", "services"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods/status", "nodes", "nodes/status"},
+				Verbs:     []string{"patch"},
+			},
+			{
+				APIGroups: []string{"networking.k8s.io"},
+				Resources: []string{"networkpolicies"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{CalicoCRDsGroup},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			},
+		},
+	}
+
+	clusterRoleBinding = rbac.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CalicoClusterRoleName,
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "ClusterRole",
+			Name:     CalicoClusterRoleName,
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      rbac.ServiceAccountKind,
+				Name:      CalicoServiceAccountName,
+				Namespace: metav1.NamespaceSystem,
+			},
+		},
+	}
+
+	clusterRoleBindingPSP = rbac.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CalicoClusterRoleNamePSP,
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "suse:kubic:psp:privileged",
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      rbac.ServiceAccountKind,
+				Name:      CalicoServiceAccountName,
+				Namespace: metav1.NamespaceSystem,
+			},
+		},
+	}
+)
+
+// the name of the objects created for calico
+const (
+	calicoConfigMapName = "calico-config"
+
+	calicoDaemonSetName = "calico-node"
+)
+
+func init() {
+	// self-register in the CNI plugins registry
+	cni.Registry.Register("calico", &CalicoPlugin{})
+
+	// check the calico configuration when calico is used
+	config.RegisterValidation(func(cfg *config.KubicInitConfiguration) field.ErrorList {
+		allErrs := field.ErrorList{}
+		if cfg.Network.Cni.Driver != "calico" {
+			return allErrs
+		}
+		supported := sets.NewString(EncapsulationIPIP, EncapsulationVXLAN, EncapsulationNone)
+		if encapsulation := cfg.Network.Cni.Calico.Encapsulation; !supported.Has(encapsulation) {
+			allErrs = append(allErrs, field.NotSupported(field.NewPath("network", "cni", "calico", "encapsulation"),
+				encapsulation, supported.List()))
+		}
+		return allErrs
+	})
+
+	// remove the calico interfaces and state when resetting a node
+	// (the "tunl0" interface used by "ipip" cannot be removed)
+	reset.Register(reset.Step{
+		Name:        "calico",
+		Description: "remove the calico network interfaces",
+		Enabled: func(cfg *config.KubicInitConfiguration) bool {
+			return cfg.Network.Cni.Driver == "calico"
+		},
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			return []string{"/var/run/calico", "/var/lib/calico"}
+		},
+		Run: func(cfg *config.KubicInitConfiguration) error {
+			return reset.DeleteInterfaces("vxlan.calico")
+		},
+	})
+}
+
+// CalicoPlugin is the calico CNI plugin
+type CalicoPlugin struct{}
+
+// Describe returns a description of the calico plugin
+func (CalicoPlugin) Describe() cni.CniPluginDescription {
+	return cni.CniPluginDescription{
+		Description: "calico: L3 networking, with NetworkPolicy support",
+		Options: map[string]string{
+			"network.cni.image":                "the calico-node image",
+			"network.cni.binDir":               "directory for the CNI binaries in the host",
+			"network.cni.confDir":              "directory for the CNI configuration in the host",
+			"network.cni.calico.encapsulation": "encapsulation between nodes: ipip, vxlan or none",
+			"network.cni.calico.cniImage":      "the image with the calico CNI plugin",
+			"network.podSubnet":                "the subnet used for the default IP pool",
+		},
+		ConfFiles: []string{"10-calico.conflist", "calico-kubeconfig"},
+		Families:  []string{kubicutil.FamilyIPv4, kubicutil.FamilyIPv6},
+	}
+}
+
+// Install creates the calico CRDs and addons
+func (CalicoPlugin) Install(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	// the CRDs must be present before calico-node starts
+	crdOptions := loader.CRDInstallOptions{CRDs: loader.NewCRDsSet(calicoCRDs...)}
+	if err := loader.InstallCRDs(cfg, clients, crdOptions); err != nil {
+		return fmt.Errorf("error when creating calico CRDs: %v", err)
+	}
+
+	if err := createServiceAccount(clients.Kubernetes); err != nil {
+		return fmt.Errorf("error when creating calico service account: %v", err)
+	}
+
+	var calicoConfigMapBytes, calicoDaemonSetBytes []byte
+	calicoConfigMapBytes, err := kubeadmutil.ParseTemplate(CalicoConfigMap,
+		struct {
+			Backend     string
+			IPv4Network string
+			IPv6Network string
+		}{
+			getBackend(cfg.Network.Cni.Calico.Encapsulation),
+			cfg.Network.GetPodSubnet(kubicutil.FamilyIPv4),
+			cfg.Network.GetPodSubnet(kubicutil.FamilyIPv6),
+		})
+
+	if err != nil {
+		return fmt.Errorf("error when parsing calico configmap template: %v", err)
+	}
+
+	calicoDaemonSetBytes, err = kubeadmutil.ParseTemplate(CalicoDaemonSet,
+		struct {
+			Image          string
+			CniImage       string
+			IPv4Network    string
+			IPv6Network    string
+			IPIPMode       string
+			VXLANMode      string
+			HealthzPort    int
+			ConfDir        string
+			BinDir         string
+			ServiceAccount string
+		}{
+			cfg.GetCniImage(),
+			cfg.Network.Cni.Calico.CniImage,
+			cfg.Network.GetPodSubnet(kubicutil.FamilyIPv4),
+			cfg.Network.GetPodSubnet(kubicutil.FamilyIPv6),
+			getEncapsulationMode(cfg.Network.Cni.Calico.Encapsulation, EncapsulationIPIP),
+			getEncapsulationMode(cfg.Network.Cni.Calico.Encapsulation, EncapsulationVXLAN),
+			CalicoHealthPort,
+			cfg.Network.Cni.ConfDir,
+			cfg.Network.Cni.BinDir,
+			CalicoServiceAccountName,
+		})
+
+	if err != nil {
+		return fmt.Errorf("error when parsing calico daemonset template: %v", err)
+	}
+
+	if err := createCalicoAddon(calicoConfigMapBytes, calicoDaemonSetBytes, clients.Kubernetes); err != nil {
+		return err
+	}
+
+	if err := createRBACRules(clients.Kubernetes, cfg.Features.PSP); err != nil {
+		return fmt.Errorf("error when creating calico RBAC rules: %v", err)
+	}
+
+	glog.V(1).Infof("[kubic] installed calico CNI driver (encapsulation: %s)", cfg.Network.Cni.Calico.Encapsulation)
+	return nil
+}
+
+// Upgrade upgrades calico: the CRDs are updated and the DaemonSet is updated with a rolling update
+func (p CalicoPlugin) Upgrade(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	return p.Install(cfg, clients)
+}
+
+// Status returns the rollout status of the calico-node DaemonSet
+func (CalicoPlugin) Status(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) (cni.CniPluginStatus, error) {
+	return cni.DaemonSetStatus(clients.Kubernetes, metav1.NamespaceSystem, calicoDaemonSetName)
+}
+
+// Uninstall removes all the calico objects, including the CRDs
+func (CalicoPlugin) Uninstall(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	client := clients.Kubernetes
+	deleteOptions := cni.DeleteOptions()
+
+	if err := client.AppsV1().DaemonSets(metav1.NamespaceSystem).Delete(calicoDaemonSetName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Delete(calicoConfigMapName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	for _, name := range []string{CalicoClusterRoleNamePSP, CalicoClusterRoleName} {
+		if err := client.RbacV1().ClusterRoleBindings().Delete(name, deleteOptions); cni.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if err := client.RbacV1().ClusterRoles().Delete(CalicoClusterRoleName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := client.CoreV1().ServiceAccounts(metav1.NamespaceSystem).Delete(CalicoServiceAccountName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	for _, crd := range calicoCRDs {
+		if err := clients.APIExtensions.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(crd.Name, deleteOptions); cni.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	glog.V(1).Infof("[kubic] calico CNI driver removed")
+	return nil
+}
+
+// getBackend returns the calico networking backend for an encapsulation
+// (BGP is used for routing unless VXLAN is used)
+func getBackend(encapsulation string) string {
+	if encapsulation == EncapsulationVXLAN {
+		return "vxlan"
+	}
+	return "bird"
+}
+
+// getEncapsulationMode returns the mode ("Always" or "Never") for the encapsulation `mode`
+func getEncapsulationMode(encapsulation string, mode string) string {
+	if encapsulation == mode {
+		return "Always"
+	}
+	return "Never"
+}
+
+func createServiceAccount(client clientset.Interface) error {
+	return apiclient.CreateOrUpdateServiceAccount(client, &serviceAccount)
+}
+
+func createCalicoAddon(configMapBytes, daemonSetbytes []byte, client clientset.Interface) error {
+	calicoConfigMap := &v1.ConfigMap{}
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), configMapBytes, calicoConfigMap); err != nil {
+		return fmt.Errorf("unable to decode calico configmap %v", err)
+	}
+
+	// Create the ConfigMap for calico or update it in case it already exists
+	if err := apiclient.CreateOrUpdateConfigMap(client, calicoConfigMap); err != nil {
+		return err
+	}
+
+	calicoDaemonSet := &apps.DaemonSet{}
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), daemonSetbytes, calicoDaemonSet); err != nil {
+		return fmt.Errorf("unable to decode calico daemonset %v", err)
+	}
+
+	// Create the DaemonSet for calico or update it in case it already exists
+	return apiclient.CreateOrUpdateDaemonSet(client, calicoDaemonSet)
+}
+
+// createRBACRules creates the RBAC rules needed by calico
+func createRBACRules(client clientset.Interface, psp bool) error {
+	var err error
+
+	if err = apiclient.CreateOrUpdateClusterRole(client, &clusterRole); err != nil {
+		return err
+	}
+
+	if err = apiclient.CreateOrUpdateClusterRoleBinding(client, &clusterRoleBinding); err != nil {
+		return err
+	}
+
+	if psp {
+		if err = apiclient.CreateOrUpdateClusterRoleBinding(client, &clusterRoleBindingPSP); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package calico
+
+import (
+	"testing"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+func TestValidateEncapsulation(t *testing.T) {
+	tests := []struct {
+		driver        string
+		encapsulation string
+		valid         bool
+	}{
+		{"calico", EncapsulationIPIP, true},
+		{"calico", EncapsulationVXLAN, true},
+		{"calico", EncapsulationNone, true},
+		{"calico", "gre", false},
+		{"flannel", "gre", true},
+	}
+
+	for _, test := range tests {
+		cfg, err := config.BytesToKubicInitConfig([]byte{}, false)
+		if err != nil {
+			t.Fatalf("Error: could not get a default configuration: %s", err)
+		}
+		cfg.Network.Cni.Driver = test.driver
+		cfg.Network.Cni.Calico.Encapsulation = test.encapsulation
+
+		found := false
+		for _, e := range cfg.Validate() {
+			if e.Field == "network.cni.calico.encapsulation" {
+				t.Logf("Validation error (driver %s): %s", test.driver, e)
+				found = true
+			}
+		}
+		if found == test.valid {
+			t.Fatalf("Error: unexpected validation result for encapsulation %q with driver %s", test.encapsulation, test.driver)
+		}
+	}
+}
+
+func TestEncapsulationModes(t *testing.T) {
+	if getEncapsulationMode(EncapsulationIPIP, EncapsulationIPIP) != "Always" ||
+		getEncapsulationMode(EncapsulationIPIP, EncapsulationVXLAN) != "Never" {
+		t.Fatalf("Error: wrong modes for IPIP")
+	}
+	if getBackend(EncapsulationVXLAN) != "vxlan" || getBackend(EncapsulationNone) != "bird" {
+		t.Fatalf("Error: wrong backends")
+	}
+}
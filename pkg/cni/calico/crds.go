@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package calico
+
+import (
+	"strings"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CalicoCRDsGroup is the API group for the Calico CRDs
+	CalicoCRDsGroup = "crd.projectcalico.org"
+
+	// CalicoCRDsVersion is the version of the Calico CRDs
+	CalicoCRDsVersion = "v1"
+)
+
+// the CRDs used by Calico when using the Kubernetes datastore
+var calicoCRDs = []*apiextensionsv1beta1.CustomResourceDefinition{
+	newCalicoCRD("felixconfigurations", "FelixConfiguration", false),
+	newCalicoCRD("ipamblocks", "IPAMBlock", false),
+	newCalicoCRD("blockaffinities", "BlockAffinity", false),
+	newCalicoCRD("ipamhandles", "IPAMHandle", false),
+	newCalicoCRD("ipamconfigs", "IPAMConfig", false),
+	newCalicoCRD("bgppeers", "BGPPeer", false),
+	newCalicoCRD("bgpconfigurations", "BGPConfiguration", false),
+	newCalicoCRD("ippools", "IPPool", false),
+	newCalicoCRD("hostendpoints", "HostEndpoint", false),
+	newCalicoCRD("clusterinformations", "ClusterInformation", false),
+	newCalicoCRD("globalnetworkpolicies", "GlobalNetworkPolicy", false),
+	newCalicoCRD("globalnetworksets", "GlobalNetworkSet", false),
+	newCalicoCRD("networkpolicies", "NetworkPolicy", true),
+	newCalicoCRD("networksets", "NetworkSet", true),
+}
+
+// newCalicoCRD creates a CRD in the Calico API group
+func newCalicoCRD(plural, kind string, namespaced bool) *apiextensionsv1beta1.CustomResourceDefinition {
+	scope := apiextensionsv1beta1.ClusterScoped
+	if namespaced {
+		scope = apiextensionsv1beta1.NamespaceScoped
+	}
+
+	return &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: plural + "." + CalicoCRDsGroup,
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   CalicoCRDsGroup,
+			Version: CalicoCRDsVersion,
+			Scope:   scope,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:   plural,
+				Singular: strings.ToLower(kind),
+				Kind:     kind,
+			},
+		},
+	}
+}
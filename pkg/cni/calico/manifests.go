@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package calico
+
+const (
+	CalicoConfigMap = `
+kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: calico-config
+  namespace: kube-system
+  labels:
+    tier: node
+    app: calico
+data:
+  calico_backend: "{{ .Backend }}"
+  veth_mtu: "1440"
+  cni_network_config: |-
+    {
+      "name": "k8s-pod-network",
+      "cniVersion": "0.3.1",
+      "plugins": [
+        {
+          "type": "calico",
+          "log_level": "info",
+          "datastore_type": "kubernetes",
+          "nodename": "__KUBERNETES_NODE_NAME__",
+          "mtu": __CNI_MTU__,
+          "ipam": {
+              "type": "calico-ipam",
+              "assign_ipv4": "{{ if .IPv4Network }}true{{ else }}false{{ end }}",
+              "assign_ipv6": "{{ if .IPv6Network }}true{{ else }}false{{ end }}"
+          },
+          "policy": {
+              "type": "k8s"
+          },
+          "kubernetes": {
+              "kubeconfig": "__KUBECONFIG_FILEPATH__"
+          }
+        },
+        {
+          "type": "portmap",
+          "snat": true,
+          "capabilities": {"portMappings": true}
+        }
+      ]
+    }
+`
+
+	CalicoDaemonSet = `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: calico-node
+  namespace: kube-system
+  labels:
+    tier: node
+    k8s-app: calico-node
+spec:
+  selector:
+    matchLabels:
+      tier: node
+      k8s-app: calico-node
+  template:
+    metadata:
+      labels:
+        tier: node
+        k8s-app: calico-node
+    spec:
+      serviceAccountName: {{ .ServiceAccount }}
+      terminationGracePeriodSeconds: 0
+      priorityClassName: system-node-critical
+      initContainers:
+      - name: install-cni
+        image: {{ .CniImage }}
+        command: ["/install-cni.sh"]
+        env:
+        - name: CNI_CONF_NAME
+          value: "10-calico.conflist"
+        - name: CNI_NETWORK_CONFIG
+          valueFrom:
+            configMapKeyRef:
+              name: calico-config
+              key: cni_network_config
+        - name: KUBERNETES_NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+        - name: CNI_MTU
+          valueFrom:
+            configMapKeyRef:
+              name: calico-config
+              key: veth_mtu
+        - name: SLEEP
+          value: "false"
+        volumeMounts:
+        - name: host-cni-bin
+          mountPath: /host/opt/cni/bin
+        - name: host-cni-conf
+          mountPath: /host/etc/cni/net.d
+      containers:
+      - name: calico-node
+        image: {{ .Image }}
+        env:
+        - name: DATASTORE_TYPE
+          value: "kubernetes"
+        - name: WAIT_FOR_DATASTORE
+          value: "true"
+        - name: NODENAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+        - name: CALICO_NETWORKING_BACKEND
+          valueFrom:
+            configMapKeyRef:
+              name: calico-config
+              key: calico_backend
+        - name: CLUSTER_TYPE
+          value: "k8s,bgp"
+        - name: IP
+          value: "{{ if .IPv4Network }}autodetect{{ else }}none{{ end }}"
+        - name: IP6
+          value: "{{ if .IPv6Network }}autodetect{{ else }}none{{ end }}"
+        # the default IP pools, created from the pods subnets
+{{- if .IPv4Network }}
+        - name: CALICO_IPV4POOL_CIDR
+          value: "{{ .IPv4Network }}"
+        - name: CALICO_IPV4POOL_IPIP
+          value: "{{ .IPIPMode }}"
+        - name: CALICO_IPV4POOL_VXLAN
+          value: "{{ .VXLANMode }}"
+{{- end }}
+{{- if .IPv6Network }}
+        - name: CALICO_IPV6POOL_CIDR
+          value: "{{ .IPv6Network }}"
+        - name: CALICO_IPV6POOL_NAT_OUTGOING
+          value: "true"
+{{- end }}
+        - name: FELIX_IPINIPMTU
+          valueFrom:
+            configMapKeyRef:
+              name: calico-config
+              key: veth_mtu
+        - name: CALICO_DISABLE_FILE_LOGGING
+          value: "true"
+        - name: FELIX_DEFAULTENDPOINTTOHOSTACTION
+          value: "ACCEPT"
+        - name: FELIX_IPV6SUPPORT
+          value: "{{ if .IPv6Network }}true{{ else }}false{{ end }}"
+        - name: FELIX_LOGSEVERITYSCREEN
+          value: "info"
+        - name: FELIX_HEALTHENABLED
+          value: "true"
+        - name: FELIX_HEALTHPORT
+          value: "{{ .HealthzPort }}"
+        securityContext:
+          privileged: true
+        resources:
+          requests:
+            cpu: 250m
+        livenessProbe:
+          httpGet:
+            path: /liveness
+            port: {{ .HealthzPort }}
+            host: localhost
+          periodSeconds: 10
+          initialDelaySeconds: 10
+          failureThreshold: 6
+        readinessProbe:
+          httpGet:
+            path: /readiness
+            port: {{ .HealthzPort }}
+            host: localhost
+          periodSeconds: 10
+        volumeMounts:
+        - name: lib-modules
+          mountPath: /lib/modules
+          readOnly: true
+        - name: xtables-lock
+          mountPath: /run/xtables.lock
+          readOnly: false
+        - name: var-run-calico
+          mountPath: /var/run/calico
+          readOnly: false
+        - name: var-lib-calico
+          mountPath: /var/lib/calico
+          readOnly: false
+      hostNetwork: true
+      nodeSelector:
+        beta.kubernetes.io/os: linux
+      tolerations:
+        # Make sure calico-node gets scheduled on all nodes.
+        - effect: NoSchedule
+          operator: Exists
+        # Mark the pod as a critical add-on for rescheduling.
+        - key: CriticalAddonsOnly
+          operator: Exists
+        - effect: NoExecute
+          operator: Exists
+      volumes:
+        - name: lib-modules
+          hostPath:
+            path: /lib/modules
+        - name: var-run-calico
+          hostPath:
+            path: /var/run/calico
+        - name: var-lib-calico
+          hostPath:
+            path: /var/lib/calico
+        - name: xtables-lock
+          hostPath:
+            path: /run/xtables.lock
+            type: FileOrCreate
+        - name: host-cni-bin
+          hostPath:
+            path: {{ .BinDir }}
+        - name: host-cni-conf
+          hostPath:
+            path: {{ .ConfDir }}
+  updateStrategy:
+    rollingUpdate:
+      maxUnavailable: 1
+    type: RollingUpdate
+`
+)
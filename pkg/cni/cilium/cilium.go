@@ -0,0 +1,319 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cilium
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/apiclient"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/cni"
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+const (
+	// CiliumClusterRoleName sets the name for the cilium ClusterRole
+	CiliumClusterRoleName = "kubic:cilium"
+
+	// the PSP cluster role
+	CiliumClusterRoleNamePSP = "kubic:psp:cilium"
+
+	// CiliumServiceAccountName describes the name of the ServiceAccount for the cilium addon
+	CiliumServiceAccountName = "kubic-cilium"
+
+	// Default health port for Cilium
+	CiliumHealthPort = 9876
+)
+
+var (
+	serviceAccount = v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CiliumServiceAccountName,
+			Namespace: metav1.NamespaceSystem,
+		},
+	}
+
+	clusterRole = rbac.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CiliumClusterRoleName,
+		},
+		Rules: []rbac.PolicyRule{
+			{
+				APIGroups: []string{"networking.k8s.io"},
+				Resources: []string{"networkpolicies"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"namespaces", "services", "endpoints", "pods", "nodes", "componentstatuses"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "nodes"},
+				Verbs:     []string{"update"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes", "nodes/status"},
+				Verbs:     []string{"patch"},
+			},
+			{
+				APIGroups: []string{"extensions"},
+				Resources: []string{"ingresses"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"apiextensions.k8s.io"},
+				Resources: []string{"customresourcedefinitions"},
+				Verbs:     []string{"create", "get", "list", "watch", "update"},
+			},
+			{
+				APIGroups: []string{"cilium.io"},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			},
+		},
+	}
+
+	clusterRoleBinding = rbac.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CiliumClusterRoleName,
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "ClusterRole",
+			Name:     CiliumClusterRoleName,
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      rbac.ServiceAccountKind,
+				Name:      CiliumServiceAccountName,
+				Namespace: metav1.NamespaceSystem,
+			},
+		},
+	}
+
+	clusterRoleBindingPSP = rbac.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CiliumClusterRoleNamePSP,
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "suse:kubic:psp:privileged",
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      rbac.ServiceAccountKind,
+				Name:      CiliumServiceAccountName,
+				Namespace: metav1.NamespaceSystem,
+			},
+		},
+	}
+)
+
+// the name of the objects created for cilium
+const (
+	ciliumConfigMapName = "cilium-config"
+
+	ciliumDaemonSetName = "cilium"
+)
+
+func init() {
+	// self-register in the CNI plugins registry
+	cni.Registry.Register("cilium", &CiliumPlugin{})
+
+	// remove the cilium interfaces and state when resetting a node
+	reset.Register(reset.Step{
+		Name:        "cilium",
+		Description: "remove the cilium network interfaces",
+		Enabled: func(cfg *config.KubicInitConfiguration) bool {
+			return cfg.Network.Cni.Driver == "cilium"
+		},
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			return []string{"/var/run/cilium"}
+		},
+		Run: func(cfg *config.KubicInitConfiguration) error {
+			return reset.DeleteInterfaces("cilium_vxlan", "cilium_host", "cilium_net")
+		},
+	})
+}
+
+// CiliumPlugin is the cilium CNI plugin
+type CiliumPlugin struct{}
+
+// Describe returns a description of the cilium plugin
+func (CiliumPlugin) Describe() cni.CniPluginDescription {
+	return cni.CniPluginDescription{
+		Description: "cilium: BPF-based networking, with NetworkPolicy support",
+		Options: map[string]string{
+			"network.cni.image":   "the cilium image",
+			"network.cni.binDir":  "directory for the CNI binaries in the host",
+			"network.cni.confDir": "directory for the CNI configuration in the host",
+			"network.podSubnet":   "the subnet used for the pods",
+		},
+		ConfFiles: []string{"05-cilium.conf"},
+		Families:  []string{kubicutil.FamilyIPv4, kubicutil.FamilyIPv6},
+	}
+}
+
+// Install creates the cilium addons
+func (CiliumPlugin) Install(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	if err := createServiceAccount(clients.Kubernetes); err != nil {
+		return fmt.Errorf("error when creating cilium service account: %v", err)
+	}
+
+	var ciliumConfigMapBytes, ciliumDaemonSetBytes []byte
+	ciliumConfigMapBytes, err := kubeadmutil.ParseTemplate(CiliumConfigMap,
+		struct {
+			IPv4Network string
+			IPv6Network string
+		}{
+			cfg.Network.GetPodSubnet(kubicutil.FamilyIPv4),
+			cfg.Network.GetPodSubnet(kubicutil.FamilyIPv6),
+		})
+
+	if err != nil {
+		return fmt.Errorf("error when parsing cilium configmap template: %v", err)
+	}
+
+	ciliumDaemonSetBytes, err = kubeadmutil.ParseTemplate(CiliumDaemonSet,
+		struct {
+			Image          string
+			HealthzPort    int
+			ConfDir        string
+			BinDir         string
+			ServiceAccount string
+		}{
+			cfg.GetCniImage(),
+			CiliumHealthPort,
+			cfg.Network.Cni.ConfDir,
+			cfg.Network.Cni.BinDir,
+			CiliumServiceAccountName,
+		})
+
+	if err != nil {
+		return fmt.Errorf("error when parsing cilium daemonset template: %v", err)
+	}
+
+	if err := createCiliumAddon(ciliumConfigMapBytes, ciliumDaemonSetBytes, clients.Kubernetes); err != nil {
+		return err
+	}
+
+	if err := createRBACRules(clients.Kubernetes, cfg.Features.PSP); err != nil {
+		return fmt.Errorf("error when creating cilium RBAC rules: %v", err)
+	}
+
+	glog.V(1).Infof("[kubic] installed cilium CNI driver")
+	return nil
+}
+
+// Upgrade upgrades cilium: the DaemonSet is updated with a rolling update
+func (p CiliumPlugin) Upgrade(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	return p.Install(cfg, clients)
+}
+
+// Status returns the rollout status of the cilium DaemonSet
+func (CiliumPlugin) Status(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) (cni.CniPluginStatus, error) {
+	return cni.DaemonSetStatus(clients.Kubernetes, metav1.NamespaceSystem, ciliumDaemonSetName)
+}
+
+// Uninstall removes all the cilium objects
+func (CiliumPlugin) Uninstall(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	client := clients.Kubernetes
+	deleteOptions := cni.DeleteOptions()
+
+	if err := client.AppsV1().DaemonSets(metav1.NamespaceSystem).Delete(ciliumDaemonSetName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Delete(ciliumConfigMapName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	for _, name := range []string{CiliumClusterRoleNamePSP, CiliumClusterRoleName} {
+		if err := client.RbacV1().ClusterRoleBindings().Delete(name, deleteOptions); cni.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if err := client.RbacV1().ClusterRoles().Delete(CiliumClusterRoleName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := client.CoreV1().ServiceAccounts(metav1.NamespaceSystem).Delete(CiliumServiceAccountName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	glog.V(1).Infof("[kubic] cilium CNI driver removed")
+	return nil
+}
+
+func createServiceAccount(client clientset.Interface) error {
+	return apiclient.CreateOrUpdateServiceAccount(client, &serviceAccount)
+}
+
+func createCiliumAddon(configMapBytes, daemonSetbytes []byte, client clientset.Interface) error {
+	ciliumConfigMap := &v1.ConfigMap{}
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), configMapBytes, ciliumConfigMap); err != nil {
+		return fmt.Errorf("unable to decode cilium configmap %v", err)
+	}
+
+	// Create the ConfigMap for cilium or update it in case it already exists
+	if err := apiclient.CreateOrUpdateConfigMap(client, ciliumConfigMap); err != nil {
+		return err
+	}
+
+	ciliumDaemonSet := &apps.DaemonSet{}
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), daemonSetbytes, ciliumDaemonSet); err != nil {
+		return fmt.Errorf("unable to decode cilium daemonset %v", err)
+	}
+
+	// Create the DaemonSet for cilium or update it in case it already exists
+	return apiclient.CreateOrUpdateDaemonSet(client, ciliumDaemonSet)
+}
+
+// createRBACRules creates the RBAC rules needed by cilium
+func createRBACRules(client clientset.Interface, psp bool) error {
+	var err error
+
+	if err = apiclient.CreateOrUpdateClusterRole(client, &clusterRole); err != nil {
+		return err
+	}
+
+	if err = apiclient.CreateOrUpdateClusterRoleBinding(client, &clusterRoleBinding); err != nil {
+		return err
+	}
+
+	if psp {
+		if err = apiclient.CreateOrUpdateClusterRoleBinding(client, &clusterRoleBindingPSP); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
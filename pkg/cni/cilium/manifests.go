@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cilium
+
+const (
+	CiliumConfigMap = `
+kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: cilium-config
+  namespace: kube-system
+  labels:
+    tier: node
+    app: cilium
+data:
+  # identities are stored in CRDs, so no external key-value store is needed
+  identity-allocation-mode: crd
+  debug: "false"
+  enable-ipv4: "{{ if .IPv4Network }}true{{ else }}false{{ end }}"
+  enable-ipv6: "{{ if .IPv6Network }}true{{ else }}false{{ end }}"
+  tunnel: vxlan
+  # use the pod CIDRs assigned to the nodes by the controller manager
+  ipam: kubernetes
+{{- if .IPv4Network }}
+  k8s-require-ipv4-pod-cidr: "true"
+{{- end }}
+{{- if .IPv6Network }}
+  k8s-require-ipv6-pod-cidr: "true"
+{{- end }}
+  masquerade: "true"
+  install-iptables-rules: "true"
+  enable-policy: default
+  preallocate-bpf-maps: "false"
+`
+
+	CiliumDaemonSet = `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: cilium
+  namespace: kube-system
+  labels:
+    tier: node
+    k8s-app: cilium
+spec:
+  selector:
+    matchLabels:
+      tier: node
+      k8s-app: cilium
+  template:
+    metadata:
+      labels:
+        tier: node
+        k8s-app: cilium
+    spec:
+      serviceAccountName: {{ .ServiceAccount }}
+      initContainers:
+      - name: install-cni-bin
+        image: {{ .Image }}
+        command:
+          - /bin/sh
+          - "-c"
+          - "cp -f /usr/lib/cni/* /host/opt/cni/bin/"
+        volumeMounts:
+        - name: host-cni-bin
+          mountPath: /host/opt/cni/bin/
+      containers:
+      - name: cilium-agent
+        image: {{ .Image }}
+        command:
+          - cilium-agent
+        args:
+          - "--config-dir=/tmp/cilium/config-map"
+          - "--agent-health-port={{ .HealthzPort }}"
+        lifecycle:
+          postStart:
+            exec:
+              command:
+                - /cni-install.sh
+          preStop:
+            exec:
+              command:
+                - /cni-uninstall.sh
+        securityContext:
+          privileged: true
+          capabilities:
+            add:
+              - NET_ADMIN
+              - SYS_MODULE
+        readinessProbe:
+          httpGet:
+            host: 127.0.0.1
+            path: /healthz
+            port: {{ .HealthzPort }}
+          initialDelaySeconds: 5
+          periodSeconds: 5
+        livenessProbe:
+          initialDelaySeconds: 120
+          timeoutSeconds: 5
+          failureThreshold: 10
+          httpGet:
+            host: 127.0.0.1
+            path: /healthz
+            port: {{ .HealthzPort }}
+        env:
+        - name: K8S_NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+        - name: CILIUM_K8S_NAMESPACE
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.namespace
+        - name: CILIUM_CNI_CONF_DIR
+          value: /host/etc/cni/net.d
+        volumeMounts:
+        - name: bpf-maps
+          mountPath: /sys/fs/bpf
+        - name: cilium-run
+          mountPath: /var/run/cilium
+        - name: host-cni-conf
+          mountPath: /host/etc/cni/net.d
+        - name: host-cni-bin
+          mountPath: /host/opt/cni/bin/
+        - name: cilium-config-path
+          mountPath: /tmp/cilium/config-map
+          readOnly: true
+        - name: lib-modules
+          mountPath: /lib/modules
+          readOnly: true
+        - name: xtables-lock
+          mountPath: /run/xtables.lock
+      hostNetwork: true
+      hostPID: false
+      priorityClassName: system-node-critical
+      restartPolicy: Always
+      tolerations:
+        # Allow the pod to run on the master.
+        - operator: Exists
+      volumes:
+        - name: cilium-run
+          hostPath:
+            path: /var/run/cilium
+            type: DirectoryOrCreate
+        - name: bpf-maps
+          hostPath:
+            path: /sys/fs/bpf
+            type: DirectoryOrCreate
+        - name: host-cni-conf
+          hostPath:
+            path: {{ .ConfDir }}
+            type: DirectoryOrCreate
+        - name: host-cni-bin
+          hostPath:
+            path: {{ .BinDir }}
+            type: DirectoryOrCreate
+        - name: lib-modules
+          hostPath:
+            path: /lib/modules
+        - name: xtables-lock
+          hostPath:
+            path: /run/xtables.lock
+            type: FileOrCreate
+        - name: cilium-config-path
+          configMap:
+            name: cilium-config
+  updateStrategy:
+    rollingUpdate:
+      maxUnavailable: 2
+    type: RollingUpdate
+`
+)
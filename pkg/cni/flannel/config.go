@@ -0,0 +1,163 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package flannel
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// backends supported
+const (
+	BackendVXLAN = "vxlan"
+
+	BackendHostGW = "host-gw"
+
+	BackendWireguard = "wireguard"
+
+	BackendIPSec = "ipsec"
+)
+
+const (
+	// max VXLAN identifier
+	maxVNI = 1<<24 - 1
+
+	// min length for the IPSec PSK (as required by flannel)
+	minIPSecPSKLen = 96
+
+	// min and max MTU
+	minMTU = 576
+	maxMTU = 9000
+
+	// max log level
+	maxLogLevel = 10
+)
+
+var supportedBackends = sets.NewString(BackendVXLAN, BackendHostGW, BackendWireguard, BackendIPSec)
+
+// backends not supported by the flannel version in the default image (ipsec
+// needs flannel 0.10 and wireguard needs flannel 0.14)
+var customImageBackends = sets.NewString(BackendWireguard, BackendIPSec)
+
+// usesDefaultImage returns true when flannel will be deployed with the default image
+func usesDefaultImage(cfg *config.KubicInitConfiguration) bool {
+	return cfg.GetCniImage() == config.DefaultCniImages["flannel"]
+}
+
+// validateConfig checks the flannel configuration. Options that need a more
+// recent flannel are only accepted when using a custom image.
+func validateConfig(cfg *config.FlannelConfiguration, defaultImage bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	backend := cfg.Backend
+	backendPath := fldPath.Child("backend")
+	if !supportedBackends.Has(backend.Type) {
+		allErrs = append(allErrs, field.NotSupported(backendPath.Child("type"), backend.Type, supportedBackends.List()))
+	} else if defaultImage && customImageBackends.Has(backend.Type) {
+		allErrs = append(allErrs, field.Invalid(backendPath.Child("type"), backend.Type,
+			"not supported by the default flannel image: set a more recent image in network.cni.image"))
+	}
+
+	if backend.VNI != 0 {
+		if backend.Type != BackendVXLAN {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("vni"), backend.VNI, "only supported by the vxlan backend"))
+		} else if backend.VNI < 0 || backend.VNI > maxVNI {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("vni"), backend.VNI, fmt.Sprintf("must be between 1 and %d", maxVNI)))
+		}
+	}
+
+	if backend.Port != 0 {
+		if backend.Type != BackendVXLAN && backend.Type != BackendWireguard {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("port"), backend.Port, "only supported by the vxlan and wireguard backends"))
+		} else if backend.Port < 0 || backend.Port > 65535 {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("port"), backend.Port, "must be a valid port number"))
+		}
+	}
+
+	if backend.DirectRouting {
+		if backend.Type != BackendVXLAN {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("directRouting"), backend.DirectRouting, "only supported by the vxlan backend"))
+		} else if defaultImage {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("directRouting"), backend.DirectRouting,
+				"not supported by the default flannel image: set a more recent image in network.cni.image"))
+		}
+	}
+
+	switch backend.Type {
+	case BackendIPSec:
+		if len(backend.PSK) < minIPSecPSKLen {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("psk"), "<hidden>",
+				fmt.Sprintf("a pre-shared key of at least %d characters is required by the ipsec backend", minIPSecPSKLen)))
+		}
+	case BackendWireguard:
+	default:
+		if len(backend.PSK) > 0 {
+			allErrs = append(allErrs, field.Invalid(backendPath.Child("psk"), "<hidden>", "only supported by the ipsec and wireguard backends"))
+		}
+	}
+
+	if cfg.MTU != 0 && (cfg.MTU < minMTU || cfg.MTU > maxMTU) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("mtu"), cfg.MTU, fmt.Sprintf("must be between %d and %d", minMTU, maxMTU)))
+	}
+
+	if cfg.LogLevel < 0 || cfg.LogLevel > maxLogLevel {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("logLevel"), cfg.LogLevel, fmt.Sprintf("must be between 0 and %d", maxLogLevel)))
+	}
+
+	return allErrs
+}
+
+// getBackendJSON returns the "Backend" section of the flannel net-conf.json
+func getBackendJSON(backend config.FlannelBackendConfiguration) (string, error) {
+	conf := map[string]interface{}{
+		"Type": backend.Type,
+	}
+
+	switch backend.Type {
+	case BackendVXLAN:
+		if backend.VNI != 0 {
+			conf["VNI"] = backend.VNI
+		}
+		if backend.Port != 0 {
+			conf["Port"] = backend.Port
+		}
+		if backend.DirectRouting {
+			conf["DirectRouting"] = true
+		}
+	case BackendWireguard:
+		if backend.Port != 0 {
+			conf["ListenPort"] = backend.Port
+		}
+		if len(backend.PSK) > 0 {
+			conf["PSK"] = backend.PSK
+		}
+	case BackendIPSec:
+		conf["PSK"] = backend.PSK
+	}
+
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
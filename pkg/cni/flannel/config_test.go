@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package flannel
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		descr        string
+		cfg          config.FlannelConfiguration
+		defaultImage bool
+		fields       []string
+	}{
+		{
+			descr:  "default configuration",
+			cfg:    config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: BackendVXLAN}, LogLevel: 1},
+			fields: []string{},
+		},
+		{
+			descr:  "unknown backend",
+			cfg:    config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: "udp"}},
+			fields: []string{"flannel.backend.type"},
+		},
+		{
+			descr:  "vxlan options in host-gw",
+			cfg:    config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: BackendHostGW, VNI: 2, Port: 8472}},
+			fields: []string{"flannel.backend.vni", "flannel.backend.port"},
+		},
+		{
+			descr:  "ipsec without a PSK",
+			cfg:    config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: BackendIPSec}},
+			fields: []string{"flannel.backend.psk"},
+		},
+		{
+			descr:        "ipsec with the default image",
+			cfg:          config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: BackendIPSec, PSK: strings.Repeat("k", minIPSecPSKLen)}},
+			defaultImage: true,
+			fields:       []string{"flannel.backend.type"},
+		},
+		{
+			descr:        "direct routing with the default image",
+			cfg:          config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: BackendVXLAN, DirectRouting: true}},
+			defaultImage: true,
+			fields:       []string{"flannel.backend.directRouting"},
+		},
+		{
+			descr:  "direct routing with a custom image",
+			cfg:    config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: BackendVXLAN, DirectRouting: true}},
+			fields: []string{},
+		},
+		{
+			descr:  "invalid MTU and log level",
+			cfg:    config.FlannelConfiguration{Backend: config.FlannelBackendConfiguration{Type: BackendVXLAN}, MTU: 100, LogLevel: -1},
+			fields: []string{"flannel.mtu", "flannel.logLevel"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %s", test.descr)
+		errs := validateConfig(&test.cfg, test.defaultImage, field.NewPath("flannel"))
+		if len(errs) != len(test.fields) {
+			t.Fatalf("Error: %d errors expected, got %v", len(test.fields), errs)
+		}
+		for i, f := range test.fields {
+			if errs[i].Field != f {
+				t.Fatalf("Error: error in %q expected, got %v", f, errs[i])
+			}
+		}
+	}
+}
+
+func TestBackendJSON(t *testing.T) {
+	b, err := getBackendJSON(config.FlannelBackendConfiguration{Type: BackendVXLAN, VNI: 4, DirectRouting: true})
+	if err != nil {
+		t.Fatalf("Error: could not generate the backend: %s", err)
+	}
+	t.Logf("Backend: %s", b)
+	if !strings.Contains(b, `"VNI":4`) || !strings.Contains(b, `"DirectRouting":true`) {
+		t.Fatalf("Error: options missing in the vxlan backend: %s", b)
+	}
+
+	b, err = getBackendJSON(config.FlannelBackendConfiguration{Type: BackendHostGW})
+	if err != nil {
+		t.Fatalf("Error: could not generate the backend: %s", err)
+	}
+	if b != `{"Type":"host-gw"}` {
+		t.Fatalf("Error: unexpected host-gw backend: %s", b)
+	}
+}
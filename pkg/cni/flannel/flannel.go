@@ -26,13 +26,17 @@ import (
 	rbac "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	clientset "k8s.io/client-go/kubernetes"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
 	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
 	"k8s.io/kubernetes/cmd/kubeadm/app/util/apiclient"
 
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
 	"github.com/kubic-project/kubic-init/pkg/cni"
 	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
 )
 
 const (
@@ -117,25 +121,110 @@ var (
 	}
 )
 
+// the name of the objects created for flannel
+const (
+	flannelConfigMapName = "flannel-plugin-config-map"
+
+	flannelDaemonSetName = "kube-flannel"
+)
+
 func init() {
 	// self-register in the CNI plugins registry
-	cni.Registry.Register("flannel", EnsureFlannelAddon)
+	cni.Registry.Register("flannel", &FlannelPlugin{})
+
+	// check the flannel configuration when flannel is used
+	config.RegisterValidation(func(cfg *config.KubicInitConfiguration) field.ErrorList {
+		if cfg.Network.Cni.Driver != "flannel" {
+			return field.ErrorList{}
+		}
+		return validateConfig(&cfg.Network.Cni.Flannel, usesDefaultImage(cfg), field.NewPath("network", "cni", "flannel"))
+	})
+
+	// remove the flannel interfaces and state when resetting a node
+	reset.Register(reset.Step{
+		Name:        "flannel",
+		Description: "remove the flannel network interfaces",
+		Enabled: func(cfg *config.KubicInitConfiguration) bool {
+			return cfg.Network.Cni.Driver == "flannel"
+		},
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			return []string{"/run/flannel"}
+		},
+		Run: func(cfg *config.KubicInitConfiguration) error {
+			return reset.DeleteInterfaces(interfaces(&cfg.Network.Cni.Flannel)...)
+		},
+	})
 }
 
-// EnsureFlannelAddon creates the flannel addons
-func EnsureFlannelAddon(cfg *config.KubicInitConfiguration, client clientset.Interface) error {
-	if err := createServiceAccount(client); err != nil {
+// interfaces returns the network interfaces created by flannel in the hosts
+func interfaces(cfg *config.FlannelConfiguration) []string {
+	res := []string{"cni0"}
+	switch cfg.Backend.Type {
+	case BackendVXLAN:
+		vni := cfg.Backend.VNI
+		if vni == 0 {
+			vni = 1
+		}
+		res = append(res, fmt.Sprintf("flannel.%d", vni))
+	case BackendWireguard:
+		res = append(res, "flannel-wg")
+	}
+	return res
+}
+
+// FlannelPlugin is the flannel CNI plugin
+type FlannelPlugin struct{}
+
+// Describe returns a description of the flannel plugin
+func (FlannelPlugin) Describe() cni.CniPluginDescription {
+	return cni.CniPluginDescription{
+		Description: "flannel: a simple overlay network",
+		Options: map[string]string{
+			"network.cni.image":   "the flannel image",
+			"network.cni.binDir":  "directory for the CNI binaries in the host",
+			"network.cni.confDir": "directory for the CNI configuration in the host",
+			"network.podSubnet":   "the subnet used for the pods",
+
+			"network.cni.flannel.backend.type":          "the backend: vxlan, host-gw, wireguard or ipsec",
+			"network.cni.flannel.backend.vni":           "the VXLAN identifier (vxlan)",
+			"network.cni.flannel.backend.port":          "the UDP port (vxlan and wireguard)",
+			"network.cni.flannel.backend.directRouting": "use direct routes for hosts in the same subnet (vxlan)",
+			"network.cni.flannel.backend.psk":           "the pre-shared key (ipsec and wireguard)",
+			"network.cni.flannel.mtu":                   "the MTU for the pods interfaces",
+			"network.cni.flannel.interface":             "the interface used for the inter-host communication",
+			"network.cni.flannel.logLevel":              "the log level",
+		},
+		ConfFiles: []string{"10-flannel.conflist"},
+		Families:  []string{kubicutil.FamilyIPv4},
+	}
+}
+
+// Install creates the flannel addons
+func (FlannelPlugin) Install(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	flannelCfg := cfg.Network.Cni.Flannel
+	if errs := validateConfig(&flannelCfg, usesDefaultImage(cfg), field.NewPath("network", "cni", "flannel")); len(errs) > 0 {
+		return fmt.Errorf("invalid flannel configuration: %v", errs.ToAggregate())
+	}
+
+	backend, err := getBackendJSON(flannelCfg.Backend)
+	if err != nil {
+		return fmt.Errorf("error when generating the flannel backend configuration: %v", err)
+	}
+
+	if err := createServiceAccount(clients.Kubernetes); err != nil {
 		return fmt.Errorf("error when creating flannel service account: %v", err)
 	}
 
 	var flannelConfigMapBytes, flannelDaemonSetBytes []byte
-	flannelConfigMapBytes, err := kubeadmutil.ParseTemplate(FlannelConfigMap19,
+	flannelConfigMapBytes, err = kubeadmutil.ParseTemplate(FlannelConfigMap19,
 		struct {
 			Network string
 			Backend string
+			MTU     int
 		}{
-			cfg.Network.PodSubnet,
-			"vxlan", // TODO: replace by some config arg
+			cfg.Network.GetPodSubnet(kubicutil.FamilyIPv4),
+			backend,
+			flannelCfg.MTU,
 		})
 
 	if err != nil {
@@ -146,13 +235,15 @@ func EnsureFlannelAddon(cfg *config.KubicInitConfiguration, client clientset.Int
 		struct {
 			Image          string
 			LogLevel       int
+			Interface      string
 			HealthzPort    int
 			ConfDir        string
 			BinDir         string
 			ServiceAccount string
 		}{
-			cfg.Network.Cni.Image,
-			1, // TODO: replace by some config arg
+			cfg.GetCniImage(),
+			flannelCfg.LogLevel,
+			flannelCfg.Interface,
 			FlannelHealthPort,
 			cfg.Network.Cni.ConfDir,
 			cfg.Network.Cni.BinDir,
@@ -163,15 +254,52 @@ func EnsureFlannelAddon(cfg *config.KubicInitConfiguration, client clientset.Int
 		return fmt.Errorf("error when parsing flannel daemonset template: %v", err)
 	}
 
-	if err := createFlannelAddon(flannelConfigMapBytes, flannelDaemonSetBytes, client); err != nil {
+	if err := createFlannelAddon(flannelConfigMapBytes, flannelDaemonSetBytes, clients.Kubernetes); err != nil {
 		return err
 	}
 
-	if err := createRBACRules(client, cfg.Features.PSP); err != nil {
+	if err := createRBACRules(clients.Kubernetes, cfg.Features.PSP); err != nil {
 		return fmt.Errorf("error when creating flannel RBAC rules: %v", err)
 	}
 
-	glog.V(1).Infof("[kubic] installed flannel CNI driver")
+	glog.V(1).Infof("[kubic] installed flannel CNI driver (backend: %s)", flannelCfg.Backend.Type)
+	return nil
+}
+
+// Upgrade upgrades flannel: the DaemonSet is updated with a rolling update
+func (p FlannelPlugin) Upgrade(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	return p.Install(cfg, clients)
+}
+
+// Status returns the rollout status of the flannel DaemonSet
+func (FlannelPlugin) Status(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) (cni.CniPluginStatus, error) {
+	return cni.DaemonSetStatus(clients.Kubernetes, metav1.NamespaceSystem, flannelDaemonSetName)
+}
+
+// Uninstall removes all the flannel objects
+func (FlannelPlugin) Uninstall(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	client := clients.Kubernetes
+	deleteOptions := cni.DeleteOptions()
+
+	if err := client.AppsV1().DaemonSets(metav1.NamespaceSystem).Delete(flannelDaemonSetName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Delete(flannelConfigMapName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	for _, name := range []string{FlannelClusterRoleNamePSP, FlannelClusterRoleName} {
+		if err := client.RbacV1().ClusterRoleBindings().Delete(name, deleteOptions); cni.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if err := client.RbacV1().ClusterRoles().Delete(FlannelClusterRoleName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := client.CoreV1().ServiceAccounts(metav1.NamespaceSystem).Delete(FlannelServiceAccountName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	glog.V(1).Infof("[kubic] flannel CNI driver removed")
 	return nil
 }
 
@@ -36,6 +36,9 @@ data:
         {
           "type":"flannel",
           "delegate":{
+              {{- if .MTU }}
+              "mtu":{{ .MTU }},
+              {{- end }}
               "forceAddress":true,
               "isDefaultGateway":true
           }
@@ -51,10 +54,7 @@ data:
   net-conf.json: |
     {
       "Network": "{{ .Network }}",
-      "Backend":
-      {
-        "Type": "{{ .Backend }}"
-      }
+      "Backend": {{ .Backend }}
     }
 `
 
@@ -108,7 +108,11 @@ spec:
           - "--ip-masq"
           - "--kube-subnet-mgr"
           - "--v={{ .LogLevel }}"
+          {{- if .Interface }}
+          - "--iface={{ .Interface }}"
+          {{- else }}
           - "--iface=$(POD_IP)"
+          {{- end }}
           - "--healthz-ip=$(POD_IP)"
           - "--healthz-port={{ .HealthzPort }}"
         securityContext:
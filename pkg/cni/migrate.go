@@ -0,0 +1,356 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cni
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/apiclient"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	kubiccluster "github.com/kubic-project/kubic-init/pkg/cluster"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+const (
+	// the name of the DaemonSet used for removing the CNI configuration files of the old driver
+	cleanupDaemonSetName = "kubic-cni-cleanup"
+
+	// interval between checks of the status of the CNI driver
+	migrationPollInterval = 5 * time.Second
+
+	// DefaultMigrationTimeout is the default max time we wait for a driver to be ready
+	DefaultMigrationTimeout = 5 * time.Minute
+)
+
+const cleanupDaemonSet = `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: {{ .Name }}
+  namespace: kube-system
+  labels:
+    k8s-app: {{ .Name }}
+spec:
+  selector:
+    matchLabels:
+      k8s-app: {{ .Name }}
+  template:
+    metadata:
+      labels:
+        k8s-app: {{ .Name }}
+    spec:
+      hostNetwork: true
+      initContainers:
+      - name: cleanup
+        image: {{ .Image }}
+        command:
+          - /bin/sh
+          - "-c"
+          - "rm -rf {{ .Files }}"
+        volumeMounts:
+        - name: host-cni-conf
+          mountPath: /host/etc/cni/net.d
+      containers:
+      - name: wait
+        image: {{ .Image }}
+        command:
+          - /bin/sh
+          - "-c"
+          - "while true ; do sleep 3600 ; done"
+      tolerations:
+        - operator: Exists
+      volumes:
+        - name: host-cni-conf
+          hostPath:
+            path: {{ .ConfDir }}
+`
+
+// MigrationOptions are the options for migrating from one CNI driver to another
+type MigrationOptions struct {
+	// To is the new driver
+	To string
+
+	// Image is the image for the new driver (the default image for the driver when empty)
+	Image string
+
+	// DryRun is true when only the steps to perform should be printed
+	DryRun bool
+
+	// Timeout is the max time we wait for the drivers to be ready
+	Timeout time.Duration
+
+	// Out is the output for the dry-run mode and the progress messages
+	Out io.Writer
+}
+
+// migration is a migration in progress
+type migration struct {
+	MigrationOptions
+
+	clients *kubicclient.Clients
+	from    *config.KubicInitConfiguration
+	to      *config.KubicInitConfiguration
+}
+
+// Migrate switches a running cluster from the current CNI driver (as found in the configuration
+// uploaded to the cluster) to a new driver: the old driver is uninstalled, the CNI configuration
+// files are removed from all the nodes, the new driver is installed and then the nodes
+// are rolled, one by one, for re-creating the pods in the new network.
+// The migration is aborted as soon as the new driver is not healthy.
+func Migrate(clients *kubicclient.Clients, options MigrationOptions) error {
+	if options.Timeout == 0 {
+		options.Timeout = DefaultMigrationTimeout
+	}
+
+	from, err := config.FromConfigMap(clients.Kubernetes, config.DefaultKubicInitConfigmap)
+	if err != nil {
+		return fmt.Errorf("could not read the cluster configuration: %v", err)
+	}
+	if from.Network.Cni.Driver == options.To {
+		return fmt.Errorf("the cluster is already using the %q CNI driver", options.To)
+	}
+
+	to := from.DeepCopy()
+	to.Network.Cni.Driver = options.To
+	to.Network.Cni.Image = options.Image
+	if err := to.Validate().ToAggregate(); err != nil {
+		return fmt.Errorf("invalid configuration for the %q CNI driver: %v", options.To, err)
+	}
+
+	m := migration{
+		MigrationOptions: options,
+		clients:          clients,
+		from:             from,
+		to:               to,
+	}
+	return m.run()
+}
+
+func (m *migration) run() error {
+	oldPlugin, err := Registry.Get(m.from.Network.Cni.Driver)
+	if err != nil {
+		return err
+	}
+	newPlugin, err := Registry.Get(m.to.Network.Cni.Driver)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := kubiccluster.GetNodesNames(m.clients.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	m.printf("migrating from %q to %q in %d nodes", m.from.Network.Cni.Driver, m.to.Network.Cni.Driver, len(nodes))
+
+	if err := m.step(fmt.Sprintf("uninstall the %q CNI driver", m.from.Network.Cni.Driver), func() error {
+		return oldPlugin.Uninstall(m.from, m.clients)
+	}); err != nil {
+		return err
+	}
+
+	// the configuration generated by the meta-plugin wraps the configuration
+	// of the old driver, so it must be removed too
+	confFiles := oldPlugin.Describe().ConfFiles
+	var metaPlugin CniPlugin
+	if meta := m.to.Network.Cni.Meta; len(meta) > 0 {
+		if metaPlugin, err = MetaRegistry.Get(meta); err != nil {
+			return err
+		}
+		confFiles = append(confFiles, metaPlugin.Describe().ConfFiles...)
+	}
+
+	if err := m.step("remove the old CNI configuration files in all the nodes", func() error {
+		return m.cleanup(confFiles)
+	}); err != nil {
+		return err
+	}
+
+	if err := m.step(fmt.Sprintf("install the %q CNI driver", m.to.Network.Cni.Driver), func() error {
+		if err := newPlugin.Install(m.to, m.clients); err != nil {
+			return err
+		}
+		return m.waitForDriver(newPlugin)
+	}); err != nil {
+		return err
+	}
+
+	if metaPlugin != nil {
+		if err := m.step(fmt.Sprintf("upgrade the %q CNI meta-plugin", m.to.Network.Cni.Meta), func() error {
+			return metaPlugin.Upgrade(m.to, m.clients)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := m.step("update the configuration in the cluster", m.uploadConfig); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := m.step(fmt.Sprintf("roll node %s (cordon, restart pods, uncordon)", node), func() error {
+			return m.rollNode(node, newPlugin)
+		}); err != nil {
+			return err
+		}
+	}
+
+	m.printf("migration to %q finished", m.to.Network.Cni.Driver)
+	return nil
+}
+
+// step runs a step of the migration (or just prints it in dry-run mode)
+func (m *migration) step(descr string, f func() error) error {
+	if m.DryRun {
+		fmt.Fprintf(m.Out, "[dry-run] would %s\n", descr)
+		return nil
+	}
+
+	m.printf("%s...", descr)
+	if err := f(); err != nil {
+		return fmt.Errorf("migration aborted: could not %s: %v", descr, err)
+	}
+	return nil
+}
+
+func (m *migration) printf(format string, args ...interface{}) {
+	glog.V(1).Infof("[kubic] "+format, args...)
+	if m.Out != nil && !m.DryRun {
+		fmt.Fprintf(m.Out, "[cni-migrate] "+format+"\n", args...)
+	}
+}
+
+// cleanup removes the configuration files of the old driver in all the nodes with a DaemonSet
+func (m *migration) cleanup(files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, filepath.Join("/host/etc/cni/net.d", file))
+	}
+
+	dsBytes, err := kubeadmutil.ParseTemplate(cleanupDaemonSet,
+		struct {
+			Name    string
+			Image   string
+			Files   string
+			ConfDir string
+		}{
+			cleanupDaemonSetName,
+			m.from.GetCniImage(),
+			strings.Join(paths, " "),
+			m.from.Network.Cni.ConfDir,
+		})
+	if err != nil {
+		return fmt.Errorf("error when parsing the cleanup daemonset template: %v", err)
+	}
+
+	ds := &apps.DaemonSet{}
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), dsBytes, ds); err != nil {
+		return fmt.Errorf("unable to decode the cleanup daemonset: %v", err)
+	}
+
+	if err := apiclient.CreateOrUpdateDaemonSet(m.clients.Kubernetes, ds); err != nil {
+		return err
+	}
+
+	// the files have been removed once all the pods are running (after the init container)
+	err = wait.PollImmediate(migrationPollInterval, m.Timeout, func() (bool, error) {
+		status, err := DaemonSetStatus(m.clients.Kubernetes, metav1.NamespaceSystem, cleanupDaemonSetName)
+		if err != nil {
+			return false, err
+		}
+		glog.V(3).Infof("[kubic] cleanup: %s", status.Message)
+		return status.Ready, nil
+	})
+	if err != nil {
+		return fmt.Errorf("the cleanup DaemonSet did not finish: %v", err)
+	}
+
+	return IgnoreNotFound(m.clients.Kubernetes.AppsV1().DaemonSets(metav1.NamespaceSystem).Delete(cleanupDaemonSetName, DeleteOptions()))
+}
+
+// waitForDriver waits until the new driver is healthy
+func (m *migration) waitForDriver(plugin CniPlugin) error {
+	var last CniPluginStatus
+	err := wait.PollImmediate(migrationPollInterval, m.Timeout, func() (bool, error) {
+		var err error
+		last, err = plugin.Status(m.to, m.clients)
+		if err != nil {
+			return false, err
+		}
+		return last.Ready, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("the %q CNI driver is not healthy: %s", m.to.Network.Cni.Driver, last.Message)
+	}
+	return err
+}
+
+// uploadConfig saves the configuration with the new driver in the cluster,
+// keeping the labels in the current ConfigMap
+func (m *migration) uploadConfig() error {
+	cm, err := m.clients.Kubernetes.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(config.DefaultKubicInitConfigmap, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return m.to.ToConfigMap(m.clients.Kubernetes, config.DefaultKubicInitConfigmap, cm.Labels)
+}
+
+// rollNode restarts all the pods in a node, so they get an address in the new network
+// Nodes that were cordoned before the migration are left cordoned.
+// When the new driver is not healthy after the pods restart, the node is left cordoned.
+func (m *migration) rollNode(node string, plugin CniPlugin) error {
+	wasCordoned, err := kubiccluster.IsNodeCordoned(m.clients.Kubernetes, node)
+	if err != nil {
+		return err
+	}
+
+	if err := kubiccluster.CordonNode(m.clients.Kubernetes, node, true); err != nil {
+		return err
+	}
+
+	deleted, err := kubiccluster.RestartPodsInNode(m.clients.Kubernetes, node)
+	if err != nil {
+		return err
+	}
+	glog.V(3).Infof("[kubic] %d pods restarted in %s", deleted, node)
+
+	if err := m.waitForDriver(plugin); err != nil {
+		return fmt.Errorf("%v (node %s has been left cordoned)", err, node)
+	}
+
+	if wasCordoned {
+		return nil
+	}
+	return kubiccluster.CordonNode(m.clients.Kubernetes, node, false)
+}
@@ -0,0 +1,293 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cni
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// migrationPlugin is a plugin that records the calls it receives.
+// The plugin "owns" a DaemonSet with its name, removed when uninstalling it.
+type migrationPlugin struct {
+	name  string
+	ready bool
+	calls *[]string
+}
+
+func (p migrationPlugin) Describe() CniPluginDescription {
+	return CniPluginDescription{Description: p.name, ConfFiles: []string{"10-" + p.name + ".conf"}}
+}
+
+func (p migrationPlugin) Install(*config.KubicInitConfiguration, *kubicclient.Clients) error {
+	*p.calls = append(*p.calls, "install "+p.name)
+	return nil
+}
+
+func (p migrationPlugin) Upgrade(*config.KubicInitConfiguration, *kubicclient.Clients) error {
+	*p.calls = append(*p.calls, "upgrade "+p.name)
+	return nil
+}
+
+func (p migrationPlugin) Status(*config.KubicInitConfiguration, *kubicclient.Clients) (CniPluginStatus, error) {
+	return CniPluginStatus{Ready: p.ready, Message: "testing"}, nil
+}
+
+func (p migrationPlugin) Uninstall(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	*p.calls = append(*p.calls, "uninstall "+p.name)
+	return IgnoreNotFound(clients.Kubernetes.AppsV1().DaemonSets(metav1.NamespaceSystem).Delete(p.name, DeleteOptions()))
+}
+
+func newMigrationNode(name string, cordoned bool) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: cordoned},
+	}
+}
+
+func newMigrationPod(name, node string, hostNetwork bool) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault},
+		Spec:       corev1.PodSpec{NodeName: node, HostNetwork: hostNetwork},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// newMigrationClients returns some clients for a cluster using the "test-old" driver,
+// with a schedulable node (node-a) and a cordoned node (node-b)
+func newMigrationClients(t *testing.T) (*kubicclient.Clients, *fake.Clientset) {
+	client := fake.NewSimpleClientset(
+		newMigrationNode("node-a", false),
+		newMigrationNode("node-b", true),
+		newMigrationPod("pod-a", "node-a", false),
+		newMigrationPod("pod-a-host", "node-a", true),
+		newMigrationPod("pod-b", "node-b", false),
+		&apps.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "test-old", Namespace: metav1.NamespaceSystem}},
+	)
+
+	// the fake clientset ignores field selectors, so filter the pods by node
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		restrictions := action.(k8stesting.ListAction).GetListRestrictions()
+		obj, err := client.Tracker().List(corev1.SchemeGroupVersion.WithResource("pods"),
+			corev1.SchemeGroupVersion.WithKind("Pod"), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		pods := obj.(*corev1.PodList)
+		filtered := []corev1.Pod{}
+		for _, pod := range pods.Items {
+			if restrictions.Fields.Matches(fields.Set{"spec.nodeName": pod.Spec.NodeName}) {
+				filtered = append(filtered, pod)
+			}
+		}
+		pods.Items = filtered
+		return true, pods, nil
+	})
+
+	cfg, err := config.BytesToKubicInitConfig([]byte{}, false)
+	if err != nil {
+		t.Fatalf("could not get a default configuration: %v", err)
+	}
+	cfg.Network.Cni.Driver = "test-old"
+	if err := cfg.ToConfigMap(client, config.DefaultKubicInitConfigmap, map[string]string{"test": "label"}); err != nil {
+		t.Fatalf("could not upload the configuration: %v", err)
+	}
+	client.ClearActions()
+
+	return &kubicclient.Clients{Kubernetes: client}, client
+}
+
+// registerMigrationPlugins registers the "test-old" and "test-new" drivers,
+// returning a function for unregistering them
+func registerMigrationPlugins(newReady bool, calls *[]string) func() {
+	Registry.Register("test-old", migrationPlugin{name: "test-old", ready: true, calls: calls})
+	Registry.Register("test-new", migrationPlugin{name: "test-new", ready: newReady, calls: calls})
+	return func() {
+		delete(Registry, "test-old")
+		delete(Registry, "test-new")
+	}
+}
+
+// rollEvents returns the node (un)cordons and pod deletions performed, in order
+func rollEvents(client *fake.Clientset) []string {
+	events := []string{}
+	for _, action := range client.Actions() {
+		switch {
+		case action.Matches("update", "nodes"):
+			node := action.(k8stesting.UpdateAction).GetObject().(*corev1.Node)
+			if node.Spec.Unschedulable {
+				events = append(events, "cordon "+node.Name)
+			} else {
+				events = append(events, "uncordon "+node.Name)
+			}
+		case action.Matches("delete", "pods"):
+			events = append(events, "delete "+action.(k8stesting.DeleteAction).GetName())
+		}
+	}
+	return events
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	calls := []string{}
+	defer registerMigrationPlugins(true, &calls)()
+	clients, client := newMigrationClients(t)
+
+	out := &bytes.Buffer{}
+	if err := Migrate(clients, MigrationOptions{To: "test-new", DryRun: true, Out: out}); err != nil {
+		t.Fatalf("could not run the migration: %v", err)
+	}
+	t.Logf("output:\n%s", out)
+
+	expected := []string{
+		`[dry-run] would uninstall the "test-old" CNI driver`,
+		`[dry-run] would remove the old CNI configuration files in all the nodes`,
+		`[dry-run] would install the "test-new" CNI driver`,
+		`[dry-run] would update the configuration in the cluster`,
+		`[dry-run] would roll node node-a (cordon, restart pods, uncordon)`,
+		`[dry-run] would roll node node-b (cordon, restart pods, uncordon)`,
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected output: %q", lines)
+	}
+
+	if len(calls) > 0 {
+		t.Fatalf("plugins called in dry-run mode: %v", calls)
+	}
+	for _, action := range client.Actions() {
+		if verb := action.GetVerb(); verb != "get" && verb != "list" {
+			t.Fatalf("unexpected %s of %s in dry-run mode", verb, action.GetResource().Resource)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	calls := []string{}
+	defer registerMigrationPlugins(true, &calls)()
+	clients, client := newMigrationClients(t)
+
+	if err := Migrate(clients, MigrationOptions{To: "test-new", Timeout: time.Minute, Out: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("could not run the migration: %v", err)
+	}
+
+	if strings.Join(calls, ",") != "uninstall test-old,install test-new" {
+		t.Fatalf("unexpected calls to the plugins: %v", calls)
+	}
+
+	// the objects of the old driver and the cleanup DaemonSet must be gone
+	for _, name := range []string{"test-old", cleanupDaemonSetName} {
+		if _, err := client.AppsV1().DaemonSets(metav1.NamespaceSystem).Get(name, metav1.GetOptions{}); err == nil {
+			t.Fatalf("DaemonSet %s has not been removed", name)
+		}
+	}
+
+	// the cleanup DaemonSet must remove the configuration of the old driver
+	cleanupCreated := false
+	for _, action := range client.Actions() {
+		if !action.Matches("create", "daemonsets") {
+			continue
+		}
+		ds := action.(k8stesting.CreateAction).GetObject().(*apps.DaemonSet)
+		if ds.Name != cleanupDaemonSetName {
+			continue
+		}
+		cleanupCreated = true
+		command := strings.Join(ds.Spec.Template.Spec.InitContainers[0].Command, " ")
+		if !strings.Contains(command, "rm -rf /host/etc/cni/net.d/10-test-old.conf") {
+			t.Fatalf("unexpected cleanup command: %q", command)
+		}
+	}
+	if !cleanupCreated {
+		t.Fatalf("the cleanup DaemonSet was not created")
+	}
+
+	// nodes are rolled in order, and node-b is left cordoned
+	expected := []string{"cordon node-a", "delete pod-a", "uncordon node-a", "delete pod-b"}
+	if events := rollEvents(client); strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected roll of the nodes: %v", events)
+	}
+
+	cfg, err := config.FromConfigMap(client, config.DefaultKubicInitConfigmap)
+	if err != nil {
+		t.Fatalf("could not read the configuration: %v", err)
+	}
+	if cfg.Network.Cni.Driver != "test-new" {
+		t.Fatalf("the configuration was not updated: driver %q", cfg.Network.Cni.Driver)
+	}
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(config.DefaultKubicInitConfigmap, metav1.GetOptions{})
+	if err != nil || cm.Labels["test"] != "label" {
+		t.Fatalf("the labels of the configuration were lost: %v %v", err, cm)
+	}
+}
+
+func TestMigrateUnhealthyDriver(t *testing.T) {
+	calls := []string{}
+	defer registerMigrationPlugins(false, &calls)()
+	clients, client := newMigrationClients(t)
+
+	err := Migrate(clients, MigrationOptions{To: "test-new", Timeout: time.Millisecond, Out: &bytes.Buffer{}})
+	if err == nil {
+		t.Fatalf("the migration did not fail with an unhealthy driver")
+	}
+	t.Logf("error obtained (as expected): %v", err)
+
+	// the migration is aborted before touching the nodes or the configuration
+	if events := rollEvents(client); len(events) > 0 {
+		t.Fatalf("nodes rolled with an unhealthy driver: %v", events)
+	}
+	cfg, err := config.FromConfigMap(client, config.DefaultKubicInitConfigmap)
+	if err != nil {
+		t.Fatalf("could not read the configuration: %v", err)
+	}
+	if cfg.Network.Cni.Driver != "test-old" {
+		t.Fatalf("the configuration was updated: driver %q", cfg.Network.Cni.Driver)
+	}
+}
+
+func TestMigrationRollNodeUnhealthy(t *testing.T) {
+	calls := []string{}
+	clients, client := newMigrationClients(t)
+	m := migration{
+		MigrationOptions: MigrationOptions{To: "test-new", Timeout: time.Millisecond},
+		clients:          clients,
+		to:               &config.KubicInitConfiguration{},
+	}
+
+	err := m.rollNode("node-a", migrationPlugin{name: "test-new", ready: false, calls: &calls})
+	if err == nil {
+		t.Fatalf("rolling the node did not fail with an unhealthy driver")
+	}
+
+	// the node is left cordoned
+	expected := []string{"cordon node-a", "delete pod-a"}
+	if events := rollEvents(client); strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected roll of the node: %v", events)
+	}
+}
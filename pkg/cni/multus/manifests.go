@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package multus
+
+const (
+	// note well: with "--multus-conf-file=auto", multus generates a "00-multus.conf"
+	// that wraps the first configuration file found in the CNI configuration
+	// directory (ie, the conflist of the CNI driver), so it is used as the
+	// default network for all the pods. The driver is added as an annotation,
+	// so the pods are restarted (and the file is re-generated) when it changes.
+	MultusDaemonSet = `
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: kube-multus
+  namespace: kube-system
+  labels:
+    tier: node
+    k8s-app: multus
+spec:
+  selector:
+    matchLabels:
+      tier: node
+      k8s-app: multus
+  template:
+    metadata:
+      labels:
+        tier: node
+        k8s-app: multus
+      annotations:
+        kubic.suse.com/cni-driver: "{{ .Driver }}"
+    spec:
+      serviceAccountName: {{ .ServiceAccount }}
+      hostNetwork: true
+      nodeSelector:
+        beta.kubernetes.io/arch: amd64
+      tolerations:
+        - operator: Exists
+          effect: NoSchedule
+      containers:
+      - name: kube-multus
+        image: {{ .Image }}
+        command: ["/entrypoint.sh"]
+        args:
+          - "--multus-conf-file=auto"
+          - "--cni-version=0.3.1"
+          - "--multus-kubeconfig-file-host={{ .ConfDir }}/multus.d/multus.kubeconfig"
+        resources:
+          requests:
+            cpu: "100m"
+            memory: "50Mi"
+          limits:
+            cpu: "100m"
+            memory: "50Mi"
+        securityContext:
+          privileged: true
+        volumeMounts:
+        - name: host-cni-conf
+          mountPath: /host/etc/cni/net.d
+        - name: host-cni-bin
+          mountPath: /host/opt/cni/bin
+      volumes:
+        - name: host-cni-conf
+          hostPath:
+            path: {{ .ConfDir }}
+        - name: host-cni-bin
+          hostPath:
+            path: {{ .BinDir }}
+  updateStrategy:
+    rollingUpdate:
+      maxUnavailable: 1
+    type: RollingUpdate
+`
+)
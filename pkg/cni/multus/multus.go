@@ -0,0 +1,325 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package multus
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientset "k8s.io/client-go/kubernetes"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/apiclient"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/cni"
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/loader"
+)
+
+const (
+	// MultusClusterRoleName sets the name for the multus ClusterRole
+	MultusClusterRoleName = "kubic:multus"
+
+	// the PSP cluster role
+	MultusClusterRoleNamePSP = "kubic:psp:multus"
+
+	// MultusServiceAccountName describes the name of the ServiceAccount for the multus addon
+	MultusServiceAccountName = "kubic-multus"
+)
+
+// the NetworkAttachmentDefinition resource
+var networkAttachmentDefinitionResource = schema.GroupVersionResource{
+	Group:    "k8s.cni.cncf.io",
+	Version:  "v1",
+	Resource: "network-attachment-definitions",
+}
+
+var (
+	networkAttachmentDefinitionCRD = &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: networkAttachmentDefinitionResource.Resource + "." + networkAttachmentDefinitionResource.Group,
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   networkAttachmentDefinitionResource.Group,
+			Version: networkAttachmentDefinitionResource.Version,
+			Scope:   apiextensionsv1beta1.NamespaceScoped,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:     networkAttachmentDefinitionResource.Resource,
+				Singular:   "network-attachment-definition",
+				Kind:       "NetworkAttachmentDefinition",
+				ShortNames: []string{"net-attach-def"},
+			},
+		},
+	}
+
+	serviceAccount = v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MultusServiceAccountName,
+			Namespace: metav1.NamespaceSystem,
+		},
+	}
+
+	clusterRole = rbac.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: MultusClusterRoleName,
+		},
+		Rules: []rbac.PolicyRule{
+			{
+				APIGroups: []string{networkAttachmentDefinitionResource.Group},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "pods/status"},
+				Verbs:     []string{"get", "update"},
+			},
+			{
+				APIGroups: []string{"", "events.k8s.io"},
+				Resources: []string{"events"},
+				Verbs:     []string{"create", "patch", "update"},
+			},
+		},
+	}
+
+	clusterRoleBinding = rbac.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: MultusClusterRoleName,
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "ClusterRole",
+			Name:     MultusClusterRoleName,
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      rbac.ServiceAccountKind,
+				Name:      MultusServiceAccountName,
+				Namespace: metav1.NamespaceSystem,
+			},
+		},
+	}
+
+	clusterRoleBindingPSP = rbac.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: MultusClusterRoleNamePSP,
+		},
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "suse:kubic:psp:privileged",
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      rbac.ServiceAccountKind,
+				Name:      MultusServiceAccountName,
+				Namespace: metav1.NamespaceSystem,
+			},
+		},
+	}
+)
+
+// the name of the objects created for multus
+const (
+	multusDaemonSetName = "kube-multus"
+)
+
+func init() {
+	// self-register in the CNI meta-plugins registry
+	cni.MetaRegistry.Register("multus", &MultusPlugin{})
+}
+
+// MultusPlugin is the multus CNI meta-plugin
+type MultusPlugin struct{}
+
+// Describe returns a description of the multus meta-plugin
+func (MultusPlugin) Describe() cni.CniPluginDescription {
+	return cni.CniPluginDescription{
+		Description: "multus: attach multiple network interfaces to pods",
+		Options: map[string]string{
+			"network.cni.metaImage": "the multus image",
+			"network.cni.binDir":    "directory for the CNI binaries in the host",
+			"network.cni.confDir":   "directory for the CNI configuration in the host",
+			"network.cni.networks":  "additional networks, created as NetworkAttachmentDefinitions",
+		},
+		ConfFiles: []string{"00-multus.conf", "multus.d"},
+	}
+}
+
+// Install creates the NetworkAttachmentDefinition CRD, the multus addons and the additional networks
+func (MultusPlugin) Install(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	crdOptions := loader.CRDInstallOptions{CRDs: loader.NewCRDsSet(networkAttachmentDefinitionCRD)}
+	if err := loader.InstallCRDs(cfg, clients, crdOptions); err != nil {
+		return fmt.Errorf("error when creating the NetworkAttachmentDefinition CRD: %v", err)
+	}
+
+	if err := createServiceAccount(clients.Kubernetes); err != nil {
+		return fmt.Errorf("error when creating multus service account: %v", err)
+	}
+
+	multusDaemonSetBytes, err := kubeadmutil.ParseTemplate(MultusDaemonSet,
+		struct {
+			Image          string
+			Driver         string
+			ConfDir        string
+			BinDir         string
+			ServiceAccount string
+		}{
+			cfg.GetCniMetaImage(),
+			cfg.Network.Cni.Driver,
+			cfg.Network.Cni.ConfDir,
+			cfg.Network.Cni.BinDir,
+			MultusServiceAccountName,
+		})
+
+	if err != nil {
+		return fmt.Errorf("error when parsing multus daemonset template: %v", err)
+	}
+
+	if err := createMultusAddon(multusDaemonSetBytes, clients.Kubernetes); err != nil {
+		return err
+	}
+
+	if err := createRBACRules(clients.Kubernetes, cfg.Features.PSP); err != nil {
+		return fmt.Errorf("error when creating multus RBAC rules: %v", err)
+	}
+
+	for _, network := range cfg.Network.Cni.Networks {
+		if err := createOrUpdateNetwork(clients, network); err != nil {
+			return fmt.Errorf("error when creating network %s/%s: %v", network.Namespace, network.Name, err)
+		}
+	}
+
+	glog.V(1).Infof("[kubic] installed multus CNI meta-plugin (with %d additional networks)", len(cfg.Network.Cni.Networks))
+	return nil
+}
+
+// Upgrade upgrades multus: the DaemonSet is updated with a rolling update
+func (p MultusPlugin) Upgrade(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	return p.Install(cfg, clients)
+}
+
+// Status returns the rollout status of the multus DaemonSet
+func (MultusPlugin) Status(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) (cni.CniPluginStatus, error) {
+	return cni.DaemonSetStatus(clients.Kubernetes, metav1.NamespaceSystem, multusDaemonSetName)
+}
+
+// Uninstall removes all the multus objects, including the NetworkAttachmentDefinitions
+func (MultusPlugin) Uninstall(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	client := clients.Kubernetes
+	deleteOptions := cni.DeleteOptions()
+
+	if err := client.AppsV1().DaemonSets(metav1.NamespaceSystem).Delete(multusDaemonSetName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	for _, name := range []string{MultusClusterRoleNamePSP, MultusClusterRoleName} {
+		if err := client.RbacV1().ClusterRoleBindings().Delete(name, deleteOptions); cni.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if err := client.RbacV1().ClusterRoles().Delete(MultusClusterRoleName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := client.CoreV1().ServiceAccounts(metav1.NamespaceSystem).Delete(MultusServiceAccountName, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+	// note well: removing the CRD removes all the NetworkAttachmentDefinitions
+	crds := clients.APIExtensions.ApiextensionsV1beta1().CustomResourceDefinitions()
+	if err := crds.Delete(networkAttachmentDefinitionCRD.Name, deleteOptions); cni.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	glog.V(1).Infof("[kubic] multus CNI meta-plugin removed")
+	return nil
+}
+
+// newNetworkAttachmentDefinition creates a NetworkAttachmentDefinition for an additional network
+func newNetworkAttachmentDefinition(network config.CniNetworkConfiguration) *unstructured.Unstructured {
+	nad := &unstructured.Unstructured{}
+	nad.SetAPIVersion(networkAttachmentDefinitionResource.GroupVersion().String())
+	nad.SetKind(networkAttachmentDefinitionCRD.Spec.Names.Kind)
+	nad.SetName(network.Name)
+	nad.SetNamespace(network.Namespace)
+	unstructured.SetNestedField(nad.Object, network.Config, "spec", "config")
+	return nad
+}
+
+// createOrUpdateNetwork creates (or updates) the NetworkAttachmentDefinition for an additional network
+func createOrUpdateNetwork(clients *kubicclient.Clients, network config.CniNetworkConfiguration) error {
+	nad := newNetworkAttachmentDefinition(network)
+	rsi := clients.Dynamic.Resource(networkAttachmentDefinitionResource).Namespace(network.Namespace)
+
+	glog.V(3).Infof("[kubic] creating NetworkAttachmentDefinition %s/%s", network.Namespace, network.Name)
+	existing, err := rsi.Get(network.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = rsi.Create(nad, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	// custom resources cannot be updated without the current resourceVersion
+	nad.SetResourceVersion(existing.GetResourceVersion())
+	_, err = rsi.Update(nad, metav1.UpdateOptions{})
+	return err
+}
+
+func createServiceAccount(client clientset.Interface) error {
+	return apiclient.CreateOrUpdateServiceAccount(client, &serviceAccount)
+}
+
+func createMultusAddon(daemonSetbytes []byte, client clientset.Interface) error {
+	multusDaemonSet := &apps.DaemonSet{}
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), daemonSetbytes, multusDaemonSet); err != nil {
+		return fmt.Errorf("unable to decode multus daemonset %v", err)
+	}
+
+	// Create the DaemonSet for multus or update it in case it already exists
+	return apiclient.CreateOrUpdateDaemonSet(client, multusDaemonSet)
+}
+
+// createRBACRules creates the RBAC rules needed by multus
+func createRBACRules(client clientset.Interface, psp bool) error {
+	var err error
+
+	if err = apiclient.CreateOrUpdateClusterRole(client, &clusterRole); err != nil {
+		return err
+	}
+
+	if err = apiclient.CreateOrUpdateClusterRoleBinding(client, &clusterRoleBinding); err != nil {
+		return err
+	}
+
+	if psp {
+		if err = apiclient.CreateOrUpdateClusterRoleBinding(client, &clusterRoleBindingPSP); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package multus
+
+import (
+	"testing"
+
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+func newTestClients() (*kubicclient.Clients, *dynamicfake.FakeDynamicClient) {
+	dynamic := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	return &kubicclient.Clients{
+		Kubernetes:    fake.NewSimpleClientset(),
+		APIExtensions: apiextensionsfake.NewSimpleClientset(),
+		Dynamic:       dynamic,
+		// do not wait for the CRDs to be served
+		DryRun: true,
+	}, dynamic
+}
+
+func newTestConfig(t *testing.T, networks ...config.CniNetworkConfiguration) *config.KubicInitConfiguration {
+	cfg, err := config.BytesToKubicInitConfig([]byte{}, false)
+	if err != nil {
+		t.Fatalf("could not get a default configuration: %v", err)
+	}
+	cfg.Network.Cni.Meta = "multus"
+	cfg.Network.Cni.Networks = networks
+	return cfg
+}
+
+func TestInstall(t *testing.T) {
+	clients, dynamic := newTestClients()
+
+	// a network that already exists (ie, when upgrading)
+	existing := newNetworkAttachmentDefinition(config.CniNetworkConfiguration{
+		Name:      "net-a",
+		Namespace: "default",
+		Config:    `{"type": "bridge"}`,
+	})
+	existing.SetResourceVersion("7")
+	nads := dynamic.Resource(networkAttachmentDefinitionResource)
+	if _, err := nads.Namespace("default").Create(existing, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("could not create NetworkAttachmentDefinition: %v", err)
+	}
+	dynamic.ClearActions()
+
+	cfg := newTestConfig(t,
+		config.CniNetworkConfiguration{Name: "net-a", Namespace: "default", Config: `{"type": "macvlan"}`},
+		config.CniNetworkConfiguration{Name: "net-b", Namespace: "other", Config: `{"type": "ipvlan"}`},
+	)
+	if err := (MultusPlugin{}).Install(cfg, clients); err != nil {
+		t.Fatalf("could not install multus: %v", err)
+	}
+
+	crds := clients.APIExtensions.ApiextensionsV1beta1().CustomResourceDefinitions()
+	if _, err := crds.Get(networkAttachmentDefinitionCRD.Name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("the NetworkAttachmentDefinition CRD has not been created: %v", err)
+	}
+	if _, err := clients.Kubernetes.AppsV1().DaemonSets(metav1.NamespaceSystem).Get(multusDaemonSetName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("the multus DaemonSet has not been created: %v", err)
+	}
+
+	for _, expected := range []struct {
+		namespace, name, config string
+	}{
+		{"default", "net-a", `{"type": "macvlan"}`},
+		{"other", "net-b", `{"type": "ipvlan"}`},
+	} {
+		nad, err := nads.Namespace(expected.namespace).Get(expected.name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("could not get NetworkAttachmentDefinition %s/%s: %v", expected.namespace, expected.name, err)
+		}
+		if conf, _, _ := unstructured.NestedString(nad.Object, "spec", "config"); conf != expected.config {
+			t.Fatalf("unexpected config in %s/%s: %q", expected.namespace, expected.name, conf)
+		}
+	}
+
+	// the existing network must be updated with its resourceVersion
+	updated := false
+	for _, action := range dynamic.Actions() {
+		update, ok := action.(k8stesting.UpdateAction)
+		if !ok {
+			continue
+		}
+		nad := update.GetObject().(*unstructured.Unstructured)
+		if nad.GetName() != "net-a" {
+			t.Fatalf("unexpected update of %s/%s", nad.GetNamespace(), nad.GetName())
+		}
+		if nad.GetResourceVersion() != "7" {
+			t.Fatalf("net-a updated without its resourceVersion: %q", nad.GetResourceVersion())
+		}
+		updated = true
+	}
+	if !updated {
+		t.Fatalf("net-a has not been updated")
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	clients, _ := newTestClients()
+	cfg := newTestConfig(t)
+
+	if err := (MultusPlugin{}).Install(cfg, clients); err != nil {
+		t.Fatalf("could not install multus: %v", err)
+	}
+	if err := (MultusPlugin{}).Uninstall(cfg, clients); err != nil {
+		t.Fatalf("could not uninstall multus: %v", err)
+	}
+
+	crds := clients.APIExtensions.ApiextensionsV1beta1().CustomResourceDefinitions()
+	if _, err := crds.Get(networkAttachmentDefinitionCRD.Name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("the NetworkAttachmentDefinition CRD has not been removed: %v", err)
+	}
+	if _, err := clients.Kubernetes.AppsV1().DaemonSets(metav1.NamespaceSystem).Get(multusDaemonSetName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("the multus DaemonSet has not been removed: %v", err)
+	}
+
+	// uninstalling is idempotent
+	if err := (MultusPlugin{}).Uninstall(cfg, clients); err != nil {
+		t.Fatalf("could not uninstall multus again: %v", err)
+	}
+}
@@ -18,13 +18,63 @@
 package cni
 
 import (
-	clientset "k8s.io/client-go/kubernetes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
 	"github.com/kubic-project/kubic-init/pkg/config"
 )
 
-// A CNI plugin is a function that is responsible for setting up everything
-type CniPlugin func(*config.KubicInitConfiguration, clientset.Interface) error
+// CniPluginDescription describes a CNI plugin and the configuration options it supports
+type CniPluginDescription struct {
+	// Description is a short description of the plugin
+	Description string
+
+	// Options is a map of the configuration options supported (ie, "network.cni.image")
+	// and their description
+	Options map[string]string
+
+	// ConfFiles are the files created by the plugin in the CNI configuration directory
+	ConfFiles []string
+
+	// Families are the address families supported by the plugin (ie, "ipv4" and "ipv6").
+	// An empty list means the plugin does not care about addresses (ie, meta-plugins).
+	Families []string
+}
+
+// CniPluginStatus is the status of a CNI plugin in the cluster
+type CniPluginStatus struct {
+	// Ready is true when the plugin has been completely rolled out
+	Ready bool
+
+	// Message is a human-readable description of the status
+	Message string
+}
+
+// A CNI plugin is responsible for setting up (and tearing down) everything
+// needed by a CNI driver in the cluster
+type CniPlugin interface {
+	// Describe returns a description of the plugin
+	Describe() CniPluginDescription
+
+	// Install installs the plugin. It must be idempotent.
+	Install(*config.KubicInitConfiguration, *kubicclient.Clients) error
+
+	// Upgrade upgrades an existing installation to the current configuration
+	Upgrade(*config.KubicInitConfiguration, *kubicclient.Clients) error
+
+	// Status returns the status of the plugin in the cluster
+	Status(*config.KubicInitConfiguration, *kubicclient.Clients) (CniPluginStatus, error)
+
+	// Uninstall removes everything created by the plugin
+	Uninstall(*config.KubicInitConfiguration, *kubicclient.Clients) error
+}
 
 type CniRegistry map[string]CniPlugin
 
@@ -37,9 +87,125 @@ func (registry CniRegistry) Has(name string) bool {
 	return found
 }
 
-func (registry CniRegistry) Load(name string, cfg *config.KubicInitConfiguration, client clientset.Interface) error {
-	return registry[name](cfg, client)
+// Names returns the (sorted) list of registered drivers
+func (registry CniRegistry) Names() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the plugin for a driver
+func (registry CniRegistry) Get(name string) (CniPlugin, error) {
+	plugin, found := registry[name]
+	if !found {
+		return nil, fmt.Errorf("unknown CNI driver %q: registered drivers: %s",
+			name, strings.Join(registry.Names(), ", "))
+	}
+	return plugin, nil
+}
+
+// Load installs a driver
+func (registry CniRegistry) Load(name string, cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	plugin, err := registry.Get(name)
+	if err != nil {
+		return err
+	}
+	return plugin.Install(cfg, clients)
 }
 
 // Global Registry
 var Registry = CniRegistry{}
+
+// MetaRegistry is the registry of meta-plugins: plugins that are chained
+// in front of the CNI driver (ie, for adding secondary networks)
+var MetaRegistry = CniRegistry{}
+
+// Deploy installs the CNI driver and, if present, the meta-plugin
+func Deploy(cfg *config.KubicInitConfiguration, clients *kubicclient.Clients) error {
+	if err := Registry.Load(cfg.Network.Cni.Driver, cfg, clients); err != nil {
+		return err
+	}
+
+	if len(cfg.Network.Cni.Meta) > 0 {
+		if err := MetaRegistry.Load(cfg.Network.Cni.Meta, cfg, clients); err != nil {
+			return fmt.Errorf("could not install the %q CNI meta-plugin: %v", cfg.Network.Cni.Meta, err)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	// check the CNI driver is in the registry when validating the configuration
+	config.RegisterValidation(func(cfg *config.KubicInitConfiguration) field.ErrorList {
+		allErrs := field.ErrorList{}
+		fldPath := field.NewPath("network", "cni")
+		if !Registry.Has(cfg.Network.Cni.Driver) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("driver"),
+				cfg.Network.Cni.Driver, Registry.Names()))
+		}
+		if len(cfg.Network.Cni.Meta) > 0 && !MetaRegistry.Has(cfg.Network.Cni.Meta) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("meta"),
+				cfg.Network.Cni.Meta, MetaRegistry.Names()))
+		}
+		allErrs = append(allErrs, validateFamilies(&cfg.Network, fldPath)...)
+		allErrs = append(allErrs, validateNetworks(&cfg.Network.Cni, fldPath)...)
+		return allErrs
+	})
+}
+
+// validateFamilies checks the driver supports the address families of the pods subnets
+func validateFamilies(network *config.NetworkConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	plugin, err := Registry.Get(network.Cni.Driver)
+	if err != nil {
+		return allErrs
+	}
+
+	supported := plugin.Describe().Families
+	if len(supported) == 0 {
+		return allErrs
+	}
+	for _, family := range network.GetFamilies() {
+		if !sets.NewString(supported...).Has(family) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("driver"), network.Cni.Driver,
+				fmt.Sprintf("the driver does not support %s subnets (supported: %s)",
+					family, strings.Join(supported, ", "))))
+		}
+	}
+	return allErrs
+}
+
+// validateNetworks checks the additional networks
+func validateNetworks(cniCfg *config.CniConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(cniCfg.Networks) > 0 && len(cniCfg.Meta) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("networks"), len(cniCfg.Networks),
+			"additional networks require a meta-plugin (see network.cni.meta)"))
+	}
+
+	seen := sets.NewString()
+	for i, network := range cniCfg.Networks {
+		idxPath := fldPath.Child("networks").Index(i)
+		for _, msg := range validation.IsDNS1123Subdomain(network.Name) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), network.Name, msg))
+		}
+		for _, msg := range validation.IsDNS1123Label(network.Namespace) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("namespace"), network.Namespace, msg))
+		}
+
+		key := network.Namespace + "/" + network.Name
+		if seen.Has(key) {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), key))
+		}
+		seen.Insert(key)
+
+		if !json.Valid([]byte(network.Config)) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("config"), network.Config, "must be a valid JSON CNI configuration"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cni
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+type dummyPlugin struct{}
+
+func (dummyPlugin) Describe() CniPluginDescription {
+	return CniPluginDescription{Description: "dummy"}
+}
+
+func (dummyPlugin) Install(*config.KubicInitConfiguration, *kubicclient.Clients) error { return nil }
+
+func (dummyPlugin) Upgrade(*config.KubicInitConfiguration, *kubicclient.Clients) error { return nil }
+
+func (dummyPlugin) Status(*config.KubicInitConfiguration, *kubicclient.Clients) (CniPluginStatus, error) {
+	return CniPluginStatus{Ready: true}, nil
+}
+
+func (dummyPlugin) Uninstall(*config.KubicInitConfiguration, *kubicclient.Clients) error { return nil }
+
+func TestRegistryUnknownDriver(t *testing.T) {
+	registry := CniRegistry{}
+	registry.Register("b-dummy", dummyPlugin{})
+	registry.Register("a-dummy", dummyPlugin{})
+
+	if _, err := registry.Get("a-dummy"); err != nil {
+		t.Fatalf("Error: could not get a registered driver: %s", err)
+	}
+
+	err := registry.Load("something", &config.KubicInitConfiguration{}, nil)
+	if err == nil {
+		t.Fatalf("Error: no error when loading an unknown driver")
+	}
+	t.Logf("Error obtained (as expected): %s", err)
+	if !strings.Contains(err.Error(), "a-dummy, b-dummy") {
+		t.Fatalf("Error: the registered drivers are not listed in the error: %s", err)
+	}
+}
+
+func TestValidateNetworks(t *testing.T) {
+	cniCfg := config.CniConfiguration{
+		Networks: []config.CniNetworkConfiguration{
+			{Name: "macvlan", Namespace: "default", Config: `{"cniVersion": "0.3.1", "type": "macvlan"}`},
+			{Name: "macvlan", Namespace: "default", Config: `{"cniVersion": "0.3.1", "type": "macvlan"}`},
+			{Name: "Bad_Name", Namespace: "default", Config: `{"cniVersion": "0.3.1"`},
+		},
+	}
+
+	expected := []string{
+		"network.cni.networks",
+		"network.cni.networks[1].name",
+		"network.cni.networks[2].name",
+		"network.cni.networks[2].config",
+	}
+
+	errs := validateNetworks(&cniCfg, field.NewPath("network", "cni"))
+	if len(errs) != len(expected) {
+		t.Fatalf("Error: %d errors expected, got %v", len(expected), errs)
+	}
+	for i, f := range expected {
+		t.Logf("Validation error: %s", errs[i])
+		if errs[i].Field != f {
+			t.Fatalf("Error: error in %q expected, got %v", f, errs[i])
+		}
+	}
+
+	cniCfg.Meta = "multus"
+	cniCfg.Networks = cniCfg.Networks[:1]
+	if errs := validateNetworks(&cniCfg, field.NewPath("network", "cni")); len(errs) > 0 {
+		t.Fatalf("Error: unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cni
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+func init() {
+	// register the cleanups common to all the CNI drivers
+	reset.Register(reset.Step{
+		Name:        "cni-conf",
+		Description: "remove the CNI configuration files",
+		Paths:       confFiles,
+	})
+
+	reset.Register(reset.Step{
+		Name:        "cni-bin",
+		Description: "remove the CNI binaries",
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			return []string{cfg.Network.Cni.BinDir}
+		},
+	})
+
+	// opt-in, as the rules of other software (ie, a firewall) could also jump to these chains
+	reset.Register(reset.Step{
+		Name:        "iptables",
+		Description: "remove the iptables chains of kube-proxy and the CNI driver",
+		Run:         cleanupIptables,
+		OptIn:       true,
+	})
+}
+
+// prefixes of the iptables chains created by kube-proxy and the CNI drivers
+var iptablesChainPrefixes = []string{"KUBE-", "CNI-", "FLANNEL", "cali-", "CILIUM_"}
+
+// confFiles returns the CNI configuration files created by the driver and the meta-plugin
+func confFiles(cfg *config.KubicInitConfiguration) []string {
+	files := []string{}
+	for _, plugin := range []struct {
+		registry CniRegistry
+		name     string
+	}{
+		{Registry, cfg.Network.Cni.Driver},
+		{MetaRegistry, cfg.Network.Cni.Meta},
+	} {
+		if len(plugin.name) == 0 || !plugin.registry.Has(plugin.name) {
+			continue
+		}
+		p, _ := plugin.registry.Get(plugin.name)
+		for _, file := range p.Describe().ConfFiles {
+			files = append(files, filepath.Join(cfg.Network.Cni.ConfDir, file))
+		}
+	}
+	return files
+}
+
+// cleanupIptables removes the iptables chains created by the CNI driver and by
+// kube-proxy (and the rules jumping to them), as "kubeadm reset" does not remove them.
+// Any other chain or rule is left untouched.
+func cleanupIptables(cfg *config.KubicInitConfiguration) error {
+	commands := []string{"iptables"}
+	for _, family := range cfg.Network.GetFamilies() {
+		if family == kubicutil.FamilyIPv6 {
+			commands = append(commands, "ip6tables")
+		}
+	}
+
+	for _, command := range commands {
+		for _, table := range []string{"filter", "nat", "mangle"} {
+			save, err := kubicutil.CommandOutput(command+"-save", "-t", table)
+			if err != nil {
+				return err
+			}
+			cleanup, err := iptablesCleanup(table, save)
+			if err != nil {
+				return err
+			}
+			for _, args := range cleanup {
+				if err := kubicutil.RunCommand(command, args...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isCleanupChain returns true for the chains created by kube-proxy and the CNI drivers
+func isCleanupChain(chain string) bool {
+	for _, prefix := range iptablesChainPrefixes {
+		if strings.HasPrefix(chain, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// iptablesCleanup returns the arguments for the iptables commands that remove, from
+// a `table` dumped with iptables-save, the rules jumping to the chains of kube-proxy
+// and the CNI drivers, and then these chains
+func iptablesCleanup(table string, save string) ([][]string, error) {
+	chains := []string{}
+	jumps := [][]string{}
+	for _, line := range strings.Split(save, "\n") {
+		switch {
+		case strings.HasPrefix(line, ":"):
+			// a chain declaration (ie, ":KUBE-SERVICES - [0:0]")
+			fields := strings.Fields(line[1:])
+			if len(fields) > 0 && isCleanupChain(fields[0]) {
+				chains = append(chains, fields[0])
+			}
+		case strings.HasPrefix(line, "-A "):
+			args, err := splitIptablesRule(line)
+			if err != nil {
+				return nil, err
+			}
+			if len(args) < 2 || isCleanupChain(args[1]) {
+				// the rules in these chains are removed when flushing them
+				continue
+			}
+			if isCleanupChain(iptablesRuleTarget(args)) {
+				jumps = append(jumps, append([]string{"-t", table, "-D"}, args[1:]...))
+			}
+		}
+	}
+
+	cleanup := jumps
+	for _, chain := range chains {
+		cleanup = append(cleanup, []string{"-t", table, "-F", chain})
+	}
+	for _, chain := range chains {
+		cleanup = append(cleanup, []string{"-t", table, "-X", chain})
+	}
+	return cleanup, nil
+}
+
+// iptablesRuleTarget returns the target of a rule (the chain it jumps or goes to)
+func iptablesRuleTarget(args []string) string {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "-j" || args[i] == "-g" {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// splitIptablesRule splits a rule printed by iptables-save in its arguments,
+// where arguments with spaces are double-quoted (ie, comments)
+func splitIptablesRule(line string) ([]string, error) {
+	args := []string{}
+	arg := bytes.Buffer{}
+	inArg, quoted := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(line):
+			i++
+			arg.WriteByte(line[i])
+		case c == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && (c == ' ' || c == '\t'):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in iptables rule %q", line)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cni
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIptablesCleanup(t *testing.T) {
+	save := `# Generated by iptables-save v1.6.1
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+:DOCKER - [0:0]
+:KUBE-SERVICES - [0:0]
+:KUBE-SVC-NPX46M4PTMTKRN6Y - [0:0]
+:CNI-8f2b1a - [0:0]
+-A PREROUTING -m comment --comment "kubernetes service portals" -j KUBE-SERVICES
+-A PREROUTING -m addrtype --dst-type LOCAL -j DOCKER
+-A POSTROUTING -s 10.88.0.0/16 -j CNI-8f2b1a
+-A POSTROUTING -s 172.17.0.0/16 ! -o docker0 -j MASQUERADE
+-A KUBE-SERVICES -d 172.24.0.1/32 -p tcp -j KUBE-SVC-NPX46M4PTMTKRN6Y
+-A CNI-8f2b1a -d 10.88.0.0/16 -j ACCEPT
+COMMIT
+`
+	expected := [][]string{
+		{"-t", "nat", "-D", "PREROUTING", "-m", "comment", "--comment", "kubernetes service portals", "-j", "KUBE-SERVICES"},
+		{"-t", "nat", "-D", "POSTROUTING", "-s", "10.88.0.0/16", "-j", "CNI-8f2b1a"},
+		{"-t", "nat", "-F", "KUBE-SERVICES"},
+		{"-t", "nat", "-F", "KUBE-SVC-NPX46M4PTMTKRN6Y"},
+		{"-t", "nat", "-F", "CNI-8f2b1a"},
+		{"-t", "nat", "-X", "KUBE-SERVICES"},
+		{"-t", "nat", "-X", "KUBE-SVC-NPX46M4PTMTKRN6Y"},
+		{"-t", "nat", "-X", "CNI-8f2b1a"},
+	}
+
+	cleanup, err := iptablesCleanup("nat", save)
+	if err != nil {
+		t.Fatalf("could not get the iptables cleanup: %v", err)
+	}
+	if !reflect.DeepEqual(cleanup, expected) {
+		got := []string{}
+		for _, args := range cleanup {
+			got = append(got, strings.Join(args, " "))
+		}
+		t.Fatalf("unexpected iptables cleanup:\n%s", strings.Join(got, "\n"))
+	}
+
+	if _, err := iptablesCleanup("nat", `-A INPUT -m comment --comment "unterminated -j KUBE-FIREWALL`); err == nil {
+		t.Fatalf("unterminated quotes should be an error")
+	}
+}
+
+func TestSplitIptablesRule(t *testing.T) {
+	args, err := splitIptablesRule(`-A INPUT -m comment --comment "say \"hi\"" -j ACCEPT`)
+	if err != nil {
+		t.Fatalf("could not split the rule: %v", err)
+	}
+	expected := []string{"-A", "INPUT", "-m", "comment", "--comment", `say "hi"`, "-j", "ACCEPT"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("unexpected arguments: %q", args)
+	}
+}
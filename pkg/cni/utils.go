@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cni
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// DaemonSetStatus returns the rollout status of the DaemonSet of a CNI driver
+func DaemonSetStatus(client clientset.Interface, namespace, name string) (CniPluginStatus, error) {
+	ds, err := client.AppsV1().DaemonSets(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return CniPluginStatus{Ready: false, Message: fmt.Sprintf("DaemonSet %s/%s not found", namespace, name)}, nil
+	} else if err != nil {
+		return CniPluginStatus{}, err
+	}
+
+	status := ds.Status
+	switch {
+	case ds.Generation > status.ObservedGeneration:
+		return CniPluginStatus{Ready: false, Message: "waiting for the DaemonSet spec update to be observed"}, nil
+	case status.UpdatedNumberScheduled < status.DesiredNumberScheduled:
+		return CniPluginStatus{Ready: false, Message: fmt.Sprintf("%d out of %d new pods have been updated",
+			status.UpdatedNumberScheduled, status.DesiredNumberScheduled)}, nil
+	case status.NumberAvailable < status.DesiredNumberScheduled:
+		return CniPluginStatus{Ready: false, Message: fmt.Sprintf("%d of %d updated pods are available",
+			status.NumberAvailable, status.DesiredNumberScheduled)}, nil
+	}
+
+	return CniPluginStatus{Ready: true, Message: fmt.Sprintf("%d pods available", status.NumberAvailable)}, nil
+}
+
+// IgnoreNotFound returns nil for NotFound errors (useful when uninstalling things)
+func IgnoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// DeleteOptions returns the options for deleting objects (in foreground)
+func DeleteOptions() *metav1.DeleteOptions {
+	foregroundDelete := metav1.DeletePropagationForeground
+	return &metav1.DeleteOptions{
+		PropagationPolicy: &foregroundDelete,
+	}
+}
@@ -16,10 +16,12 @@
  */
 
 //go:generate sh -c "GO111MODULE=off deepcopy-gen -O zz_generated.deepcopy -i ./... -h ../../hack/boilerplate.go.txt"
+//go:generate sh -c "GO111MODULE=off defaulter-gen -O zz_generated.defaults -i ./v1alpha2,./v1alpha3 -h ../../hack/boilerplate.go.txt"
 
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -28,96 +30,232 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/golang/glog"
 	"github.com/kubernetes/kubernetes/cmd/kubeadm/app/util/apiclient"
 	"github.com/yuroyoro/swalker"
-	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
 	clientset "k8s.io/client-go/kubernetes"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
 
+	"github.com/kubic-project/kubic-init/pkg/config/v1alpha2"
+	"github.com/kubic-project/kubic-init/pkg/crypto"
 	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
 )
 
 // The CNI configuration
 // Subnets details are specified in the kubeadm configuration file
 type CniConfiguration struct {
-	BinDir  string `json:"binDir,omitempty"`
-	ConfDir string `json:"confDir,omitempty"`
-	Driver  string `json:"driver,omitempty"`
-	Image   string `json:"image,omitempty"`
+	BinDir  string
+	ConfDir string
+	Driver  string
+	Image   string
+	Calico  CalicoConfiguration
+	Flannel FlannelConfiguration
+
+	// Meta is an (optional) meta-plugin chained in front of the driver (ie, "multus")
+	Meta string
+	// MetaImage is the image for the meta-plugin
+	MetaImage string
+	// Networks are additional networks, created as NetworkAttachmentDefinitions
+	// (only available with a meta-plugin)
+	Networks []CniNetworkConfiguration
+}
+
+// An additional network
+type CniNetworkConfiguration struct {
+	Name      string
+	Namespace string
+	// Config is the CNI configuration (in JSON) for the network
+	Config string
+}
+
+// The Flannel configuration
+type FlannelConfiguration struct {
+	Backend FlannelBackendConfiguration
+	// MTU for the pods interfaces (0 for using the MTU obtained by flannel)
+	MTU int
+	// Interface is the interface used for the inter-host communication
+	// (the interface with the pod IP is used when empty)
+	Interface string
+	LogLevel  int
+}
+
+// The Flannel backend configuration
+type FlannelBackendConfiguration struct {
+	// Type is the backend type: "vxlan", "host-gw", "wireguard" or "ipsec"
+	Type string
+	// VNI is the VXLAN identifier (only for "vxlan")
+	VNI int
+	// Port is the UDP port used by "vxlan" or "wireguard"
+	Port int
+	// DirectRouting enables direct routes when the hosts are in the same subnet (only for "vxlan")
+	DirectRouting bool
+	// PSK is the pre-shared key for "ipsec" or "wireguard"
+	PSK string `kubic:"sensitive"`
+}
+
+// The Calico configuration
+type CalicoConfiguration struct {
+	// Encapsulation is the encapsulation used for the traffic between pods
+	// in different nodes: "ipip", "vxlan" or "none"
+	Encapsulation string
+	// CniImage is the image with the Calico CNI plugin
+	CniImage string
 }
 
 type ClusterFormationConfiguration struct {
-	Seeder      string `json:"seeder,omitempty"`
-	Token       string `json:"token,omitempty"`
-	AutoApprove bool   `json:"autoApprove,omitempty"`
+	Seeder      string
+	Token       string `kubic:"sensitive"`
+	AutoApprove bool
+	// Role is the role of a node joining the seeder: "master" or "worker"
+	Role string
+	// ControlPlaneSecret is a secret shared by all the masters, used for
+	// encrypting the control-plane certificates uploaded to the cluster
+	ControlPlaneSecret string `kubic:"sensitive"`
+	// Discovery is used for finding the seeder when no seeder is provided
+	Discovery DiscoveryConfiguration
+	// Approval is used for approving new nodes when AutoApprove is disabled
+	Approval ApprovalConfiguration
+}
+
+type ApprovalConfiguration struct {
+	// Allowlist is a list of node names and IP addresses approved automatically
+	Allowlist []string
+	// Policy is used for approving automatically the nodes not in the Allowlist
+	Policy ApprovalPolicyConfiguration
+}
+
+// ApprovalPolicyConfiguration is a policy for approving new nodes: nodes that
+// satisfy all the rules are approved (empty rules are not checked)
+type ApprovalPolicyConfiguration struct {
+	// Hostnames are regular expressions for the names of the nodes approved
+	Hostnames []string
+	// CIDRs are the subnets for the IP addresses of the nodes approved. The addresses are
+	// the source addresses of the registrations of the joining nodes (not the node names)
+	CIDRs []string
+	// MaxNodes is the maximum number of nodes in the cluster
+	MaxNodes int
+	// MaxNodesPerToken is the maximum number of nodes approved with the same bootstrap token
+	MaxNodesPerToken int
+	// Windows are the time windows when nodes can be approved (ie, "Mon-Fri 08:00-18:00")
+	Windows []string
+}
+
+// IsEmpty returns true when the policy has no rules
+func (policy ApprovalPolicyConfiguration) IsEmpty() bool {
+	return len(policy.Hostnames) == 0 && len(policy.CIDRs) == 0 &&
+		policy.MaxNodes == 0 && policy.MaxNodesPerToken == 0 && len(policy.Windows) == 0
+}
+
+type DiscoveryConfiguration struct {
+	// Providers used (in order) for finding the seeder: "mdns", "dns" and/or "file"
+	Providers []string
+	// Domain for the DNS SRV lookups (ie, "_kubic-seeder._tcp.<domain>")
+	Domain string
+	// File with the addresses of the seeder (one per line)
+	File string
+	// Election enables the election of a seeder among the nodes started
+	// when no seeder can be found (requires the "mdns" provider)
+	Election bool
 }
 
 type OIDCConfiguration struct {
-	Issuer   string `yaml:"issuer,omitempty"`
-	ClientID string `yaml:"clientID,omitempty"`
-	CA       string `yaml:"ca,omitempty"`
-	Username string `yaml:"username,omitempty"`
-	Groups   string `yaml:"groups,omitempty"`
+	Issuer   string
+	ClientID string
+	CA       string
+	Username string
+	Groups   string
 }
 
 type AuthConfiguration struct {
-	OIDC OIDCConfiguration `yaml:"OIDC,omitempty"`
+	OIDC OIDCConfiguration
 }
 
 type CertsConfiguration struct {
-	// TODO
-	Directory string `yaml:"directory,omitempty"`
-	CaHash    string `yaml:"caCrtHash,omitempty"`
+	Directory string
+	// CaHashes are the public key pins (ie, "sha256:...") accepted for the seeder CA
+	// (more than one hash can be provided, ie, during a CA rotation)
+	CaHashes []string
+	// CaCrt is the seeder CA certificate (a path or some inline PEM), used for
+	// verifying the identity of the seeder
+	CaCrt string
+	// RequireVerification makes joining without verifying the seeder identity an error
+	RequireVerification bool
 }
 
 type DNSConfiguration struct {
-	Domain       string `yaml:"domain,omitempty"`
-	ExternalFqdn string `yaml:"externalFqdn,omitempty"`
+	Domain       string
+	ExternalFqdn string
 }
 
 type ProxyConfiguration struct {
-	Http       string `yaml:"http,omitempty"`
-	Https      string `yaml:"https,omitempty"`
-	NoProxy    string `yaml:"noProxy,omitempty"`
-	SystemWide bool   `yaml:"systemWide,omitempty"`
+	Http       string
+	Https      string
+	NoProxy    string
+	SystemWide bool
 }
 
 type BindConfiguration struct {
-	Address   string `yaml:"address,omitempty"`
-	Interface string `yaml:"interface,omitempty"`
+	Address   string
+	Interface string
+	Family    string
+}
+
+// LoadBalancerConfiguration is the configuration for the built-in
+// load balancer for the API servers (enabled when a VirtualIP is provided)
+type LoadBalancerConfiguration struct {
+	// VirtualIP is the address (moved between masters) where the API servers are reachable
+	VirtualIP string
+	// Interface is the network interface where the VirtualIP is added
+	Interface string
+	// Port is the port where the load balancer listens in the VirtualIP
+	Port int
+	// RouterID is the VRRP virtual router ID (must be unique in the network segment)
+	RouterID int
+	// Image is the image for the VRRP daemon (keepalived)
+	Image string
+	// ProxyImage is the image for the TCP proxy (haproxy)
+	ProxyImage string
 }
 
 type PathsConfigration struct {
-	Kubeadm string `yaml:"kubeadm,omitempty"`
+	Kubeadm string
 }
 
 type LocalEtcdConfiguration struct {
-	ServerCertSANs []string `yaml:"serverCertSANs,omitempty"`
-	PeerCertSANs   []string `yaml:"peerCertSANs,omitempty"`
+	ServerCertSANs []string
+	PeerCertSANs   []string
 }
 
 type EtcdConfiguration struct {
-	LocalEtcd *LocalEtcdConfiguration `yaml:"local,omitempty"`
+	LocalEtcd *LocalEtcdConfiguration
 }
 
 type NetworkConfiguration struct {
-	Bind          BindConfiguration  `yaml:"bind,omitempty"`
-	Cni           CniConfiguration   `yaml:"cni,omitempty"`
-	Dns           DNSConfiguration   `yaml:"dns,omitempty"`
-	Proxy         ProxyConfiguration `yaml:"proxy,omitempty"`
-	PodSubnet     string             `yaml:"podSubnet,omitempty"`
-	ServiceSubnet string             `yaml:"serviceSubnet,omitempty"`
+	Bind  BindConfiguration
+	Cni   CniConfiguration
+	Dns   DNSConfiguration
+	Proxy ProxyConfiguration
+	// LoadBalancer is the built-in load balancer for the API servers
+	LoadBalancer LoadBalancerConfiguration
+	// PodSubnets are the subnets for the pods: one subnet, or one IPv4
+	// and one IPv6 subnet for dual-stack
+	PodSubnets []string
+	// ServiceSubnets are the subnets for the services, in the same families as the PodSubnets
+	ServiceSubnets []string
 }
 
 type RuntimeConfiguration struct {
-	Engine string `yaml:"engine,omitempty"`
+	Engine string
 }
 
 type FeaturesConfiguration struct {
-	PSP bool `yaml:"PSP,omitempty"`
+	PSP bool
 }
 
 type ServicesConfiguration struct {
@@ -125,63 +263,29 @@ type ServicesConfiguration struct {
 
 // The kubic-init configuration
 //
-// +genclient
+// This is the internal version of the configuration: the configuration
+// file is decoded in one of the versioned types (ie, v1alpha3) and then
+// converted to this type.
+//
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
-// +genclient:nonNamespaced
-// +k8s:openapi-gen=true
 type KubicInitConfiguration struct {
 	metav1.TypeMeta
-	Network          NetworkConfiguration          `yaml:"network,omitempty"`
-	Paths            PathsConfigration             `yaml:"paths,omitempty"`
-	ClusterFormation ClusterFormationConfiguration `yaml:"clusterFormation,omitempty"`
-	Certificates     CertsConfiguration            `yaml:"certificates,omitempty"`
-	Etcd             EtcdConfiguration             `yaml:"etcd,omitempty"`
-	Runtime          RuntimeConfiguration          `yaml:"runtime,omitempty"`
-	Features         FeaturesConfiguration         `yaml:"features,omitempty"`
-	Services         ServicesConfiguration         `yaml:"services,omitempty"`
-	Auth             AuthConfiguration             `yaml:"auth,omitempty"`
-}
-
-// defaultConfiguration is the default configuration
-var defaultConfiguration = KubicInitConfiguration{
-	Certificates: CertsConfiguration{
-		Directory: DefaultCertsDirectory,
-	},
-	Paths: PathsConfigration{
-		Kubeadm: DefaultKubeadmPath,
-	},
-	Etcd: EtcdConfiguration{
-		LocalEtcd: &LocalEtcdConfiguration{},
-	},
-	Network: NetworkConfiguration{
-		PodSubnet:     DefaultPodSubnet,
-		ServiceSubnet: DefaultServiceSubnet,
-		Dns: DNSConfiguration{
-			Domain: DefaultDNSDomain,
-		},
-		Cni: CniConfiguration{
-			Driver:  DefaultCniDriver,
-			BinDir:  DefaultCniBinDir,
-			ConfDir: DefaultCniConfDir,
-			Image:   DefaultCniImage,
-		},
-	},
-	ClusterFormation: ClusterFormationConfiguration{
-		AutoApprove: true,
-	},
-	Runtime: RuntimeConfiguration{
-		Engine: DefaultRuntimeEngine,
-	},
-	Features: FeaturesConfiguration{
-		PSP: true,
-	},
+	Network          NetworkConfiguration
+	Paths            PathsConfigration
+	ClusterFormation ClusterFormationConfiguration
+	Certificates     CertsConfiguration
+	Etcd             EtcdConfiguration
+	Runtime          RuntimeConfiguration
+	Features         FeaturesConfiguration
+	Services         ServicesConfiguration
+	Auth             AuthConfiguration
 }
 
 // Load a Kubic configuration file, setting some default values
-func ConfigFileAndDefaultsToKubicInitConfig(cfgPath string) (*KubicInitConfiguration, error) {
+// Unknown keys in the file are considered an error unless `lenient` is set.
+func ConfigFileAndDefaultsToKubicInitConfig(cfgPath string, lenient bool) (*KubicInitConfiguration, error) {
 	var err error
-
-	internalcfg := defaultConfiguration.DeepCopy()
+	var b []byte
 
 	if len(cfgPath) > 0 {
 		glog.V(1).Infof("[kubic] loading kubic-init configuration from '%s'", cfgPath)
@@ -189,16 +293,15 @@ func ConfigFileAndDefaultsToKubicInitConfig(cfgPath string) (*KubicInitConfigura
 			return nil, fmt.Errorf("%q does not exist: %v", cfgPath, err)
 		}
 
-		b, err := ioutil.ReadFile(cfgPath)
+		b, err = ioutil.ReadFile(cfgPath)
 		if err != nil {
 			return nil, fmt.Errorf("unable to read config from %q [%v]", cfgPath, err)
 		}
+	}
 
-		if err = yaml.Unmarshal(b, &internalcfg); err != nil {
-			return nil, fmt.Errorf("unable to decode config from bytes: %v", err)
-		}
-
-		// TODO: check the internalcfg['kind']
+	internalcfg, err := BytesToKubicInitConfig(b, lenient)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode config from %q: %v", cfgPath, err)
 	}
 
 	// Overwrite some values with environment variables
@@ -212,47 +315,133 @@ func ConfigFileAndDefaultsToKubicInitConfig(cfgPath string) (*KubicInitConfigura
 		internalcfg.ClusterFormation.Token = tokenEnv
 	}
 
-	// The seeder is a IP:PORT, so parse the current seeder and reformat it appropriately
-	if len(internalcfg.ClusterFormation.Seeder) > 0 {
-		seeder := internalcfg.ClusterFormation.Seeder
-		if !strings.HasPrefix(seeder, "http") {
-			seeder = fmt.Sprintf("https://%s", internalcfg.ClusterFormation.Seeder)
-		}
-		u, err := url.Parse(seeder)
+	if secretEnv, found := os.LookupEnv(DefaultEnvVarControlPlaneSecret); found {
+		glog.V(3).Infof("[kubic] environment: setting control-plane secret")
+		internalcfg.ClusterFormation.ControlPlaneSecret = secretEnv
+	}
+
+	if err := internalcfg.SetSeeder(internalcfg.ClusterFormation.Seeder); err != nil {
+		return nil, err
+	}
+
+	if glog.V(8) {
+		marshalled, err := MarshalKubicInitConfig(internalcfg)
 		if err != nil {
 			return nil, err
 		}
-		port := u.Port()
+		glog.Infof("[kubic] after parsing the config file:\n%s", marshalled)
+	}
+
+	return internalcfg, nil
+}
+
+// the version used by the first configuration files (ie, in the cloud-init templates),
+// that were decoded with the v1alpha2 schema and ignoring the unknown keys
+var legacyGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+// BytesToKubicInitConfig decodes a (versioned) configuration, converting it
+// to the internal type and setting the default values.
+// The version is obtained from the "apiVersion", assuming v1alpha2 when it is
+// missing, or the latest version when there is no configuration at all.
+// Configurations for v1alpha1 are decoded as v1alpha2, ignoring the unknown keys.
+func BytesToKubicInitConfig(b []byte, lenient bool) (*KubicInitConfiguration, error) {
+	gvk, err := getGroupVersionKind(b)
+	if err != nil {
+		return nil, err
+	}
 
-		// if no port has been provided, use the API server default port
-		if len(port) == 0 {
-			port = fmt.Sprintf("%d", DefaultAPIServerPort)
+	if gvk.GroupVersion() == legacyGroupVersion {
+		glog.V(1).Infof("[kubic] WARNING: configuration uses %q: decoding it as %q",
+			legacyGroupVersion, v1alpha2.SchemeGroupVersion)
+		gvk = v1alpha2.SchemeGroupVersion.WithKind(gvk.Kind)
+		lenient = true
+	}
+
+	versioned, err := Scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported configuration version %q: %v", gvk.GroupVersion(), err)
+	}
+
+	if err := checkUnknownKeys(b, versioned); err != nil {
+		if !lenient {
+			return nil, fmt.Errorf("%v\n(use --lenient-config for ignoring unknown keys)", err)
 		}
+		glog.V(1).Infof("[kubic] WARNING: ignoring %v", err)
+	}
 
-		internalcfg.ClusterFormation.Seeder = fmt.Sprintf("%s:%s", u.Hostname(), port)
+	if err := yaml.Unmarshal(b, versioned); err != nil {
+		return nil, err
 	}
+	Scheme.Default(versioned)
 
-	if glog.V(8) {
-		marshalled, err := yaml.Marshal(internalcfg)
+	internalcfg := &KubicInitConfiguration{}
+	if err := Scheme.Convert(versioned, internalcfg, nil); err != nil {
+		return nil, err
+	}
+
+	// an old version could not have some of the fields in the latest version: convert
+	// the configuration to the latest version, so they can get their default values
+	if gvk.GroupVersion() != LatestVersion {
+		glog.V(1).Infof("[kubic] WARNING: configuration uses an old version, %q: please migrate it to %q",
+			gvk.GroupVersion(), LatestVersion)
+
+		latest, err := Scheme.ConvertToVersion(internalcfg, LatestVersion)
 		if err != nil {
 			return nil, err
 		}
-		glog.Infof("[kubic] after parsing the config file:\n%s", marshalled)
+		Scheme.Default(latest)
+
+		internalcfg = &KubicInitConfiguration{}
+		if err := Scheme.Convert(latest, internalcfg, nil); err != nil {
+			return nil, err
+		}
 	}
 
 	return internalcfg, nil
 }
 
+// MarshalKubicInitConfig marshals the configuration, using the latest version
+func MarshalKubicInitConfig(kubicCfg *KubicInitConfiguration) ([]byte, error) {
+	return kubeadmutil.MarshalToYamlForCodecs(kubicCfg, LatestVersion, Codecs)
+}
+
+// getGroupVersionKind returns the GroupVersionKind of a configuration
+func getGroupVersionKind(b []byte) (schema.GroupVersionKind, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return LatestVersion.WithKind(KubicInitConfigurationKind), nil
+	}
+
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(b, &typeMeta); err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+
+	if len(typeMeta.APIVersion) == 0 {
+		glog.V(1).Infof("[kubic] WARNING: no apiVersion in the configuration: assuming %q", v1alpha2.SchemeGroupVersion)
+		typeMeta.APIVersion = v1alpha2.SchemeGroupVersion.String()
+	}
+	if len(typeMeta.Kind) == 0 {
+		typeMeta.Kind = KubicInitConfigurationKind
+	}
+
+	return typeMeta.GroupVersionKind(), nil
+}
+
 // ToConfigMap uploads the configuration to a "kubic-init.yaml" file in a ConfigMap
+// Sensitive values (see Scrub()) are removed from the ConfigMap and stored in a Secret
+// with the same name plus a "-secrets" suffix. Use FromConfigMap() for reading it.
 func (kubicCfg *KubicInitConfiguration) ToConfigMap(client clientset.Interface, name string, extraLabels map[string]string) error {
 	filename := filepath.Base(DefaultKubicInitConfig)
 
 	glog.V(3).Infof("[kubic] uploading to ConfigMap %s/%s the '%s' configuration",
 		metav1.NamespaceSystem, name, filename)
 
-	// TODO: check there is no sensible information in the kubicCfg and remove it...
+	scrubbed, sensitive := kubicCfg.Scrub()
+	if err := toSecret(client, name+secretNameSuffix, sensitive, extraLabels); err != nil {
+		return err
+	}
 
-	marshalled, err := yaml.Marshal(kubicCfg)
+	marshalled, err := MarshalKubicInitConfig(scrubbed)
 	if err != nil {
 		return err
 	}
@@ -295,7 +484,7 @@ func (kubicCfg *KubicInitConfiguration) SetVars(vars []string) error {
 		}
 
 		if glog.V(8) {
-			marshalled, err := yaml.Marshal(kubicCfg)
+			marshalled, err := MarshalKubicInitConfig(kubicCfg)
 			if err != nil {
 				return err
 			}
@@ -310,15 +499,130 @@ func (kubicCfg KubicInitConfiguration) IsSeeder() bool {
 	return len(kubicCfg.ClusterFormation.Seeder) == 0
 }
 
+// HasDiscovery returns true when the seeder must be discovered (ie, no seeder
+// has been provided but some discovery providers have been configured)
+func (kubicCfg KubicInitConfiguration) HasDiscovery() bool {
+	return kubicCfg.IsSeeder() && len(kubicCfg.ClusterFormation.Discovery.Providers) > 0
+}
+
+// SetSeeder sets the seeder address, reformatting it as a IP:PORT
+// (an empty address makes this node the seeder)
+func (kubicCfg *KubicInitConfiguration) SetSeeder(seeder string) error {
+	if len(seeder) == 0 {
+		kubicCfg.ClusterFormation.Seeder = ""
+		return nil
+	}
+
+	if !strings.HasPrefix(seeder, "http") {
+		seeder = fmt.Sprintf("https://%s", seeder)
+	}
+	u, err := url.Parse(seeder)
+	if err != nil {
+		return err
+	}
+	port := u.Port()
+
+	// if no port has been provided, use the API server default port
+	if len(port) == 0 {
+		port = fmt.Sprintf("%d", DefaultAPIServerPort)
+	}
+
+	kubicCfg.ClusterFormation.Seeder = net.JoinHostPort(u.Hostname(), port)
+	return nil
+}
+
+// IsMaster returns true when this node will be part of the control plane:
+// the seeder, or a node joining the seeder with the "master" role
+func (kubicCfg KubicInitConfiguration) IsMaster() bool {
+	return kubicCfg.IsSeeder() || kubicCfg.ClusterFormation.Role == RoleMaster
+}
+
+// GetFamilies returns the address families of the pods subnets (ie, ["ipv4", "ipv6"])
+func (network NetworkConfiguration) GetFamilies() []string {
+	families := []string{}
+	for _, subnet := range network.PodSubnets {
+		if family, err := kubicutil.GetCIDRFamily(subnet); err == nil {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// IsDualStack returns true when the cluster has both IPv4 and IPv6 subnets
+func (network NetworkConfiguration) IsDualStack() bool {
+	return len(network.GetFamilies()) > 1
+}
+
+// SupportsDualStack returns true when a Kubernetes version supports dual-stack clusters
+func SupportsDualStack(kubernetesVersion string) bool {
+	v, err := utilversion.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return false
+	}
+	return v.AtLeast(utilversion.MustParseGeneric(DualStackMinKubernetesVersion))
+}
+
+// GetPodSubnet returns the pods subnet in an address family (or an empty string)
+func (network NetworkConfiguration) GetPodSubnet(family string) string {
+	return getSubnetInFamily(network.PodSubnets, family)
+}
+
+// GetServiceSubnet returns the services subnet in an address family (or an empty string)
+func (network NetworkConfiguration) GetServiceSubnet(family string) string {
+	return getSubnetInFamily(network.ServiceSubnets, family)
+}
+
+func getSubnetInFamily(subnets []string, family string) string {
+	for _, subnet := range subnets {
+		if f, err := kubicutil.GetCIDRFamily(subnet); err == nil && f == family {
+			return subnet
+		}
+	}
+	return ""
+}
+
+// GetCniImage gets the image for the CNI driver, using the default
+// image for the driver when no image has been provided
+func (kubicCfg KubicInitConfiguration) GetCniImage() string {
+	if len(kubicCfg.Network.Cni.Image) > 0 {
+		return kubicCfg.Network.Cni.Image
+	}
+	return DefaultCniImages[kubicCfg.Network.Cni.Driver]
+}
+
+// GetCniMetaImage gets the image for the CNI meta-plugin, using the default
+// image for the meta-plugin when no image has been provided
+func (kubicCfg KubicInitConfiguration) GetCniMetaImage() string {
+	if len(kubicCfg.Network.Cni.MetaImage) > 0 {
+		return kubicCfg.Network.Cni.MetaImage
+	}
+	return DefaultCniImages[kubicCfg.Network.Cni.Meta]
+}
+
 // GetBindIP gets a valid IP address where we can bind
+// When an interface (or a pattern like "eth*") has been provided, its primary
+// address is used, preferring the address family in "network.bind.family".
 func (kubicCfg KubicInitConfiguration) GetBindIP() (net.IP, error) {
 	if len(kubicCfg.Network.Bind.Interface) > 0 {
-		// TODO: not implemented yet: get the IP address for that interface
-		return net.IP{}, nil
+		bindIP, err := kubicutil.GetInterfaceIP(kubicCfg.Network.Bind.Interface, kubicCfg.Network.Bind.Family)
+		if err != nil {
+			return nil, err
+		}
+		glog.V(3).Infof("[kubic] using %s as the bind address (from interface %s)", bindIP, kubicCfg.Network.Bind.Interface)
+		return bindIP, nil
 	} else {
 		defaultAddrStr := "0.0.0.0"
 		if len(kubicCfg.Network.Bind.Address) > 0 {
 			defaultAddrStr = kubicCfg.Network.Bind.Address
+		} else if kubicCfg.Network.Bind.Family == kubicutil.FamilyIPv6 {
+			// ChooseBindAddress() prefers IPv4 addresses in the host interfaces,
+			// so we must look for an IPv6 address ourselves
+			bindIP, err := kubicutil.GetInterfaceIP("*", kubicutil.FamilyIPv6)
+			if err == nil && kubicutil.GetIPFamily(bindIP) == kubicutil.FamilyIPv6 {
+				glog.V(3).Infof("[kubic] using %s as the bind address", bindIP)
+				return bindIP, nil
+			}
+			defaultAddrStr = "::"
 		}
 
 		defaultAddr := net.ParseIP(defaultAddrStr)
@@ -330,6 +634,39 @@ func (kubicCfg KubicInitConfiguration) GetBindIP() (net.IP, error) {
 	}
 }
 
+// GetCACertHashes returns all the hashes accepted for the seeder CA: the hashes
+// provided explicitly and the hashes of the certificates in the `caCrt`
+func (kubicCfg KubicInitConfiguration) GetCACertHashes() ([]string, error) {
+	hashes := append([]string{}, kubicCfg.Certificates.CaHashes...)
+	if len(kubicCfg.Certificates.CaCrt) > 0 {
+		caCrt, err := crypto.LoadCACert(kubicCfg.Certificates.CaCrt)
+		if err != nil {
+			return nil, err
+		}
+		crtHashes, err := crypto.CACertHashes(caCrt)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, crtHashes...)
+	}
+	return kubicutil.RemoveDuplicates(hashes), nil
+}
+
+// HasLoadBalancer returns true when the built-in load balancer for the API servers is enabled
+func (kubicCfg KubicInitConfiguration) HasLoadBalancer() bool {
+	return len(kubicCfg.Network.LoadBalancer.VirtualIP) > 0
+}
+
+// GetControlPlaneEndpoint gets the stable address (and port) for the control plane:
+// the virtual IP of the built-in load balancer, or the external FQDN (if any)
+func (kubicCfg KubicInitConfiguration) GetControlPlaneEndpoint() string {
+	if kubicCfg.HasLoadBalancer() {
+		lb := kubicCfg.Network.LoadBalancer
+		return net.JoinHostPort(lb.VirtualIP, fmt.Sprintf("%d", lb.Port))
+	}
+	return kubicCfg.Network.Dns.ExternalFqdn
+}
+
 // GetPublicAPIAddress gets a DNS name (or IP address)
 // that can be used for reaching the API server
 func (kubicCfg KubicInitConfiguration) GetPublicAPIAddress() (string, error) {
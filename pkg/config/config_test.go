@@ -0,0 +1,183 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+const testConfigUnknownKeys = `apiVersion: kubic.suse.com/v1alpha2
+kind: KubicInitConfiguration
+manager:
+  image: "kubic-init:latest"
+network:
+  cni:
+    driver: flannel
+    binDirectory: /opt/cni/bin
+clusterFormation:
+  autoApprove: false
+`
+
+func writeTestConfig(t *testing.T, contents string) string {
+	f, err := ioutil.TempFile("", "kubic-init-test-*.yaml")
+	if err != nil {
+		t.Fatalf("could not create temporary file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatalf("could not write temporary file: %v", err)
+	}
+	return f.Name()
+}
+
+func TestConfigFileUnknownKeys(t *testing.T) {
+	cfgFile := writeTestConfig(t, testConfigUnknownKeys)
+	defer os.Remove(cfgFile)
+
+	_, err := ConfigFileAndDefaultsToKubicInitConfig(cfgFile, false)
+	if err == nil {
+		t.Fatalf("unknown keys were not detected")
+	}
+	for _, expected := range []string{`line 3: unknown key "manager"`, `line 8: unknown key "binDirectory"`} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Logf("error: %s", err)
+			t.Fatalf("%q not found in the error", expected)
+		}
+	}
+
+	cfg, err := ConfigFileAndDefaultsToKubicInitConfig(cfgFile, true)
+	if err != nil {
+		t.Fatalf("unexpected error in lenient mode: %v", err)
+	}
+	if cfg.ClusterFormation.AutoApprove {
+		t.Fatalf("autoApprove was not loaded from the config file")
+	}
+	if cfg.Network.Cni.BinDir != DefaultCniBinDir {
+		t.Fatalf("unexpected CNI bin directory: %s", cfg.Network.Cni.BinDir)
+	}
+}
+
+const testConfigV1alpha2 = `kind: KubicInitConfiguration
+auth:
+  OIDC:
+    issuer: https://dex.some.name.com:32000
+features:
+  PSP: false
+`
+
+const testConfigV1alpha3 = `apiVersion: kubic.suse.com/v1alpha3
+kind: KubicInitConfiguration
+auth:
+  oidc:
+    issuer: https://dex.some.name.com:32000
+features:
+  psp: false
+`
+
+func TestConfigVersions(t *testing.T) {
+	for _, contents := range []string{testConfigV1alpha2, testConfigV1alpha3} {
+		cfg, err := BytesToKubicInitConfig([]byte(contents), false)
+		if err != nil {
+			t.Fatalf("could not load configuration: %v", err)
+		}
+		if cfg.Auth.OIDC.Issuer != "https://dex.some.name.com:32000" {
+			t.Fatalf("unexpected OIDC issuer: %q", cfg.Auth.OIDC.Issuer)
+		}
+		if cfg.Features.PSP {
+			t.Fatalf("PSP was not disabled")
+		}
+		if cfg.Network.GetPodSubnet(kubicutil.FamilyIPv4) != DefaultPodSubnet {
+			t.Fatalf("default pods subnet not set: %v", cfg.Network.PodSubnets)
+		}
+
+		marshalled, err := MarshalKubicInitConfig(cfg)
+		if err != nil {
+			t.Fatalf("could not marshal configuration: %v", err)
+		}
+		t.Logf("marshalled configuration:\n%s", marshalled)
+		if !strings.Contains(string(marshalled), "apiVersion: "+LatestVersion.String()) {
+			t.Fatalf("configuration not marshalled with the latest version")
+		}
+		if !strings.Contains(string(marshalled), "psp: false") {
+			t.Fatalf("PSP setting lost when marshalling")
+		}
+	}
+
+	// the latest version does not accept the old keys
+	if _, err := BytesToKubicInitConfig([]byte(strings.Replace(testConfigV1alpha3, "oidc", "OIDC", 1)), false); err == nil {
+		t.Fatalf("old keys accepted in %s", LatestVersion)
+	}
+}
+
+// as in the first cloud-init templates
+const testConfigV1alpha1 = `apiVersion: kubic.suse.com/v1alpha1
+kind: KubicInitConfiguration
+clusterFormation:
+  token: 94dcda.c271f4ff502789ca
+manager:
+  image: kubic-init:latest
+`
+
+func TestConfigV1alpha1(t *testing.T) {
+	cfg, err := BytesToKubicInitConfig([]byte(testConfigV1alpha1), false)
+	if err != nil {
+		t.Fatalf("could not load a v1alpha1 configuration: %v", err)
+	}
+	if cfg.ClusterFormation.Token != "94dcda.c271f4ff502789ca" {
+		t.Fatalf("unexpected token: %q", cfg.ClusterFormation.Token)
+	}
+	if cfg.Network.GetPodSubnet(kubicutil.FamilyIPv4) != DefaultPodSubnet {
+		t.Fatalf("default pods subnet not set: %v", cfg.Network.PodSubnets)
+	}
+}
+
+const testConfigV1alpha2Cilium = `kind: KubicInitConfiguration
+network:
+  cni:
+    driver: cilium
+`
+
+func TestConfigV1alpha2CniImage(t *testing.T) {
+	cfg, err := BytesToKubicInitConfig([]byte(testConfigV1alpha2Cilium), false)
+	if err != nil {
+		t.Fatalf("could not load configuration: %v", err)
+	}
+	if image := cfg.GetCniImage(); image != DefaultCniImages["cilium"] {
+		t.Fatalf("unexpected image for cilium: %q", image)
+	}
+}
+
+func TestSupportsDualStack(t *testing.T) {
+	for version, expected := range map[string]bool{
+		DefaultKubernetesVersion: false,
+		"v1.15.3":                false,
+		"1.16.0":                 true,
+		"v1.17.0-beta.1":         true,
+		"latest":                 false,
+	} {
+		if res := SupportsDualStack(version); res != expected {
+			t.Fatalf("unexpected dual-stack support for %q: %t", version, res)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubic-project/kubic-init/pkg/config/v1alpha2"
+	"github.com/kubic-project/kubic-init/pkg/config/v1alpha3"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+// note well: the structs that are identical in the internal and the versioned
+// types are converted with a Go conversion: they will stop compiling as soon
+// as they diverge, so the corresponding conversion must be written explicitly.
+
+func addConversionFuncs(scheme *runtime.Scheme) error {
+	return scheme.AddConversionFuncs(
+		Convert_v1alpha2_KubicInitConfiguration_To_config_KubicInitConfiguration,
+		Convert_config_KubicInitConfiguration_To_v1alpha2_KubicInitConfiguration,
+		Convert_v1alpha3_KubicInitConfiguration_To_config_KubicInitConfiguration,
+		Convert_config_KubicInitConfiguration_To_v1alpha3_KubicInitConfiguration,
+	)
+}
+
+// Convert_v1alpha2_KubicInitConfiguration_To_config_KubicInitConfiguration converts a v1alpha2 configuration to the internal type
+func Convert_v1alpha2_KubicInitConfiguration_To_config_KubicInitConfiguration(in *v1alpha2.KubicInitConfiguration, out *KubicInitConfiguration, s conversion.Scope) error {
+	out.Network = NetworkConfiguration{
+		Bind: BindConfiguration{
+			Address:   in.Network.Bind.Address,
+			Interface: in.Network.Bind.Interface,
+		},
+		Cni:            cniFromV1alpha2(in.Network.Cni),
+		Dns:            DNSConfiguration(in.Network.Dns),
+		Proxy:          ProxyConfiguration(in.Network.Proxy),
+		PodSubnets:     stringToList(in.Network.PodSubnet),
+		ServiceSubnets: stringToList(in.Network.ServiceSubnet),
+	}
+	out.Paths = PathsConfigration(in.Paths)
+	out.ClusterFormation = ClusterFormationConfiguration{
+		Seeder:      in.ClusterFormation.Seeder,
+		Token:       in.ClusterFormation.Token,
+		AutoApprove: boolValue(in.ClusterFormation.AutoApprove),
+		Role:        DefaultClusterFormationRole,
+		Discovery: DiscoveryConfiguration{
+			File: DefaultDiscoveryFile,
+		},
+	}
+	out.Certificates = CertsConfiguration{
+		Directory: in.Certificates.Directory,
+		CaHashes:  stringToList(in.Certificates.CaHash),
+	}
+	out.Etcd = EtcdConfiguration{
+		LocalEtcd: (*LocalEtcdConfiguration)(in.Etcd.LocalEtcd),
+	}
+	out.Runtime = RuntimeConfiguration(in.Runtime)
+	out.Features = FeaturesConfiguration{
+		PSP: boolValue(in.Features.PSP),
+	}
+	out.Services = ServicesConfiguration(in.Services)
+	out.Auth = AuthConfiguration{
+		OIDC: OIDCConfiguration(in.Auth.OIDC),
+	}
+	return nil
+}
+
+// Convert_config_KubicInitConfiguration_To_v1alpha2_KubicInitConfiguration converts the internal type to a v1alpha2 configuration
+func Convert_config_KubicInitConfiguration_To_v1alpha2_KubicInitConfiguration(in *KubicInitConfiguration, out *v1alpha2.KubicInitConfiguration, s conversion.Scope) error {
+	out.Network = v1alpha2.NetworkConfiguration{
+		Bind: v1alpha2.BindConfiguration{
+			Address:   in.Network.Bind.Address,
+			Interface: in.Network.Bind.Interface,
+		},
+		Cni:           cniToV1alpha2(in.Network.Cni),
+		Dns:           v1alpha2.DNSConfiguration(in.Network.Dns),
+		Proxy:         v1alpha2.ProxyConfiguration(in.Network.Proxy),
+		PodSubnet:     listToString(in.Network.PodSubnets),
+		ServiceSubnet: listToString(in.Network.ServiceSubnets),
+	}
+	out.Paths = v1alpha2.PathsConfigration(in.Paths)
+	out.ClusterFormation = v1alpha2.ClusterFormationConfiguration{
+		Seeder:      in.ClusterFormation.Seeder,
+		Token:       in.ClusterFormation.Token,
+		AutoApprove: boolPtr(in.ClusterFormation.AutoApprove),
+	}
+	out.Certificates = v1alpha2.CertsConfiguration{
+		Directory: in.Certificates.Directory,
+		CaHash:    listToString(in.Certificates.CaHashes),
+	}
+	out.Etcd = v1alpha2.EtcdConfiguration{
+		LocalEtcd: (*v1alpha2.LocalEtcdConfiguration)(in.Etcd.LocalEtcd),
+	}
+	out.Runtime = v1alpha2.RuntimeConfiguration(in.Runtime)
+	out.Features = v1alpha2.FeaturesConfiguration{
+		PSP: boolPtr(in.Features.PSP),
+	}
+	out.Services = v1alpha2.ServicesConfiguration(in.Services)
+	out.Auth = v1alpha2.AuthConfiguration{
+		OIDC: v1alpha2.OIDCConfiguration(in.Auth.OIDC),
+	}
+	return nil
+}
+
+// Convert_v1alpha3_KubicInitConfiguration_To_config_KubicInitConfiguration converts a v1alpha3 configuration to the internal type
+func Convert_v1alpha3_KubicInitConfiguration_To_config_KubicInitConfiguration(in *v1alpha3.KubicInitConfiguration, out *KubicInitConfiguration, s conversion.Scope) error {
+	podSubnets, err := mergeSubnets(in.Network.PodSubnet, in.Network.PodSubnets, "podSubnet")
+	if err != nil {
+		return err
+	}
+	serviceSubnets, err := mergeSubnets(in.Network.ServiceSubnet, in.Network.ServiceSubnets, "serviceSubnet")
+	if err != nil {
+		return err
+	}
+
+	out.Network = NetworkConfiguration{
+		Bind:           BindConfiguration(in.Network.Bind),
+		Cni:            cniFromV1alpha3(in.Network.Cni),
+		Dns:            DNSConfiguration(in.Network.Dns),
+		Proxy:          ProxyConfiguration(in.Network.Proxy),
+		LoadBalancer:   LoadBalancerConfiguration(in.Network.LoadBalancer),
+		PodSubnets:     podSubnets,
+		ServiceSubnets: serviceSubnets,
+	}
+	out.Paths = PathsConfigration(in.Paths)
+	out.ClusterFormation = ClusterFormationConfiguration{
+		Seeder:             in.ClusterFormation.Seeder,
+		Token:              in.ClusterFormation.Token,
+		AutoApprove:        boolValue(in.ClusterFormation.AutoApprove),
+		Role:               in.ClusterFormation.Role,
+		ControlPlaneSecret: in.ClusterFormation.ControlPlaneSecret,
+		Discovery: DiscoveryConfiguration{
+			Providers: append([]string{}, in.ClusterFormation.Discovery.Providers...),
+			Domain:    in.ClusterFormation.Discovery.Domain,
+			File:      in.ClusterFormation.Discovery.File,
+			Election:  boolValue(in.ClusterFormation.Discovery.Election),
+		},
+		Approval: ApprovalConfiguration{
+			Allowlist: append([]string{}, in.ClusterFormation.Approval.Allowlist...),
+			Policy:    ApprovalPolicyConfiguration(in.ClusterFormation.Approval.Policy),
+		},
+	}
+	out.Certificates = CertsConfiguration{
+		Directory:           in.Certificates.Directory,
+		CaHashes:            kubicutil.RemoveDuplicates(append(stringToList(in.Certificates.CaHash), in.Certificates.CaHashes...)),
+		CaCrt:               in.Certificates.CaCrt,
+		RequireVerification: boolValue(in.Certificates.RequireVerification),
+	}
+	out.Etcd = EtcdConfiguration{
+		LocalEtcd: (*LocalEtcdConfiguration)(in.Etcd.LocalEtcd),
+	}
+	out.Runtime = RuntimeConfiguration(in.Runtime)
+	out.Features = FeaturesConfiguration{
+		PSP: boolValue(in.Features.PSP),
+	}
+	out.Services = ServicesConfiguration(in.Services)
+	out.Auth = AuthConfiguration{
+		OIDC: OIDCConfiguration(in.Auth.OIDC),
+	}
+	return nil
+}
+
+// Convert_config_KubicInitConfiguration_To_v1alpha3_KubicInitConfiguration converts the internal type to a v1alpha3 configuration
+func Convert_config_KubicInitConfiguration_To_v1alpha3_KubicInitConfiguration(in *KubicInitConfiguration, out *v1alpha3.KubicInitConfiguration, s conversion.Scope) error {
+	out.Network = v1alpha3.NetworkConfiguration{
+		Bind:           v1alpha3.BindConfiguration(in.Network.Bind),
+		Cni:            cniToV1alpha3(in.Network.Cni),
+		Dns:            v1alpha3.DNSConfiguration(in.Network.Dns),
+		Proxy:          v1alpha3.ProxyConfiguration(in.Network.Proxy),
+		LoadBalancer:   v1alpha3.LoadBalancerConfiguration(in.Network.LoadBalancer),
+		PodSubnets:     append([]string{}, in.Network.PodSubnets...),
+		ServiceSubnets: append([]string{}, in.Network.ServiceSubnets...),
+	}
+	out.Paths = v1alpha3.PathsConfiguration(in.Paths)
+	out.ClusterFormation = v1alpha3.ClusterFormationConfiguration{
+		Seeder:             in.ClusterFormation.Seeder,
+		Token:              in.ClusterFormation.Token,
+		AutoApprove:        boolPtr(in.ClusterFormation.AutoApprove),
+		Role:               in.ClusterFormation.Role,
+		ControlPlaneSecret: in.ClusterFormation.ControlPlaneSecret,
+		Discovery: v1alpha3.DiscoveryConfiguration{
+			Providers: append([]string{}, in.ClusterFormation.Discovery.Providers...),
+			Domain:    in.ClusterFormation.Discovery.Domain,
+			File:      in.ClusterFormation.Discovery.File,
+			Election:  boolPtr(in.ClusterFormation.Discovery.Election),
+		},
+		Approval: v1alpha3.ApprovalConfiguration{
+			Allowlist: append([]string{}, in.ClusterFormation.Approval.Allowlist...),
+			Policy:    v1alpha3.ApprovalPolicyConfiguration(in.ClusterFormation.Approval.Policy),
+		},
+	}
+	out.Certificates = v1alpha3.CertsConfiguration{
+		Directory:           in.Certificates.Directory,
+		CaHashes:            append([]string{}, in.Certificates.CaHashes...),
+		CaCrt:               in.Certificates.CaCrt,
+		RequireVerification: boolPtr(in.Certificates.RequireVerification),
+	}
+	out.Etcd = v1alpha3.EtcdConfiguration{
+		LocalEtcd: (*v1alpha3.LocalEtcdConfiguration)(in.Etcd.LocalEtcd),
+	}
+	out.Runtime = v1alpha3.RuntimeConfiguration(in.Runtime)
+	out.Features = v1alpha3.FeaturesConfiguration{
+		PSP: boolPtr(in.Features.PSP),
+	}
+	out.Services = v1alpha3.ServicesConfiguration(in.Services)
+	out.Auth = v1alpha3.AuthConfiguration{
+		OIDC: v1alpha3.OIDCConfiguration(in.Auth.OIDC),
+	}
+	return nil
+}
+
+// the CNI configuration has driver-specific sections that are not present in v1alpha2,
+// so the defaults are used for them
+func cniFromV1alpha2(in v1alpha2.CniConfiguration) CniConfiguration {
+	return CniConfiguration{
+		BinDir:  in.BinDir,
+		ConfDir: in.ConfDir,
+		Driver:  in.Driver,
+		Image:   in.Image,
+		Calico: CalicoConfiguration{
+			Encapsulation: DefaultCalicoEncapsulation,
+			CniImage:      DefaultCalicoCniImage,
+		},
+		Flannel: FlannelConfiguration{
+			Backend: FlannelBackendConfiguration{
+				Type: DefaultFlannelBackend,
+			},
+			LogLevel: DefaultFlannelLogLevel,
+		},
+	}
+}
+
+func cniToV1alpha2(in CniConfiguration) v1alpha2.CniConfiguration {
+	return v1alpha2.CniConfiguration{
+		BinDir:  in.BinDir,
+		ConfDir: in.ConfDir,
+		Driver:  in.Driver,
+		Image:   in.Image,
+	}
+}
+
+func cniFromV1alpha3(in v1alpha3.CniConfiguration) CniConfiguration {
+	var networks []CniNetworkConfiguration
+	for _, network := range in.Networks {
+		networks = append(networks, CniNetworkConfiguration(network))
+	}
+
+	return CniConfiguration{
+		BinDir:  in.BinDir,
+		ConfDir: in.ConfDir,
+		Driver:  in.Driver,
+		Image:   in.Image,
+		Calico:  CalicoConfiguration(in.Calico),
+		Flannel: FlannelConfiguration{
+			Backend:   FlannelBackendConfiguration(in.Flannel.Backend),
+			MTU:       in.Flannel.MTU,
+			Interface: in.Flannel.Interface,
+			LogLevel:  intValue(in.Flannel.LogLevel),
+		},
+		Meta:      in.Meta,
+		MetaImage: in.MetaImage,
+		Networks:  networks,
+	}
+}
+
+func cniToV1alpha3(in CniConfiguration) v1alpha3.CniConfiguration {
+	var networks []v1alpha3.CniNetworkConfiguration
+	for _, network := range in.Networks {
+		networks = append(networks, v1alpha3.CniNetworkConfiguration(network))
+	}
+
+	return v1alpha3.CniConfiguration{
+		BinDir:  in.BinDir,
+		ConfDir: in.ConfDir,
+		Driver:  in.Driver,
+		Image:   in.Image,
+		Calico:  v1alpha3.CalicoConfiguration(in.Calico),
+		Flannel: v1alpha3.FlannelConfiguration{
+			Backend:   v1alpha3.FlannelBackendConfiguration(in.Flannel.Backend),
+			MTU:       in.Flannel.MTU,
+			Interface: in.Flannel.Interface,
+			LogLevel:  intPtr(in.Flannel.LogLevel),
+		},
+		Meta:      in.Meta,
+		MetaImage: in.MetaImage,
+		Networks:  networks,
+	}
+}
+
+// mergeSubnets merges the (deprecated) single subnet and the list of subnets
+func mergeSubnets(single string, list []string, name string) ([]string, error) {
+	if len(single) == 0 {
+		return append([]string{}, list...), nil
+	}
+	if len(list) > 0 {
+		return nil, fmt.Errorf("%s and %ss cannot be used at the same time", name, name)
+	}
+	return []string{single}, nil
+}
+
+// stringToList converts a single value to a list (an empty list for an empty value)
+func stringToList(s string) []string {
+	if len(s) == 0 {
+		return []string{}
+	}
+	return []string{s}
+}
+
+// listToString gets the first element in a list (v1alpha2 has no dual-stack support)
+func listToString(l []string) string {
+	if len(l) == 0 {
+		return ""
+	}
+	return l[0]
+}
+
+func boolValue(b *bool) bool {
+	if b == nil {
+		return false
+	}
+	return *b
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -18,17 +18,38 @@
 package config
 
 import (
+	"time"
+
 	kubeadmapiv1alpha3 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1alpha3"
+
+	"github.com/kubic-project/kubic-init/pkg/config/v1alpha3"
 )
 
 const (
-	// Kubernetes version to deploy
-	DefaultKubernetesVersion = "1.12.2"
+	// Kubernetes version to deploy when the version of the kubeadm installed cannot be obtained
+	DefaultKubernetesVersion = "v1.13.0"
+
+	// The first Kubernetes version supporting dual-stack clusters
+	// (the IPv6DualStack feature gate and the per-family node CIDR mask sizes)
+	DualStackMinKubernetesVersion = "1.16.0"
 
 	// Default API server port
 	DefaultAPIServerPort = 6443
 )
 
+// Providers for discovering the seeder
+const (
+	DiscoveryProviderMDNS = "mdns"
+	DiscoveryProviderDNS  = "dns"
+	DiscoveryProviderFile = "file"
+)
+
+// Roles for the nodes joining the seeder
+const (
+	RoleMaster = "master"
+	RoleWorker = "worker"
+)
+
 const (
 	// The environment variable used for passing the seeder
 	DefaultEnvVarSeeder = "SEEDER"
@@ -36,15 +57,21 @@ const (
 	// The environment variable used for passing the token
 	DefaultEnvVarToken = "TOKEN"
 
+	// The environment variable used for passing the control-plane secret
+	DefaultEnvVarControlPlaneSecret = "CONTROL_PLANE_SECRET"
+
 	// The environment variable used for passing the kubic-manager image
 	DefaultEnvVarManager = "MANAGER_IMAGE"
 )
 
 const (
 	// Default runtime engine
-	DefaultRuntimeEngine = "crio"
+	DefaultRuntimeEngine = v1alpha3.DefaultRuntimeEngine
 )
 
+// DefaultCniImages are the default images for the CNI drivers
+var DefaultCniImages = v1alpha3.DefaultCniImages
+
 var DefaultCriSocket = map[string]string{
 	"docker":     "/var/run/dockershim.sock",
 	"crio":       "/var/run/crio/crio.sock",
@@ -53,24 +80,48 @@ var DefaultCriSocket = map[string]string{
 
 // CNI and network defaults
 const (
-	DefaultCniDriver = "flannel"
+	DefaultCniDriver = v1alpha3.DefaultCniDriver
 
-	DefaultCniImage = "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/flannel:0.9.1"
+	DefaultCniImage = v1alpha3.DefaultCniImage
 
 	// Default directory for CNI binaries
-	DefaultCniBinDir = "/var/lib/kubelet/cni/bin"
+	DefaultCniBinDir = v1alpha3.DefaultCniBinDir
 
 	// Default directory for CNI configuration
-	DefaultCniConfDir = "/etc/cni/net.d"
+	DefaultCniConfDir = v1alpha3.DefaultCniConfDir
+
+	// Default namespace for the additional networks
+	DefaultCniNetworkNamespace = v1alpha3.DefaultCniNetworkNamespace
+
+	// Default backend for Flannel
+	DefaultFlannelBackend = v1alpha3.DefaultFlannelBackend
+
+	// Default log level for Flannel
+	DefaultFlannelLogLevel = v1alpha3.DefaultFlannelLogLevel
+
+	// Default encapsulation for Calico
+	DefaultCalicoEncapsulation = v1alpha3.DefaultCalicoEncapsulation
+
+	// Default image with the Calico CNI plugin
+	DefaultCalicoCniImage = v1alpha3.DefaultCalicoCniImage
 
 	// Default subnet for pods
-	DefaultPodSubnet = "172.16.0.0/13"
+	DefaultPodSubnet = v1alpha3.DefaultPodSubnet
 
 	// Default subnet for services
-	DefaultServiceSubnet = "172.24.0.0/16"
+	DefaultServiceSubnet = v1alpha3.DefaultServiceSubnet
 
 	// Default internal DNS name
-	DefaultDNSDomain = "cluster.local"
+	DefaultDNSDomain = v1alpha3.DefaultDNSDomain
+
+	// Default address family preferred when getting the address of an interface
+	DefaultBindFamily = v1alpha3.DefaultBindFamily
+
+	// Default size of the IPv4 subnet assigned to each node (from the pods subnet)
+	DefaultNodeCIDRMaskSizeIPv4 = 24
+
+	// Default size of the IPv6 subnet assigned to each node (from the pods subnet)
+	DefaultNodeCIDRMaskSizeIPv6 = 64
 )
 
 // etcd defaults
@@ -87,13 +138,37 @@ const (
 	DefaultKubicInitImage = "kubic-init:latest"
 
 	// Default kubeadm path
-	DefaultKubeadmPath = "/usr/bin/kubeadm"
+	DefaultKubeadmPath = v1alpha3.DefaultKubeadmPath
 )
 
 // Some important default paths
 const (
 	// Default directory for certificates
-	DefaultCertsDirectory = kubeadmapiv1alpha3.DefaultCertificatesDir
+	DefaultCertsDirectory = v1alpha3.DefaultCertsDirectory
+
+	// Default role for nodes joining the seeder
+	DefaultClusterFormationRole = v1alpha3.DefaultClusterFormationRole
+
+	// Default file with the addresses of the seeder (for the "file" discovery provider)
+	DefaultDiscoveryFile = v1alpha3.DefaultDiscoveryFile
+
+	// The file where the result of the seeder discovery is saved
+	DefaultKubicDiscoveryStateFile = "/etc/kubic/state/seeder"
+
+	// Default port for the API servers load balancer (in the virtual IP)
+	DefaultLoadBalancerPort = v1alpha3.DefaultLoadBalancerPort
+
+	// Default VRRP virtual router ID for the API servers load balancer
+	DefaultLoadBalancerRouterID = v1alpha3.DefaultLoadBalancerRouterID
+
+	// Default image for the VRRP daemon in the API servers load balancer
+	DefaultLoadBalancerImage = v1alpha3.DefaultLoadBalancerImage
+
+	// Default image for the TCP proxy in the API servers load balancer
+	DefaultLoadBalancerProxyImage = v1alpha3.DefaultLoadBalancerProxyImage
+
+	// Directory where the load balancer configuration files are written
+	DefaultLoadBalancerConfDir = "/etc/kubic/lb"
 
 	// Default CA certificate path
 	DefaultCertCA = kubeadmapiv1alpha3.DefaultCACertPath
@@ -107,6 +182,9 @@ const (
 	// The ConfigMap where the config file (from the Seeder) is stored
 	DefaultKubicInitConfigmap = "kubic-init-config-seeder"
 
+	// The Secret where the sensitive values in the config file (from the Seeder) are stored
+	DefaultKubicInitConfigSecret = DefaultKubicInitConfigmap + secretNameSuffix
+
 	// The default manifests dirctory
 	DefaultKubicManifestsDir = "/etc/kubic/manifests"
 
@@ -116,6 +194,12 @@ const (
 	// The default RBAC dirctory
 	DefaultKubicRBACDir = "/etc/kubic/rbac"
 
+	// The file where the progress of the bootstrap is saved
+	DefaultKubicBootstrapStateFile = "/etc/kubic/state/bootstrap.yaml"
+
+	// The file where the custom bootstrap state files (--state-file) are recorded, one per line
+	DefaultKubicBootstrapStateFilesRecord = "/etc/kubic/state/bootstrap-state-files"
+
 	// The default kubeconfig
 	DefaultKubicKubeconfig = "/etc/kubernetes/admin.conf"
 
@@ -123,6 +207,24 @@ const (
 	DefaultDexIssuerPort = 32000
 )
 
+// Manager defaults
+const (
+	// Default address for the health/readiness endpoints of the manager
+	DefaultManagerHealthAddress = ":8475"
+
+	// Default port where the joining nodes register their addresses (for the approval policy)
+	DefaultNodeRegistrationPort = 8476
+
+	// Default interval between reconciliations of the kubic-owned resources
+	DefaultManagerReconcileInterval = 5 * time.Minute
+)
+
+// Discovery defaults
+const (
+	// Default maximum time waiting for a seeder to be found (or elected)
+	DefaultDiscoveryTimeout = 10 * time.Minute
+)
+
 // OIDC defaults
 const (
 	DefaultOIDCClientID = "kubernetes"
@@ -17,9 +17,10 @@
 
 // Package config contains the kubic-init configuration structures and associated functions
 //
+// The types in this package are the internal version of the configuration. The versions
+// users can write in the configuration file live in the "v1alpha2" and "v1alpha3" packages.
+//
 // +k8s:openapi-gen=true
 // +k8s:deepcopy-gen=package,register
-// +k8s:conversion-gen=github.com/kubic-project/kubic-init/pkg/config
-// +k8s:defaulter-gen=TypeMeta
-// +groupName=kubic.opensuse.org
+// +groupName=kubic.suse.com
 package config
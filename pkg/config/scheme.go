@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/kubic-project/kubic-init/pkg/config/v1alpha2"
+	"github.com/kubic-project/kubic-init/pkg/config/v1alpha3"
+)
+
+// LatestVersion is the latest version of the kubic-init configuration
+var LatestVersion = v1alpha3.SchemeGroupVersion
+
+// Scheme is the runtime.Scheme to which all the kubic-init configuration versions are registered
+var Scheme = runtime.NewScheme()
+
+// Codecs provides access to encoding and decoding for the scheme
+var Codecs = serializer.NewCodecFactory(Scheme)
+
+func init() {
+	utilruntime.Must(AddToSchemeAllVersions(Scheme))
+}
+
+// AddToSchemeAllVersions adds the internal type, all the versions
+// and the conversion functions to the scheme
+func AddToSchemeAllVersions(scheme *runtime.Scheme) error {
+	if err := AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := v1alpha2.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := v1alpha3.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := addConversionFuncs(scheme); err != nil {
+		return err
+	}
+	return scheme.SetVersionPriority(v1alpha3.SchemeGroupVersion, v1alpha2.SchemeGroupVersion)
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/kubernetes/kubernetes/cmd/kubeadm/app/util/apiclient"
+	"github.com/yuroyoro/swalker"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// Fields in the internal configuration can be marked as sensitive with
+//
+//	Token string `kubic:"sensitive"`
+//
+// Sensitive values are never uploaded to the (world-readable) ConfigMap:
+// they are stored in a companion Secret, using the path of the field
+// (ie, "ClusterFormation.Token") as the key.
+const (
+	sensitiveTagName  = "kubic"
+	sensitiveTagValue = "sensitive"
+)
+
+// the suffix added to the ConfigMap name for getting the name of the Secret
+const secretNameSuffix = "-secrets"
+
+// Scrub returns a copy of the configuration where all the sensitive values have been
+// removed, as well as a map (indexed by the field path) with those values
+func (kubicCfg *KubicInitConfiguration) Scrub() (*KubicInitConfiguration, map[string]string) {
+	scrubbed := kubicCfg.DeepCopy()
+	sensitive := map[string]string{}
+	scrubValue(reflect.ValueOf(scrubbed).Elem(), "", sensitive)
+	return scrubbed, sensitive
+}
+
+// scrubValue walks a struct, removing the sensitive strings
+func scrubValue(v reflect.Value, path string, sensitive map[string]string) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			scrubValue(v.Elem(), path, sensitive)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.Anonymous || len(f.PkgPath) > 0 {
+				continue // ignore the TypeMeta and unexported fields
+			}
+
+			fieldPath := f.Name
+			if len(path) > 0 {
+				fieldPath = path + "." + f.Name
+			}
+
+			if f.Tag.Get(sensitiveTagName) == sensitiveTagValue {
+				if f.Type.Kind() != reflect.String {
+					panic(fmt.Sprintf("only strings can be marked as sensitive: %s is a %s", fieldPath, f.Type))
+				}
+				if value := v.Field(i).String(); len(value) > 0 {
+					sensitive[fieldPath] = value
+					v.Field(i).SetString("")
+				}
+				continue
+			}
+
+			scrubValue(v.Field(i), fieldPath, sensitive)
+		}
+	}
+}
+
+// Restore sets the sensitive values (as returned by Scrub) in the configuration
+func (kubicCfg *KubicInitConfiguration) Restore(sensitive map[string]string) error {
+	paths := []string{}
+	for path := range sensitive {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		glog.V(8).Infof("[kubic] restoring sensitive value for '%s'", path)
+		if err := swalker.Write(path, kubicCfg, sensitive[path]); err != nil {
+			return fmt.Errorf("could not restore %q: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// toSecret uploads the sensitive values to a Secret
+func toSecret(client clientset.Interface, name string, sensitive map[string]string, extraLabels map[string]string) error {
+	data := map[string][]byte{}
+	for path, value := range sensitive {
+		data[path] = []byte(value)
+	}
+
+	glog.V(3).Infof("[kubic] uploading %d sensitive value(s) to Secret %s/%s",
+		len(data), metav1.NamespaceSystem, name)
+
+	return apiclient.CreateOrUpdateSecret(client, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels:    extraLabels,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	})
+}
+
+// FromConfigMap reads a configuration previously uploaded with ToConfigMap,
+// restoring the sensitive values from the companion Secret.
+// When the Secret cannot be read (ie, because of some RBAC rules), the configuration
+// is returned without the sensitive values.
+func FromConfigMap(client clientset.Interface, name string) (*KubicInitConfiguration, error) {
+	filename := filepath.Base(DefaultKubicInitConfig)
+
+	glog.V(3).Infof("[kubic] reading configuration from ConfigMap %s/%s", metav1.NamespaceSystem, name)
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	contents, found := cm.Data[filename]
+	if !found {
+		return nil, fmt.Errorf("no %q found in ConfigMap %s/%s", filename, metav1.NamespaceSystem, name)
+	}
+
+	// the configuration could have been uploaded by a newer kubic-init: ignore unknown keys
+	kubicCfg, err := BytesToKubicInitConfig([]byte(contents), true)
+	if err != nil {
+		return nil, err
+	}
+
+	secretName := name + secretNameSuffix
+	secret, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			glog.V(1).Infof("[kubic] WARNING: could not read the sensitive values from %s/%s: %v",
+				metav1.NamespaceSystem, secretName, err)
+			return kubicCfg, nil
+		}
+		return nil, err
+	}
+
+	sensitive := map[string]string{}
+	for path, value := range secret.Data {
+		sensitive[path] = string(value)
+	}
+	if err := kubicCfg.Restore(sensitive); err != nil {
+		return nil, err
+	}
+
+	return kubicCfg, nil
+}
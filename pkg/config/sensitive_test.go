@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapSensitiveValues(t *testing.T) {
+	const token = "94dcda.c271f4ff502789ca"
+
+	kubicCfg, err := BytesToKubicInitConfig([]byte{}, false)
+	if err != nil {
+		t.Fatalf("could not get a default configuration: %v", err)
+	}
+	kubicCfg.ClusterFormation.Token = token
+
+	client := clientsetfake.NewSimpleClientset()
+	if err := kubicCfg.ToConfigMap(client, DefaultKubicInitConfigmap, nil); err != nil {
+		t.Fatalf("could not upload the configuration: %v", err)
+	}
+
+	if kubicCfg.ClusterFormation.Token != token {
+		t.Fatalf("the original configuration was modified")
+	}
+
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(DefaultKubicInitConfigmap, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get the ConfigMap: %v", err)
+	}
+	if strings.Contains(cm.Data[filepath.Base(DefaultKubicInitConfig)], token) {
+		t.Fatalf("token found in the ConfigMap")
+	}
+
+	secret, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Get(DefaultKubicInitConfigSecret, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get the Secret: %v", err)
+	}
+	if string(secret.Data["ClusterFormation.Token"]) != token {
+		t.Logf("secret: %+v", secret.Data)
+		t.Fatalf("token not found in the Secret")
+	}
+
+	restored, err := FromConfigMap(client, DefaultKubicInitConfigmap)
+	if err != nil {
+		t.Fatalf("could not read the configuration: %v", err)
+	}
+	if restored.ClusterFormation.Token != token {
+		t.Fatalf("token was not restored: %q", restored.ClusterFormation.Token)
+	}
+}
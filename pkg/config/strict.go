@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var yamlUnknownFieldRegexp = regexp.MustCompile(`^line (\d+): field (.+) not found in type .*$`)
+
+// checkUnknownKeys decodes the YAML in `b` in a (versioned) object, returning an error
+// with all the unknown (or duplicate) keys found and their line numbers.
+// Note well: the object is only used for getting its type: it is not modified.
+func checkUnknownKeys(b []byte, obj runtime.Object) error {
+	// metav1.TypeMeta only has "json" tags, so we must add the "apiVersion"
+	// and "kind" keys for the yaml decoder
+	wrapper := reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "APIVersion", Type: reflect.TypeOf(""), Tag: `yaml:"apiVersion,omitempty"`},
+		{Name: "Kind", Type: reflect.TypeOf(""), Tag: `yaml:"kind,omitempty"`},
+		{Name: "Config", Type: reflect.TypeOf(obj).Elem(), Tag: `yaml:",inline"`},
+	}))
+
+	err := yaml.UnmarshalStrict(b, wrapper.Interface())
+	if err == nil {
+		return nil
+	}
+
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return err
+	}
+
+	errs := []string{}
+	for _, e := range typeErr.Errors {
+		if m := yamlUnknownFieldRegexp.FindStringSubmatch(e); m != nil {
+			e = fmt.Sprintf("line %s: unknown key %q", m[1], m[2])
+		}
+		errs = append(errs, e)
+	}
+
+	return fmt.Errorf("unknown or invalid keys found:\n  %s", strings.Join(errs, "\n  "))
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package v1alpha2
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeadmapiv1alpha3 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1alpha3"
+)
+
+const (
+	// Default runtime engine
+	DefaultRuntimeEngine = "crio"
+
+	// Default kubeadm path
+	DefaultKubeadmPath = "/usr/bin/kubeadm"
+
+	// Default directory for certificates
+	DefaultCertsDirectory = kubeadmapiv1alpha3.DefaultCertificatesDir
+)
+
+// CNI and network defaults
+const (
+	DefaultCniDriver = "flannel"
+
+	DefaultCniImage = "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/flannel:0.9.1"
+
+	// Default directory for CNI binaries
+	DefaultCniBinDir = "/var/lib/kubelet/cni/bin"
+
+	// Default directory for CNI configuration
+	DefaultCniConfDir = "/etc/cni/net.d"
+
+	// Default subnet for pods
+	DefaultPodSubnet = "172.16.0.0/13"
+
+	// Default subnet for services
+	DefaultServiceSubnet = "172.24.0.0/16"
+
+	// Default internal DNS name
+	DefaultDNSDomain = "cluster.local"
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_KubicInitConfiguration assigns default values to the configuration
+func SetDefaults_KubicInitConfiguration(obj *KubicInitConfiguration) {
+	if obj.Certificates.Directory == "" {
+		obj.Certificates.Directory = DefaultCertsDirectory
+	}
+	if obj.Paths.Kubeadm == "" {
+		obj.Paths.Kubeadm = DefaultKubeadmPath
+	}
+	if obj.Etcd.LocalEtcd == nil {
+		obj.Etcd.LocalEtcd = &LocalEtcdConfiguration{}
+	}
+	if obj.ClusterFormation.AutoApprove == nil {
+		obj.ClusterFormation.AutoApprove = boolPtr(true)
+	}
+	if obj.Runtime.Engine == "" {
+		obj.Runtime.Engine = DefaultRuntimeEngine
+	}
+	if obj.Features.PSP == nil {
+		obj.Features.PSP = boolPtr(true)
+	}
+
+	setDefaultsNetwork(&obj.Network)
+}
+
+// setDefaultsNetwork assigns default values to the network configuration
+func setDefaultsNetwork(obj *NetworkConfiguration) {
+	if obj.PodSubnet == "" {
+		obj.PodSubnet = DefaultPodSubnet
+	}
+	if obj.ServiceSubnet == "" {
+		obj.ServiceSubnet = DefaultServiceSubnet
+	}
+	if obj.Dns.Domain == "" {
+		obj.Dns.Domain = DefaultDNSDomain
+	}
+	if obj.Cni.Driver == "" {
+		obj.Cni.Driver = DefaultCniDriver
+	}
+	if obj.Cni.BinDir == "" {
+		obj.Cni.BinDir = DefaultCniBinDir
+	}
+	if obj.Cni.ConfDir == "" {
+		obj.Cni.ConfDir = DefaultCniConfDir
+	}
+	// the default image is only valid for the default driver: other
+	// drivers get their image from the drivers registry
+	if obj.Cni.Image == "" && obj.Cni.Driver == DefaultCniDriver {
+		obj.Cni.Image = DefaultCniImage
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
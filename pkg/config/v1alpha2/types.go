@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The CNI configuration
+// Subnets details are specified in the kubeadm configuration file
+type CniConfiguration struct {
+	BinDir  string `json:"binDir,omitempty" yaml:"binDir,omitempty"`
+	ConfDir string `json:"confDir,omitempty" yaml:"confDir,omitempty"`
+	Driver  string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Image   string `json:"image,omitempty" yaml:"image,omitempty"`
+}
+
+type ClusterFormationConfiguration struct {
+	Seeder      string `json:"seeder,omitempty" yaml:"seeder,omitempty"`
+	Token       string `json:"token,omitempty" yaml:"token,omitempty"`
+	AutoApprove *bool  `json:"autoApprove,omitempty" yaml:"autoApprove,omitempty"`
+}
+
+type OIDCConfiguration struct {
+	Issuer   string `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	ClientID string `json:"clientID,omitempty" yaml:"clientID,omitempty"`
+	CA       string `json:"ca,omitempty" yaml:"ca,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Groups   string `json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+type AuthConfiguration struct {
+	OIDC OIDCConfiguration `json:"OIDC,omitempty" yaml:"OIDC,omitempty"`
+}
+
+type CertsConfiguration struct {
+	Directory string `json:"directory,omitempty" yaml:"directory,omitempty"`
+	CaHash    string `json:"caCrtHash,omitempty" yaml:"caCrtHash,omitempty"`
+}
+
+type DNSConfiguration struct {
+	Domain       string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	ExternalFqdn string `json:"externalFqdn,omitempty" yaml:"externalFqdn,omitempty"`
+}
+
+type ProxyConfiguration struct {
+	Http       string `json:"http,omitempty" yaml:"http,omitempty"`
+	Https      string `json:"https,omitempty" yaml:"https,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty" yaml:"noProxy,omitempty"`
+	SystemWide bool   `json:"systemWide,omitempty" yaml:"systemWide,omitempty"`
+}
+
+type BindConfiguration struct {
+	Address   string `json:"address,omitempty" yaml:"address,omitempty"`
+	Interface string `json:"interface,omitempty" yaml:"interface,omitempty"`
+}
+
+type PathsConfigration struct {
+	Kubeadm string `json:"kubeadm,omitempty" yaml:"kubeadm,omitempty"`
+}
+
+type LocalEtcdConfiguration struct {
+	ServerCertSANs []string `json:"serverCertSANs,omitempty" yaml:"serverCertSANs,omitempty"`
+	PeerCertSANs   []string `json:"peerCertSANs,omitempty" yaml:"peerCertSANs,omitempty"`
+}
+
+type EtcdConfiguration struct {
+	LocalEtcd *LocalEtcdConfiguration `json:"local,omitempty" yaml:"local,omitempty"`
+}
+
+type NetworkConfiguration struct {
+	Bind          BindConfiguration  `json:"bind,omitempty" yaml:"bind,omitempty"`
+	Cni           CniConfiguration   `json:"cni,omitempty" yaml:"cni,omitempty"`
+	Dns           DNSConfiguration   `json:"dns,omitempty" yaml:"dns,omitempty"`
+	Proxy         ProxyConfiguration `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	PodSubnet     string             `json:"podSubnet,omitempty" yaml:"podSubnet,omitempty"`
+	ServiceSubnet string             `json:"serviceSubnet,omitempty" yaml:"serviceSubnet,omitempty"`
+}
+
+type RuntimeConfiguration struct {
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty"`
+}
+
+type FeaturesConfiguration struct {
+	PSP *bool `json:"PSP,omitempty" yaml:"PSP,omitempty"`
+}
+
+type ServicesConfiguration struct {
+}
+
+// The kubic-init configuration
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type KubicInitConfiguration struct {
+	metav1.TypeMeta  `json:",inline" yaml:"-"`
+	Network          NetworkConfiguration          `json:"network,omitempty" yaml:"network,omitempty"`
+	Paths            PathsConfigration             `json:"paths,omitempty" yaml:"paths,omitempty"`
+	ClusterFormation ClusterFormationConfiguration `json:"clusterFormation,omitempty" yaml:"clusterFormation,omitempty"`
+	Certificates     CertsConfiguration            `json:"certificates,omitempty" yaml:"certificates,omitempty"`
+	Etcd             EtcdConfiguration             `json:"etcd,omitempty" yaml:"etcd,omitempty"`
+	Runtime          RuntimeConfiguration          `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	Features         FeaturesConfiguration         `json:"features,omitempty" yaml:"features,omitempty"`
+	Services         ServicesConfiguration         `json:"services,omitempty" yaml:"services,omitempty"`
+	Auth             AuthConfiguration             `json:"auth,omitempty" yaml:"auth,omitempty"`
+}
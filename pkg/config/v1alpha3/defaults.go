@@ -0,0 +1,216 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package v1alpha3
+
+import (
+	"net"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeadmapiv1alpha3 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1alpha3"
+)
+
+const (
+	// Default runtime engine
+	DefaultRuntimeEngine = "crio"
+
+	// Default kubeadm path
+	DefaultKubeadmPath = "/usr/bin/kubeadm"
+
+	// Default directory for certificates
+	DefaultCertsDirectory = kubeadmapiv1alpha3.DefaultCertificatesDir
+
+	// Default role for nodes joining the seeder
+	DefaultClusterFormationRole = "worker"
+
+	// Default file with the addresses of the seeder (for the "file" discovery provider)
+	DefaultDiscoveryFile = "/etc/kubic/seeders"
+)
+
+// CNI and network defaults
+const (
+	DefaultCniDriver = "flannel"
+
+	DefaultCniImage = "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/flannel:0.9.1"
+
+	// Default directory for CNI binaries
+	DefaultCniBinDir = "/var/lib/kubelet/cni/bin"
+
+	// Default directory for CNI configuration
+	DefaultCniConfDir = "/etc/cni/net.d"
+
+	// Default subnet for pods
+	DefaultPodSubnet = "172.16.0.0/13"
+
+	// Default subnet for services
+	DefaultServiceSubnet = "172.24.0.0/16"
+
+	// Default internal DNS name
+	DefaultDNSDomain = "cluster.local"
+
+	// Default address family preferred when getting the address of an interface
+	DefaultBindFamily = "ipv4"
+
+	// Default encapsulation for Calico
+	DefaultCalicoEncapsulation = "ipip"
+
+	// Default namespace for the additional networks
+	DefaultCniNetworkNamespace = "default"
+
+	// Default backend for Flannel
+	DefaultFlannelBackend = "vxlan"
+
+	// Default log level for Flannel
+	DefaultFlannelLogLevel = 1
+
+	// Default image with the Calico CNI plugin
+	DefaultCalicoCniImage = "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/calico-cni:3.8.2"
+)
+
+// API server load balancer defaults
+const (
+	// Default port for the load balancer (in the virtual IP)
+	DefaultLoadBalancerPort = 8443
+
+	// Default VRRP virtual router ID
+	DefaultLoadBalancerRouterID = 51
+
+	// Default image for the VRRP daemon
+	DefaultLoadBalancerImage = "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/keepalived:2.0.10"
+
+	// Default image for the TCP proxy
+	DefaultLoadBalancerProxyImage = "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/haproxy:1.8.14"
+)
+
+// DefaultCniImages are the default images for the CNI drivers
+// (the image is not set by default, as it depends on the driver)
+var DefaultCniImages = map[string]string{
+	"flannel": DefaultCniImage,
+	"cilium":  "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/cilium:1.7.4",
+	"calico":  "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/calico-node:3.8.2",
+	"multus":  "registry.opensuse.org/devel/caasp/kubic-container/container/kubic/multus:3.4",
+}
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+// SetDefaults_KubicInitConfiguration assigns default values to the configuration
+func SetDefaults_KubicInitConfiguration(obj *KubicInitConfiguration) {
+	if obj.Certificates.Directory == "" {
+		obj.Certificates.Directory = DefaultCertsDirectory
+	}
+	if obj.Certificates.RequireVerification == nil {
+		obj.Certificates.RequireVerification = boolPtr(false)
+	}
+	if obj.Paths.Kubeadm == "" {
+		obj.Paths.Kubeadm = DefaultKubeadmPath
+	}
+	if obj.Etcd.LocalEtcd == nil {
+		obj.Etcd.LocalEtcd = &LocalEtcdConfiguration{}
+	}
+	if obj.ClusterFormation.AutoApprove == nil {
+		obj.ClusterFormation.AutoApprove = boolPtr(true)
+	}
+	if obj.ClusterFormation.Role == "" {
+		obj.ClusterFormation.Role = DefaultClusterFormationRole
+	}
+	if obj.ClusterFormation.Discovery.File == "" {
+		obj.ClusterFormation.Discovery.File = DefaultDiscoveryFile
+	}
+	if obj.ClusterFormation.Discovery.Election == nil {
+		obj.ClusterFormation.Discovery.Election = boolPtr(false)
+	}
+	if obj.Runtime.Engine == "" {
+		obj.Runtime.Engine = DefaultRuntimeEngine
+	}
+	if obj.Features.PSP == nil {
+		obj.Features.PSP = boolPtr(true)
+	}
+
+	setDefaultsNetwork(&obj.Network)
+}
+
+// setDefaultsNetwork assigns default values to the network configuration
+func setDefaultsNetwork(obj *NetworkConfiguration) {
+	if obj.PodSubnet == "" && len(obj.PodSubnets) == 0 {
+		obj.PodSubnets = []string{DefaultPodSubnet}
+	}
+	if obj.ServiceSubnet == "" && len(obj.ServiceSubnets) == 0 {
+		obj.ServiceSubnets = []string{DefaultServiceSubnet}
+	}
+	if obj.Bind.Family == "" {
+		// prefer the family of the (first) pods subnet
+		obj.Bind.Family = DefaultBindFamily
+		first := obj.PodSubnet
+		if len(obj.PodSubnets) > 0 {
+			first = obj.PodSubnets[0]
+		}
+		if ip, _, err := net.ParseCIDR(first); err == nil && ip.To4() == nil {
+			obj.Bind.Family = "ipv6"
+		}
+	}
+	if obj.Dns.Domain == "" {
+		obj.Dns.Domain = DefaultDNSDomain
+	}
+	if obj.Cni.Driver == "" {
+		obj.Cni.Driver = DefaultCniDriver
+	}
+	if obj.Cni.BinDir == "" {
+		obj.Cni.BinDir = DefaultCniBinDir
+	}
+	if obj.Cni.ConfDir == "" {
+		obj.Cni.ConfDir = DefaultCniConfDir
+	}
+	for i := range obj.Cni.Networks {
+		if obj.Cni.Networks[i].Namespace == "" {
+			obj.Cni.Networks[i].Namespace = DefaultCniNetworkNamespace
+		}
+	}
+	if obj.Cni.Flannel.Backend.Type == "" {
+		obj.Cni.Flannel.Backend.Type = DefaultFlannelBackend
+	}
+	if obj.Cni.Flannel.LogLevel == nil {
+		obj.Cni.Flannel.LogLevel = intPtr(DefaultFlannelLogLevel)
+	}
+	if obj.Cni.Calico.Encapsulation == "" {
+		obj.Cni.Calico.Encapsulation = DefaultCalicoEncapsulation
+	}
+	if obj.Cni.Calico.CniImage == "" {
+		obj.Cni.Calico.CniImage = DefaultCalicoCniImage
+	}
+	if obj.LoadBalancer.Port == 0 {
+		obj.LoadBalancer.Port = DefaultLoadBalancerPort
+	}
+	if obj.LoadBalancer.RouterID == 0 {
+		obj.LoadBalancer.RouterID = DefaultLoadBalancerRouterID
+	}
+	if obj.LoadBalancer.Image == "" {
+		obj.LoadBalancer.Image = DefaultLoadBalancerImage
+	}
+	if obj.LoadBalancer.ProxyImage == "" {
+		obj.LoadBalancer.ProxyImage = DefaultLoadBalancerProxyImage
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,379 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubeadmapiv1beta1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta1"
+
+	"github.com/kubic-project/kubic-init/pkg/crypto"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+// the minimum length of the secret used for encrypting the control-plane certificates
+const minControlPlaneSecretLen = 16
+
+// ValidationFunc is a function that checks some part of the configuration
+type ValidationFunc func(*KubicInitConfiguration) field.ErrorList
+
+// extraValidations is the list of validations registered by other packages
+// (ie, the CNI registry, that knows about the drivers available)
+var extraValidations = []ValidationFunc{}
+
+// RegisterValidation registers an extra validation function that will be run by Validate()
+func RegisterValidation(f ValidationFunc) {
+	extraValidations = append(extraValidations, f)
+}
+
+// Validate checks the configuration, returning the list of errors found
+func (kubicCfg KubicInitConfiguration) Validate() field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateNetwork(&kubicCfg.Network, field.NewPath("network"))...)
+	allErrs = append(allErrs, validateClusterFormation(&kubicCfg.ClusterFormation, field.NewPath("clusterFormation"))...)
+	allErrs = append(allErrs, validateCertificates(&kubicCfg, field.NewPath("certificates"))...)
+	allErrs = append(allErrs, validateRuntime(&kubicCfg.Runtime, field.NewPath("runtime"))...)
+	allErrs = append(allErrs, validateAuth(&kubicCfg.Auth, field.NewPath("auth"))...)
+
+	for _, f := range extraValidations {
+		allErrs = append(allErrs, f(&kubicCfg)...)
+	}
+
+	return allErrs
+}
+
+func validateNetwork(network *NetworkConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(network.Bind.Address) > 0 && net.ParseIP(network.Bind.Address) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("bind", "address"),
+			network.Bind.Address, "must be a valid IP address"))
+	}
+
+	if len(network.Bind.Address) > 0 && len(network.Bind.Interface) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("bind", "interface"),
+			network.Bind.Interface, "cannot be used together with an address"))
+	}
+
+	validFamilies := sets.NewString(kubicutil.FamilyIPv4, kubicutil.FamilyIPv6)
+	if len(network.Bind.Family) > 0 && !validFamilies.Has(network.Bind.Family) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("bind", "family"),
+			network.Bind.Family, validFamilies.List()))
+	}
+
+	allErrs = append(allErrs, validateLoadBalancer(&network.LoadBalancer, fldPath.Child("loadBalancer"))...)
+
+	podFamilies, podErrs := validateSubnets(network.PodSubnets, fldPath.Child("podSubnets"))
+	allErrs = append(allErrs, podErrs...)
+
+	serviceFamilies, serviceErrs := validateSubnets(network.ServiceSubnets, fldPath.Child("serviceSubnets"))
+	allErrs = append(allErrs, serviceErrs...)
+
+	if len(podErrs) == 0 && len(serviceErrs) == 0 {
+		if !podFamilies.Equal(serviceFamilies) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("serviceSubnets"), network.ServiceSubnets,
+				"must be in the same address families as the podSubnets "+strings.Join(podFamilies.List(), ", ")))
+		}
+		if validFamilies.Has(network.Bind.Family) && !podFamilies.Has(network.Bind.Family) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("bind", "family"), network.Bind.Family,
+				"must be one of the podSubnets families "+strings.Join(podFamilies.List(), ", ")))
+		}
+	}
+
+	for _, family := range podFamilies.List() {
+		_, podSubnet, err1 := net.ParseCIDR(network.GetPodSubnet(family))
+		_, serviceSubnet, err2 := net.ParseCIDR(network.GetServiceSubnet(family))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if podSubnet.Contains(serviceSubnet.IP) || serviceSubnet.Contains(podSubnet.IP) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("serviceSubnets"),
+				serviceSubnet.String(), "overlaps with the podSubnet "+podSubnet.String()))
+		}
+
+		// the controller manager assigns a subnet of this size to each node
+		nodeMaskSize := DefaultNodeCIDRMaskSizeIPv4
+		if family == kubicutil.FamilyIPv6 {
+			nodeMaskSize = DefaultNodeCIDRMaskSizeIPv6
+		}
+		if ones, _ := podSubnet.Mask.Size(); ones >= nodeMaskSize {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("podSubnets"), podSubnet.String(),
+				fmt.Sprintf("must be bigger than the subnet assigned to each node (/%d)", nodeMaskSize)))
+		}
+	}
+
+	return allErrs
+}
+
+// validateLoadBalancer checks the configuration of the API servers load balancer
+func validateLoadBalancer(lb *LoadBalancerConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(lb.VirtualIP) == 0 {
+		return allErrs
+	}
+
+	if net.ParseIP(lb.VirtualIP) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("virtualIP"), lb.VirtualIP, "must be a valid IP address"))
+	}
+	if len(lb.Interface) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("interface"), "needed for the virtual IP"))
+	}
+	if lb.Port <= 0 || lb.Port > 65535 || lb.Port == DefaultAPIServerPort {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("port"), lb.Port,
+			fmt.Sprintf("must be a valid port, different to the API server port (%d)", DefaultAPIServerPort)))
+	}
+	if lb.RouterID < 1 || lb.RouterID > 255 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("routerID"), lb.RouterID, "must be between 1 and 255"))
+	}
+	return allErrs
+}
+
+// validateSubnets checks a list of subnets (one subnet, or an IPv4 and an IPv6 subnet
+// for dual-stack), returning the address families found
+func validateSubnets(subnets []string, fldPath *field.Path) (sets.String, field.ErrorList) {
+	allErrs := field.ErrorList{}
+	families := sets.NewString()
+
+	if len(subnets) == 0 {
+		return families, append(allErrs, field.Required(fldPath, ""))
+	}
+	if len(subnets) > 2 {
+		return families, append(allErrs, field.TooMany(fldPath, len(subnets), 2))
+	}
+
+	for i, subnet := range subnets {
+		ipNet, errs := validateCIDR(subnet, fldPath.Index(i))
+		allErrs = append(allErrs, errs...)
+		if ipNet == nil {
+			continue
+		}
+
+		family := kubicutil.GetIPFamily(ipNet.IP)
+		if families.Has(family) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), subnet,
+				"only one subnet per address family is supported"))
+		}
+		families.Insert(family)
+	}
+
+	return families, allErrs
+}
+
+// validateCIDR parses a (required) CIDR, returning the network when it is valid
+func validateCIDR(cidr string, fldPath *field.Path) (*net.IPNet, field.ErrorList) {
+	allErrs := field.ErrorList{}
+
+	if len(cidr) == 0 {
+		return nil, append(allErrs, field.Required(fldPath, ""))
+	}
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, append(allErrs, field.Invalid(fldPath, cidr, err.Error()))
+	}
+
+	return ipNet, allErrs
+}
+
+func validateClusterFormation(cf *ClusterFormationConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(cf.Token) > 0 {
+		if _, err := kubeadmapiv1beta1.NewBootstrapTokenString(cf.Token); err != nil {
+			// note well: do not include the token in the error message
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("token"),
+				"<redacted>", "must be of the form \"[a-z0-9]{6}.[a-z0-9]{16}\""))
+		}
+	}
+
+	switch cf.Role {
+	case "", RoleWorker:
+	case RoleMaster:
+		if (len(cf.Seeder) > 0 || len(cf.Discovery.Providers) > 0) && len(cf.ControlPlaneSecret) == 0 {
+			allErrs = append(allErrs, field.Required(fldPath.Child("controlPlaneSecret"),
+				"needed for getting the control-plane certificates"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("role"), cf.Role, []string{RoleMaster, RoleWorker}))
+	}
+
+	if len(cf.ControlPlaneSecret) > 0 && len(cf.ControlPlaneSecret) < minControlPlaneSecretLen {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("controlPlaneSecret"), "<redacted>",
+			fmt.Sprintf("must be at least %d characters long", minControlPlaneSecretLen)))
+	}
+
+	allErrs = append(allErrs, validateApproval(cf, fldPath.Child("approval"))...)
+
+	if len(cf.Seeder) == 0 {
+		allErrs = append(allErrs, validateDiscovery(&cf.Discovery, fldPath.Child("discovery"))...)
+
+		// the elected seeder must use a token known by all the other nodes
+		if cf.Discovery.Election && len(cf.Token) == 0 {
+			allErrs = append(allErrs, field.Required(fldPath.Child("token"), "needed for electing a seeder"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateApproval(cf *ClusterFormationConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	approval := &cf.Approval
+
+	for i, entry := range approval.Allowlist {
+		if len(strings.TrimSpace(entry)) == 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("allowlist").Index(i), entry,
+				"must be a node name or an IP address"))
+		}
+	}
+
+	policy := &approval.Policy
+	policyPath := fldPath.Child("policy")
+	for i, hostname := range policy.Hostnames {
+		if _, err := regexp.Compile(hostname); err != nil {
+			allErrs = append(allErrs, field.Invalid(policyPath.Child("hostnames").Index(i), hostname, err.Error()))
+		}
+	}
+	for i, cidr := range policy.CIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(policyPath.Child("cidrs").Index(i), cidr, err.Error()))
+		}
+	}
+	if policy.MaxNodes < 0 {
+		allErrs = append(allErrs, field.Invalid(policyPath.Child("maxNodes"), policy.MaxNodes, "must not be negative"))
+	}
+	if policy.MaxNodesPerToken < 0 {
+		allErrs = append(allErrs, field.Invalid(policyPath.Child("maxNodesPerToken"), policy.MaxNodesPerToken, "must not be negative"))
+	}
+	for i, window := range policy.Windows {
+		if _, err := kubicutil.ParseTimeWindow(window); err != nil {
+			allErrs = append(allErrs, field.Invalid(policyPath.Child("windows").Index(i), window, err.Error()))
+		}
+	}
+
+	// the nodes are approved by the controller-manager when the auto-approval is enabled
+	if cf.AutoApprove && (len(approval.Allowlist) > 0 || !policy.IsEmpty()) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Root().Child("autoApprove"), cf.AutoApprove,
+			"must be disabled for using an approval allowlist or policy"))
+	}
+
+	return allErrs
+}
+
+func validateDiscovery(discovery *DiscoveryConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	providers := sets.NewString()
+	for i, provider := range discovery.Providers {
+		switch provider {
+		case DiscoveryProviderMDNS, DiscoveryProviderDNS, DiscoveryProviderFile:
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("providers").Index(i), provider,
+				[]string{DiscoveryProviderMDNS, DiscoveryProviderDNS, DiscoveryProviderFile}))
+		}
+		if providers.Has(provider) {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("providers").Index(i), provider))
+		}
+		providers.Insert(provider)
+	}
+
+	if providers.Has(DiscoveryProviderDNS) && len(discovery.Domain) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("domain"), "needed for the DNS SRV lookups"))
+	}
+	if providers.Has(DiscoveryProviderFile) && len(discovery.File) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("file"), "needed for the file provider"))
+	}
+
+	if discovery.Election && !providers.Has(DiscoveryProviderMDNS) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("election"), discovery.Election,
+			fmt.Sprintf("the election requires the %q provider", DiscoveryProviderMDNS)))
+	}
+
+	return allErrs
+}
+
+func validateCertificates(kubicCfg *KubicInitConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	certs := &kubicCfg.Certificates
+
+	for i, hash := range certs.CaHashes {
+		if err := crypto.ValidateCACertHash(hash); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("caCrtHashes").Index(i), hash, err.Error()))
+		}
+	}
+
+	// note well: a caCrt path could not exist yet (ie, when validating the config in
+	// another machine), so we only check inline certificates
+	if crypto.IsInlinePEM(certs.CaCrt) {
+		if _, err := crypto.CACertHashes([]byte(certs.CaCrt)); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("caCrt"), "<inline PEM>", err.Error()))
+		}
+	}
+
+	if certs.RequireVerification && (!kubicCfg.IsSeeder() || kubicCfg.HasDiscovery()) && len(certs.CaHashes) == 0 && len(certs.CaCrt) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("caCrtHashes"),
+			"a caCrtHashes or a caCrt is needed for verifying the identity of the seeder"))
+	}
+
+	return allErrs
+}
+
+func validateRuntime(runtime *RuntimeConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if _, found := DefaultCriSocket[runtime.Engine]; !found {
+		engines := []string{}
+		for engine := range DefaultCriSocket {
+			engines = append(engines, engine)
+		}
+		sort.Strings(engines)
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("engine"), runtime.Engine, engines))
+	}
+
+	return allErrs
+}
+
+func validateAuth(auth *AuthConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	oidcPath := fldPath.Child("oidc")
+
+	if len(auth.OIDC.Issuer) > 0 {
+		u, err := url.Parse(auth.OIDC.Issuer)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(oidcPath.Child("issuer"), auth.OIDC.Issuer, err.Error()))
+		} else if u.Scheme != "https" || len(u.Host) == 0 {
+			allErrs = append(allErrs, field.Invalid(oidcPath.Child("issuer"), auth.OIDC.Issuer,
+				"must be an absolute \"https://\" URL"))
+		}
+	}
+
+	if len(auth.OIDC.CA) > 0 && !filepath.IsAbs(auth.OIDC.CA) {
+		allErrs = append(allErrs, field.Invalid(oidcPath.Child("ca"), auth.OIDC.CA, "must be an absolute path"))
+	}
+
+	return allErrs
+}
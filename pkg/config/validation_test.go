@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package config
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		descr  string
+		modify func(*KubicInitConfiguration)
+		fields []string
+	}{
+		{
+			descr:  "default configuration",
+			modify: func(cfg *KubicInitConfiguration) {},
+			fields: []string{},
+		},
+		{
+			descr: "overlapping subnets",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Network.PodSubnets = []string{"10.0.0.0/8"}
+				cfg.Network.ServiceSubnets = []string{"10.10.0.0/16"}
+			},
+			fields: []string{"network.serviceSubnets"},
+		},
+		{
+			descr: "invalid subnets",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Network.PodSubnets = []string{"10.0.0.0"}
+				cfg.Network.ServiceSubnets = []string{}
+			},
+			fields: []string{"network.podSubnets[0]", "network.serviceSubnets"},
+		},
+		{
+			descr: "dual-stack with mismatched families",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Network.PodSubnets = []string{"10.0.0.0/14", "fd00:10::/56"}
+				cfg.Network.ServiceSubnets = []string{"10.96.0.0/16"}
+				cfg.Network.Bind.Family = "ipv6"
+			},
+			fields: []string{"network.serviceSubnets"},
+		},
+		{
+			descr: "two subnets in the same family and a small pods subnet",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Network.PodSubnets = []string{"fd00:10::/64", "fd00:20::/56"}
+				cfg.Network.ServiceSubnets = []string{"fd00:96::/112"}
+			},
+			fields: []string{"network.podSubnets[1]", "network.podSubnets"},
+		},
+		{
+			descr: "bind address and interface",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Network.Bind.Address = "10.0.0.1"
+				cfg.Network.Bind.Interface = "eth*"
+				cfg.Network.Bind.Family = "ipx"
+			},
+			fields: []string{"network.bind.interface", "network.bind.family"},
+		},
+		{
+			descr: "malformed token",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.ClusterFormation.Token = "94dcda-c271f4ff502789ca"
+			},
+			fields: []string{"clusterFormation.token"},
+		},
+		{
+			descr: "load balancer without an interface",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Network.LoadBalancer.VirtualIP = "10.0.0.100"
+				cfg.Network.LoadBalancer.Port = 6443
+			},
+			fields: []string{"network.loadBalancer.interface", "network.loadBalancer.port"},
+		},
+		{
+			descr: "required verification without hashes",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.ClusterFormation.Seeder = "seeder.some.name.com:6443"
+				cfg.Certificates.RequireVerification = true
+			},
+			fields: []string{"certificates.caCrtHashes"},
+		},
+		{
+			descr: "invalid hash and inline CA certificate",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Certificates.CaHashes = []string{"md5:1234"}
+				cfg.Certificates.CaCrt = "-----BEGIN CERTIFICATE-----"
+			},
+			fields: []string{"certificates.caCrtHashes[0]", "certificates.caCrt"},
+		},
+		{
+			descr: "unknown role",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.ClusterFormation.Role = "boss"
+			},
+			fields: []string{"clusterFormation.role"},
+		},
+		{
+			descr: "master without a control-plane secret",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.ClusterFormation.Seeder = "seeder.some.name.com:6443"
+				cfg.ClusterFormation.Role = RoleMaster
+			},
+			fields: []string{"clusterFormation.controlPlaneSecret"},
+		},
+		{
+			descr: "unknown discovery provider, and election without mdns or a token",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.ClusterFormation.Discovery.Providers = []string{"dns", "consul"}
+				cfg.ClusterFormation.Discovery.Election = true
+			},
+			fields: []string{
+				"clusterFormation.discovery.providers[1]",
+				"clusterFormation.discovery.domain",
+				"clusterFormation.discovery.election",
+				"clusterFormation.token",
+			},
+		},
+		{
+			descr: "invalid approval policy, with auto-approval",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.ClusterFormation.AutoApprove = true
+				cfg.ClusterFormation.Approval.Policy.Hostnames = []string{"worker-[0-9+"}
+				cfg.ClusterFormation.Approval.Policy.CIDRs = []string{"10.0.0.0/33"}
+				cfg.ClusterFormation.Approval.Policy.Windows = []string{"Mon-Fri 08:00"}
+			},
+			fields: []string{
+				"clusterFormation.approval.policy.hostnames[0]",
+				"clusterFormation.approval.policy.cidrs[0]",
+				"clusterFormation.approval.policy.windows[0]",
+				"clusterFormation.autoApprove",
+			},
+		},
+		{
+			descr: "unknown runtime engine",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Runtime.Engine = "rkt"
+			},
+			fields: []string{"runtime.engine"},
+		},
+		{
+			descr: "insecure OIDC issuer and relative CA",
+			modify: func(cfg *KubicInitConfiguration) {
+				cfg.Auth.OIDC.Issuer = "http://dex.some.name.com:32000"
+				cfg.Auth.OIDC.CA = "pki/ca.crt"
+			},
+			fields: []string{"auth.oidc.issuer", "auth.oidc.ca"},
+		},
+	}
+
+	defaultCfg, err := BytesToKubicInitConfig([]byte{}, false)
+	if err != nil {
+		t.Fatalf("could not get a default configuration: %v", err)
+	}
+
+	for _, test := range tests {
+		cfg := defaultCfg.DeepCopy()
+		test.modify(cfg)
+
+		errs := cfg.Validate()
+		if len(errs) != len(test.fields) {
+			t.Logf("%s: errors: %v", test.descr, errs)
+			t.Fatalf("%s: expected %d errors, got %d", test.descr, len(test.fields), len(errs))
+		}
+		for i, e := range errs {
+			if e.Field != test.fields[i] {
+				t.Fatalf("%s: expected an error in %q, got %q", test.descr, test.fields[i], e.Field)
+			}
+		}
+	}
+}
@@ -25,6 +25,59 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalConfiguration) DeepCopyInto(out *ApprovalConfiguration) {
+	*out = *in
+	if in.Allowlist != nil {
+		in, out := &in.Allowlist, &out.Allowlist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Policy.DeepCopyInto(&out.Policy)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalConfiguration.
+func (in *ApprovalConfiguration) DeepCopy() *ApprovalConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalPolicyConfiguration) DeepCopyInto(out *ApprovalPolicyConfiguration) {
+	*out = *in
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalPolicyConfiguration.
+func (in *ApprovalPolicyConfiguration) DeepCopy() *ApprovalPolicyConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalPolicyConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthConfiguration) DeepCopyInto(out *AuthConfiguration) {
 	*out = *in
@@ -58,9 +111,30 @@ func (in *BindConfiguration) DeepCopy() *BindConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalicoConfiguration) DeepCopyInto(out *CalicoConfiguration) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalicoConfiguration.
+func (in *CalicoConfiguration) DeepCopy() *CalicoConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(CalicoConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertsConfiguration) DeepCopyInto(out *CertsConfiguration) {
 	*out = *in
+	if in.CaHashes != nil {
+		in, out := &in.CaHashes, &out.CaHashes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -77,6 +151,8 @@ func (in *CertsConfiguration) DeepCopy() *CertsConfiguration {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterFormationConfiguration) DeepCopyInto(out *ClusterFormationConfiguration) {
 	*out = *in
+	in.Discovery.DeepCopyInto(&out.Discovery)
+	in.Approval.DeepCopyInto(&out.Approval)
 	return
 }
 
@@ -93,6 +169,13 @@ func (in *ClusterFormationConfiguration) DeepCopy() *ClusterFormationConfigurati
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CniConfiguration) DeepCopyInto(out *CniConfiguration) {
 	*out = *in
+	out.Calico = in.Calico
+	out.Flannel = in.Flannel
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]CniNetworkConfiguration, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -106,6 +189,22 @@ func (in *CniConfiguration) DeepCopy() *CniConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CniNetworkConfiguration) DeepCopyInto(out *CniNetworkConfiguration) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CniNetworkConfiguration.
+func (in *CniNetworkConfiguration) DeepCopy() *CniNetworkConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(CniNetworkConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSConfiguration) DeepCopyInto(out *DNSConfiguration) {
 	*out = *in
@@ -122,6 +221,27 @@ func (in *DNSConfiguration) DeepCopy() *DNSConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveryConfiguration) DeepCopyInto(out *DiscoveryConfiguration) {
+	*out = *in
+	if in.Providers != nil {
+		in, out := &in.Providers, &out.Providers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveryConfiguration.
+func (in *DiscoveryConfiguration) DeepCopy() *DiscoveryConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveryConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EtcdConfiguration) DeepCopyInto(out *EtcdConfiguration) {
 	*out = *in
@@ -159,14 +279,47 @@ func (in *FeaturesConfiguration) DeepCopy() *FeaturesConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlannelBackendConfiguration) DeepCopyInto(out *FlannelBackendConfiguration) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlannelBackendConfiguration.
+func (in *FlannelBackendConfiguration) DeepCopy() *FlannelBackendConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(FlannelBackendConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlannelConfiguration) DeepCopyInto(out *FlannelConfiguration) {
+	*out = *in
+	out.Backend = in.Backend
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlannelConfiguration.
+func (in *FlannelConfiguration) DeepCopy() *FlannelConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(FlannelConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubicInitConfiguration) DeepCopyInto(out *KubicInitConfiguration) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	out.Network = in.Network
+	in.Network.DeepCopyInto(&out.Network)
 	out.Paths = in.Paths
-	out.ClusterFormation = in.ClusterFormation
-	out.Certificates = in.Certificates
+	in.ClusterFormation.DeepCopyInto(&out.ClusterFormation)
+	in.Certificates.DeepCopyInto(&out.Certificates)
 	in.Etcd.DeepCopyInto(&out.Etcd)
 	out.Runtime = in.Runtime
 	out.Features = in.Features
@@ -193,6 +346,22 @@ func (in *KubicInitConfiguration) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerConfiguration) DeepCopyInto(out *LoadBalancerConfiguration) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerConfiguration.
+func (in *LoadBalancerConfiguration) DeepCopy() *LoadBalancerConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LocalEtcdConfiguration) DeepCopyInto(out *LocalEtcdConfiguration) {
 	*out = *in
@@ -223,9 +392,20 @@ func (in *LocalEtcdConfiguration) DeepCopy() *LocalEtcdConfiguration {
 func (in *NetworkConfiguration) DeepCopyInto(out *NetworkConfiguration) {
 	*out = *in
 	out.Bind = in.Bind
-	out.Cni = in.Cni
+	in.Cni.DeepCopyInto(&out.Cni)
 	out.Dns = in.Dns
 	out.Proxy = in.Proxy
+	out.LoadBalancer = in.LoadBalancer
+	if in.PodSubnets != nil {
+		in, out := &in.PodSubnets, &out.PodSubnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceSubnets != nil {
+		in, out := &in.ServiceSubnets, &out.ServiceSubnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
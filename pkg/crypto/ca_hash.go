@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package crypto
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/pubkeypin"
+)
+
+// IsInlinePEM returns true when `s` looks like some PEM-encoded contents (and not like a path)
+func IsInlinePEM(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN")
+}
+
+// LoadCACert loads a CA certificate bundle from a path or from some inline PEM contents
+func LoadCACert(pathOrPEM string) ([]byte, error) {
+	if IsInlinePEM(pathOrPEM) {
+		return []byte(pathOrPEM), nil
+	}
+	contents, err := ioutil.ReadFile(pathOrPEM)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the CA certificate: %v", err)
+	}
+	return contents, nil
+}
+
+// CACertHashes returns the public key pins (ie, "sha256:...") of all the certificates
+// in a PEM bundle (a bundle can contain more than one CA, ie, during a CA rotation)
+func CACertHashes(pemBytes []byte) ([]string, error) {
+	certs, err := certutil.ParseCertsPEM(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the CA certificate: %v", err)
+	}
+
+	hashes := []string{}
+	for _, cert := range certs {
+		hashes = append(hashes, pubkeypin.Hash(cert))
+	}
+	return hashes, nil
+}
+
+// ValidateCACertHash checks a CA hash has the right format (ie, "sha256:<hex>")
+func ValidateCACertHash(hash string) error {
+	return pubkeypin.NewSet().Allow(hash)
+}
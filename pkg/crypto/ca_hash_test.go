@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func newTestCACert(t *testing.T, name string) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	cert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: name}, key)
+	if err != nil {
+		t.Fatalf("could not generate certificate: %v", err)
+	}
+	return certutil.EncodeCertPEM(cert)
+}
+
+func TestCACertHashes(t *testing.T) {
+	// a bundle with the old and the new CA, like during a CA rotation
+	bundle := append(newTestCACert(t, "old-ca"), newTestCACert(t, "new-ca")...)
+	if !IsInlinePEM(string(bundle)) {
+		t.Fatalf("the bundle was not detected as inline PEM")
+	}
+
+	hashes, err := CACertHashes(bundle)
+	if err != nil {
+		t.Fatalf("could not get the hashes: %v", err)
+	}
+	t.Logf("hashes: %v", hashes)
+	if len(hashes) != 2 || hashes[0] == hashes[1] {
+		t.Fatalf("unexpected hashes: %v", hashes)
+	}
+	for _, hash := range hashes {
+		if err := ValidateCACertHash(hash); err != nil {
+			t.Fatalf("invalid hash %q: %v", hash, err)
+		}
+	}
+
+	if err := ValidateCACertHash("md5:1234"); err == nil {
+		t.Fatalf("an invalid hash was accepted")
+	}
+}
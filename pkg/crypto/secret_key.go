@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// parameters for deriving keys from shared secrets
+const (
+	secretKeySaltLen = 16
+	secretKeyLen     = 32 // AES-256
+	scryptN          = 32768
+	scryptR          = 8
+	scryptP          = 1
+)
+
+// SecretKey is a key, derived from a secret shared by some nodes, that can
+// be used for encrypting data stored in the cluster (ie, the control-plane certificates)
+type SecretKey struct {
+	// Salt is the (random) salt used when deriving the key. It must be
+	// stored with the encrypted data.
+	Salt []byte
+
+	key []byte
+}
+
+// NewSecretKey derives a new key from a shared secret, using a random salt
+func NewSecretKey(secret string) (*SecretKey, error) {
+	salt := make([]byte, secretKeySaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return NewSecretKeyWithSalt(secret, salt)
+}
+
+// NewSecretKeyWithSalt derives a key from a shared secret and an existing salt
+func NewSecretKeyWithSalt(secret string, salt []byte) (*SecretKey, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("no secret provided")
+	}
+	key, err := scrypt.Key([]byte(secret), salt, scryptN, scryptR, scryptP, secretKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretKey{Salt: salt, key: key}, nil
+}
+
+// Encrypt encrypts (and authenticates) some data with AES-GCM. The nonce is
+// prepended to the result.
+func (sk SecretKey) Encrypt(data []byte) ([]byte, error) {
+	gcm, err := sk.newGCM()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// Decrypt decrypts some data obtained with Encrypt()
+func (sk SecretKey) Decrypt(data []byte) ([]byte, error) {
+	gcm, err := sk.newGCM()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt data (wrong secret?): %v", err)
+	}
+	return plain, nil
+}
+
+func (sk SecretKey) newGCM() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(sk.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
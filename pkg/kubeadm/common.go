@@ -20,6 +20,7 @@ package kubeadm
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -28,9 +29,9 @@ import (
 	"github.com/golang/glog"
 	"k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/validation"
 	"k8s.io/kubernetes/cmd/kubeadm/app/features"
-	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
 
 	"github.com/kubic-project/kubic-init/pkg/config"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
 )
 
 const kubeadmConfigTemplate = "kubic-kubeadm.*.yaml"
@@ -40,10 +41,11 @@ type toKubeadmConfig func(*config.KubicInitConfiguration, map[string]bool) ([]by
 
 // kubeadmCmd runs a "kubeadm" command
 func kubeadmCmd(name string, kubicCfg *config.KubicInitConfiguration, configer toKubeadmConfig, args ...string) error {
+	return kubeadmCmdWithOutput(name, kubicCfg, configer, nil, args...)
+}
 
-	featureGates, err := features.NewFeatureGate(&features.InitFeatureGates, config.DefaultFeatureGates)
-	kubeadmutil.CheckErr(err)
-	glog.V(3).Infof("[kubic] feature gates: %+v", featureGates)
+// kubeadmCmdWithOutput runs a "kubeadm" command, copying its standard output to `out` (when not nil)
+func kubeadmCmdWithOutput(name string, kubicCfg *config.KubicInitConfiguration, configer toKubeadmConfig, out io.Writer, args ...string) error {
 
 	args = append([]string{name}, args...)
 
@@ -55,7 +57,7 @@ func kubeadmCmd(name string, kubicCfg *config.KubicInitConfiguration, configer t
 		defer os.Remove(configFile.Name())
 
 		// get the configuration
-		marshalledBytes, err := configer(kubicCfg, featureGates)
+		marshalledBytes, err := renderConfig(kubicCfg, configer)
 		if err != nil {
 			return err
 		}
@@ -77,6 +79,7 @@ func kubeadmCmd(name string, kubicCfg *config.KubicInitConfiguration, configer t
 	// Now we can run the "kubeadm" command
 	glog.V(1).Infof("[kubic] exec: %s %s", kubeadmPath, strings.Join(args, " "))
 	cmd := exec.Command(kubeadmPath, args...)
+	cmd.Stdout = out
 	stderr, _ := cmd.StderrPipe()
 	if err := cmd.Start(); err != nil {
 		return err
@@ -96,9 +99,38 @@ func kubeadmCmd(name string, kubicCfg *config.KubicInitConfiguration, configer t
 	return nil
 }
 
+// renderConfig gets the kubeadm configuration produced by `configer`
+func renderConfig(kubicCfg *config.KubicInitConfiguration, configer toKubeadmConfig) ([]byte, error) {
+	featureGates, err := features.NewFeatureGate(&features.InitFeatureGates, config.DefaultFeatureGates)
+	if err != nil {
+		return nil, err
+	}
+	glog.V(3).Infof("[kubic] feature gates: %+v", featureGates)
+
+	return configer(kubicCfg, featureGates)
+}
+
+// RenderInitConfig returns the kubeadm configuration (InitConfiguration
+// and ClusterConfiguration) that would be used for "kubeadm init"
+func RenderInitConfig(kubicCfg *config.KubicInitConfiguration) ([]byte, error) {
+	return renderConfig(kubicCfg, toInitConfig)
+}
+
+// RenderJoinConfig returns the kubeadm configuration (JoinConfiguration)
+// that would be used for "kubeadm join"
+func RenderJoinConfig(kubicCfg *config.KubicInitConfiguration) ([]byte, error) {
+	return renderConfig(kubicCfg, toJoinConfig)
+}
+
 // getIgnorePreflightArg returns the arg for ignoring pre-flight errors
-func getIgnorePreflightArg() string {
-	ignorePreflightErrorsSet, err := validation.ValidateIgnorePreflightErrors(config.DefaultIgnoredPreflightErrors)
+func getIgnorePreflightArg(kubicCfg *config.KubicInitConfiguration) string {
+	ignored := append([]string{}, config.DefaultIgnoredPreflightErrors...)
+	if kubicCfg.IsMaster() && kubicCfg.HasLoadBalancer() {
+		// the load balancer static pod is created before running kubeadm
+		ignored = append(ignored, "DirAvailable--etc-kubernetes-manifests")
+	}
+
+	ignorePreflightErrorsSet, err := validation.ValidateIgnorePreflightErrors(ignored)
 	if err != nil {
 		panic(err)
 	}
@@ -107,6 +139,35 @@ func getIgnorePreflightArg() string {
 	return arg
 }
 
+// getKubeletExtraArgs returns a copy of the default kubelet settings
+func getKubeletExtraArgs() map[string]string {
+	args := map[string]string{}
+	for k, v := range config.DefaultKubeletSettings {
+		args[k] = v
+	}
+	return args
+}
+
+// getAdvertiseAddress returns the IP address this node should advertise, or an
+// empty string when no address (or interface) has been provided, so kubeadm
+// can detect it (kubeadm only detects IPv4 addresses, so we must do it
+// ourselves for IPv6)
+func getAdvertiseAddress(kubicCfg *config.KubicInitConfiguration) (string, error) {
+	if len(kubicCfg.Network.Bind.Address) == 0 && len(kubicCfg.Network.Bind.Interface) == 0 &&
+		kubicCfg.Network.Bind.Family != kubicutil.FamilyIPv6 {
+		return "", nil
+	}
+
+	bindIP, err := kubicCfg.GetBindIP()
+	if err != nil {
+		return "", fmt.Errorf("could not get the bind address: %v", err)
+	}
+	if bindIP.IsUnspecified() || bindIP.IsLoopback() {
+		return "", nil
+	}
+	return bindIP.String(), nil
+}
+
 func getVerboseArg() string {
 	return "--v=3" // TODO: make this configurable
 }
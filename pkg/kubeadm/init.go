@@ -34,7 +34,7 @@ import (
 func NewInit(kubicCfg *config.KubicInitConfiguration, args ...string) error {
 
 	args = append(args,
-		getIgnorePreflightArg(),
+		getIgnorePreflightArg(kubicCfg),
 		getVerboseArg())
 
 	return kubeadmCmd("init", kubicCfg, toInitConfig, args...)
@@ -46,19 +46,15 @@ func toInitConfig(kubicCfg *config.KubicInitConfiguration, featureGates map[stri
 
 	initCfg := &kubeadmapiv1beta1.InitConfiguration{
 		ClusterConfiguration: kubeadmapiv1beta1.ClusterConfiguration{
-			ControlPlaneEndpoint: kubicCfg.Network.Dns.ExternalFqdn,
+			ControlPlaneEndpoint: kubicCfg.GetControlPlaneEndpoint(),
 			FeatureGates:         featureGates,
 			APIServer: kubeadmapiv1beta1.APIServer{
 				CertSANs: []string{},
 			},
-			KubernetesVersion: config.DefaultKubernetesVersion,
-			Networking: kubeadmapiv1beta1.Networking{
-				PodSubnet:     kubicCfg.Network.PodSubnet,
-				ServiceSubnet: kubicCfg.Network.ServiceSubnet,
-			},
+			KubernetesVersion: getKubernetesVersion(kubicCfg),
 		},
 		NodeRegistration: kubeadmapiv1beta1.NodeRegistrationOptions{
-			KubeletExtraArgs: config.DefaultKubeletSettings,
+			KubeletExtraArgs: getKubeletExtraArgs(),
 		},
 	}
 
@@ -98,10 +94,20 @@ func toInitConfig(kubicCfg *config.KubicInitConfiguration, featureGates map[stri
 		initCfg.ClusterConfiguration.APIServer.ExtraArgs["oidc-issuer-url"] = fmt.Sprintf("https://%s:%d", public, config.DefaultDexIssuerPort)
 	}
 
-	if len(kubicCfg.Network.Bind.Address) > 0 && kubicCfg.Network.Bind.Address != "127.0.0.1" {
-		glog.V(8).Infof("[kubic] setting bind address: %s", kubicCfg.Network.Bind.Address)
-		initCfg.LocalAPIEndpoint.AdvertiseAddress = kubicCfg.Network.Bind.Address
-		initCfg.ClusterConfiguration.APIServer.CertSANs = append(initCfg.ClusterConfiguration.APIServer.CertSANs, kubicCfg.Network.Bind.Address)
+	if err := setNetworking(kubicCfg, &initCfg.ClusterConfiguration); err != nil {
+		return nil, err
+	}
+	setKubeletNetworking(kubicCfg, initCfg.KubernetesVersion, &initCfg.NodeRegistration)
+
+	advertiseAddress, err := getAdvertiseAddress(kubicCfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(advertiseAddress) > 0 {
+		glog.V(8).Infof("[kubic] setting bind address: %s", advertiseAddress)
+		initCfg.LocalAPIEndpoint.AdvertiseAddress = advertiseAddress
+		initCfg.ClusterConfiguration.APIServer.CertSANs = append(initCfg.ClusterConfiguration.APIServer.CertSANs, advertiseAddress)
+		initCfg.NodeRegistration.KubeletExtraArgs["node-ip"] = advertiseAddress
 	}
 
 	// TODO: enable these two args once we have OpenSUSE images in registry.opensuse.org for k8s
@@ -115,7 +121,6 @@ func toInitConfig(kubicCfg *config.KubicInitConfiguration, featureGates map[stri
 
 	if len(kubicCfg.ClusterFormation.Token) > 0 {
 		glog.V(8).Infof("[kubic] adding a bootstrap token: %s", kubicCfg.ClusterFormation.Token)
-		var err error
 		bto := kubeadmapiv1beta1.BootstrapToken{}
 		bto.Token, err = kubeadmapiv1beta1.NewBootstrapTokenString(kubicCfg.ClusterFormation.Token)
 		if err != nil {
@@ -134,6 +139,11 @@ func toInitConfig(kubicCfg *config.KubicInitConfiguration, featureGates map[stri
 		initCfg.APIServer.CertSANs = append(initCfg.APIServer.CertSANs, kubicCfg.Network.Dns.ExternalFqdn)
 	}
 
+	if kubicCfg.HasLoadBalancer() {
+		glog.V(3).Infof("[kubic] using the load balancer at %s", kubicCfg.GetControlPlaneEndpoint())
+		initCfg.APIServer.CertSANs = append(initCfg.APIServer.CertSANs, kubicCfg.Network.LoadBalancer.VirtualIP)
+	}
+
 	glog.V(3).Infof("[kubic] using container engine '%s'", kubicCfg.Runtime.Engine)
 	if socket, ok := config.DefaultCriSocket[kubicCfg.Runtime.Engine]; ok {
 		glog.V(3).Infof("[kubic] setting CRI socket '%s'", socket)
@@ -155,5 +165,13 @@ func toInitConfig(kubicCfg *config.KubicInitConfiguration, featureGates map[stri
 	}
 	allFiles = append(allFiles, clusterbytes)
 
+	proxybytes, err := getKubeProxyConfig(kubicCfg, initCfg.KubernetesVersion)
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(proxybytes) > 0 {
+		allFiles = append(allFiles, proxybytes)
+	}
+
 	return bytes.Join(allFiles, []byte(kubeadmconstants.YAMLDocumentSeparator)), nil
 }
@@ -32,7 +32,7 @@ import (
 func NewJoin(kubicCfg *config.KubicInitConfiguration, args ...string) error {
 
 	args = append(args,
-		getIgnorePreflightArg(),
+		getIgnorePreflightArg(kubicCfg),
 		getVerboseArg())
 
 	return kubeadmCmd("join", kubicCfg, toJoinConfig, args...)
@@ -43,7 +43,7 @@ func toJoinConfig(kubicCfg *config.KubicInitConfiguration, featureGates map[stri
 
 	nodeCfg := &kubeadmapiv1beta1.JoinConfiguration{
 		NodeRegistration: kubeadmapiv1beta1.NodeRegistrationOptions{
-			KubeletExtraArgs: config.DefaultKubeletSettings,
+			KubeletExtraArgs: getKubeletExtraArgs(),
 		},
 		Discovery: kubeadmapiv1beta1.Discovery{
 			BootstrapToken: &kubeadmapiv1beta1.BootstrapTokenDiscovery{
@@ -53,13 +53,51 @@ func toJoinConfig(kubicCfg *config.KubicInitConfiguration, featureGates map[stri
 		},
 	}
 
-	// Disable the ca.crt verification if no hash has been provided
-	// TODO: users should be able to provide some other methods, like a ca.crt, etc
-	if len(kubicCfg.Certificates.CaHash) == 0 {
+	// Verify the identity of the seeder with the CA pins (explicit hashes or
+	// hashes of a trusted ca.crt), or disable the verification if none has been provided
+	caHashes, err := kubicCfg.GetCACertHashes()
+	if err != nil {
+		return nil, err
+	}
+	if len(caHashes) > 0 {
+		nodeCfg.Discovery.BootstrapToken.CACertHashes = caHashes
+	} else if kubicCfg.Certificates.RequireVerification {
+		return nil, fmt.Errorf("the identity of the seeder cannot be verified: no CA certificate hashes (or CA certificate) provided")
+	} else {
 		glog.V(1).Infoln("WARNING: we will not verify the identity of the seeder")
 		nodeCfg.Discovery.BootstrapToken.UnsafeSkipCAVerification = true
 	}
 
+	setKubeletNetworking(kubicCfg, getKubernetesVersion(kubicCfg), &nodeCfg.NodeRegistration)
+
+	advertiseAddress, err := getAdvertiseAddress(kubicCfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(advertiseAddress) > 0 {
+		glog.V(8).Infof("[kubic] setting node IP: %s", advertiseAddress)
+		nodeCfg.NodeRegistration.KubeletExtraArgs["node-ip"] = advertiseAddress
+	}
+
+	if kubicCfg.IsMaster() {
+		// the API server in this node must advertise an address other masters
+		// (and the etcd members) can reach, so we cannot leave it empty
+		if len(advertiseAddress) == 0 {
+			bindIP, err := kubicCfg.GetBindIP()
+			if err != nil {
+				return nil, fmt.Errorf("could not get the advertise address: %v", err)
+			}
+			advertiseAddress = bindIP.String()
+		}
+		glog.V(3).Infof("[kubic] joining as a master, advertising %s", advertiseAddress)
+		nodeCfg.ControlPlane = &kubeadmapiv1beta1.JoinControlPlane{
+			LocalAPIEndpoint: kubeadmapiv1beta1.APIEndpoint{
+				AdvertiseAddress: advertiseAddress,
+				BindPort:         config.DefaultAPIServerPort,
+			},
+		}
+	}
+
 	glog.V(3).Infof("[kubic] using container engine '%s'", kubicCfg.Runtime.Engine)
 	if socket, ok := config.DefaultCriSocket[kubicCfg.Runtime.Engine]; ok {
 		glog.V(3).Infof("[kubic] setting CRI socket '%s'", socket)
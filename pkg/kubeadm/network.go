@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package kubeadm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	kubeadmapiv1beta1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta1"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+// the feature gate that must be enabled in all the components for dual-stack clusters
+const dualStackFeatureGate = "IPv6DualStack"
+
+// isDualStack returns true when the cluster must be configured in dual-stack mode
+func isDualStack(kubicCfg *config.KubicInitConfiguration, kubernetesVersion string) bool {
+	return kubicCfg.Network.IsDualStack() && config.SupportsDualStack(kubernetesVersion)
+}
+
+// setNetworking sets the subnets (and the dual-stack settings) in the cluster configuration
+func setNetworking(kubicCfg *config.KubicInitConfiguration, clusterCfg *kubeadmapiv1beta1.ClusterConfiguration) error {
+	if kubicCfg.Network.IsDualStack() && !config.SupportsDualStack(clusterCfg.KubernetesVersion) {
+		return fmt.Errorf("dual-stack clusters need Kubernetes %s or later (deploying %s)",
+			config.DualStackMinKubernetesVersion, clusterCfg.KubernetesVersion)
+	}
+
+	clusterCfg.Networking.PodSubnet = strings.Join(kubicCfg.Network.PodSubnets, ",")
+	clusterCfg.Networking.ServiceSubnet = strings.Join(kubicCfg.Network.ServiceSubnets, ",")
+
+	if clusterCfg.APIServer.ExtraArgs == nil {
+		clusterCfg.APIServer.ExtraArgs = map[string]string{}
+	}
+	if clusterCfg.ControllerManager.ExtraArgs == nil {
+		clusterCfg.ControllerManager.ExtraArgs = map[string]string{}
+	}
+
+	switch {
+	case isDualStack(kubicCfg, clusterCfg.KubernetesVersion):
+		addFeatureGate(clusterCfg.APIServer.ExtraArgs, dualStackFeatureGate)
+		addFeatureGate(clusterCfg.ControllerManager.ExtraArgs, dualStackFeatureGate)
+		clusterCfg.ControllerManager.ExtraArgs["node-cidr-mask-size-ipv4"] = fmt.Sprintf("%d", config.DefaultNodeCIDRMaskSizeIPv4)
+		clusterCfg.ControllerManager.ExtraArgs["node-cidr-mask-size-ipv6"] = fmt.Sprintf("%d", config.DefaultNodeCIDRMaskSizeIPv6)
+
+	case len(kubicCfg.Network.GetPodSubnet(kubicutil.FamilyIPv6)) > 0:
+		clusterCfg.ControllerManager.ExtraArgs["node-cidr-mask-size"] = fmt.Sprintf("%d", config.DefaultNodeCIDRMaskSizeIPv6)
+	}
+
+	return nil
+}
+
+// setKubeletNetworking sets the kubelet args needed for the cluster networking
+func setKubeletNetworking(kubicCfg *config.KubicInitConfiguration, kubernetesVersion string, nodeReg *kubeadmapiv1beta1.NodeRegistrationOptions) {
+	if isDualStack(kubicCfg, kubernetesVersion) {
+		addFeatureGate(nodeReg.KubeletExtraArgs, dualStackFeatureGate)
+	}
+}
+
+// getKubeProxyConfig returns a KubeProxyConfiguration document for dual-stack
+// clusters (kube-proxy only supports dual-stack in IPVS mode), or nil when the
+// kubeadm defaults can be used
+func getKubeProxyConfig(kubicCfg *config.KubicInitConfiguration, kubernetesVersion string) ([]byte, error) {
+	if !isDualStack(kubicCfg, kubernetesVersion) {
+		return nil, nil
+	}
+
+	proxyCfg := map[string]interface{}{
+		"apiVersion":  "kubeproxy.config.k8s.io/v1alpha1",
+		"kind":        "KubeProxyConfiguration",
+		"mode":        "ipvs",
+		"clusterCIDR": strings.Join(kubicCfg.Network.PodSubnets, ","),
+		"featureGates": map[string]bool{
+			dualStackFeatureGate: true,
+		},
+	}
+	return yaml.Marshal(proxyCfg)
+}
+
+// addFeatureGate enables a feature gate in the "feature-gates" arg of a component
+func addFeatureGate(args map[string]string, gate string) {
+	value := gate + "=true"
+	if current, ok := args["feature-gates"]; ok && len(current) > 0 {
+		value = current + "," + value
+	}
+	args["feature-gates"] = value
+}
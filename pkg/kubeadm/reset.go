@@ -30,7 +30,7 @@ func NewReset(kubicCfg *config.KubicInitConfiguration, args ...string) error {
 		"--cri-socket=" + criSocket,
 		"--cert-dir=" + pkiDir,
 		"--force",
-		getIgnorePreflightArg(),
+		getIgnorePreflightArg(kubicCfg),
 		getVerboseArg(),
 	}, args...)
 
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package kubeadm
+
+import (
+	"io"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// the first kubeadm version where "kubeadm upgrade node" upgrades both the workers and
+// the control-plane nodes (kubeadm 1.13 has a different subcommand for each role)
+var upgradeNodeUnifiedVersion = utilversion.MustParseSemantic("v1.14.0")
+
+// UpgradePlan prints (to `out`) the versions the control plane can be upgraded to
+// (checking an upgrade to `version` when provided)
+func UpgradePlan(kubicCfg *config.KubicInitConfiguration, version string, out io.Writer) error {
+	args := []string{"plan"}
+	if len(version) > 0 {
+		args = append(args, version)
+	}
+	args = append(args, getVerboseArg())
+
+	return kubeadmCmdWithOutput("upgrade", kubicCfg, nil, out, args...)
+}
+
+// UpgradeApply upgrades the control plane in this node to `version`
+func UpgradeApply(kubicCfg *config.KubicInitConfiguration, version string, args ...string) error {
+	args = append([]string{"apply", version,
+		"--yes",
+		getIgnorePreflightArg(kubicCfg),
+		getVerboseArg(),
+	}, args...)
+
+	return kubeadmCmd("upgrade", kubicCfg, nil, args...)
+}
+
+// UpgradeNode upgrades the kubelet configuration (and the control plane, in
+// additional masters) in this node to `version`
+func UpgradeNode(kubicCfg *config.KubicInitConfiguration, version string, controlPlane bool, args ...string) error {
+	kubeadmVersion, err := GetVersion(kubicCfg)
+	if err != nil {
+		return err
+	}
+
+	for _, upgradeArgs := range upgradeNodeArgs(kubeadmVersion, version, controlPlane) {
+		if err := kubeadmCmd("upgrade", kubicCfg, nil, append(upgradeArgs, args...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgradeNodeArgs returns the arguments for the "kubeadm upgrade" commands that upgrade
+// a node to `version`, depending on the kubeadm version and on the role of the node
+func upgradeNodeArgs(kubeadmVersion *utilversion.Version, version string, controlPlane bool) [][]string {
+	if kubeadmVersion.AtLeast(upgradeNodeUnifiedVersion) {
+		return [][]string{{"node", "--kubelet-version=" + version, getVerboseArg()}}
+	}
+
+	// the control plane must be upgraded before the kubelet configuration
+	res := [][]string{}
+	if controlPlane {
+		res = append(res, []string{"node", "experimental-control-plane", getVerboseArg()})
+	}
+	return append(res, []string{"node", "config", "--kubelet-version=" + version, getVerboseArg()})
+}
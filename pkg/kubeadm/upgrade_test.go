@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package kubeadm
+
+import (
+	"reflect"
+	"testing"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestUpgradeNodeArgs(t *testing.T) {
+	tests := []struct {
+		kubeadmVersion string
+		controlPlane   bool
+		expected       [][]string
+	}{
+		{"v1.13.1", false, [][]string{
+			{"node", "config", "--kubelet-version=v1.13.1", getVerboseArg()},
+		}},
+		{"v1.13.1", true, [][]string{
+			{"node", "experimental-control-plane", getVerboseArg()},
+			{"node", "config", "--kubelet-version=v1.13.1", getVerboseArg()},
+		}},
+		{"v1.14.0-beta.1", true, [][]string{
+			{"node", "experimental-control-plane", getVerboseArg()},
+			{"node", "config", "--kubelet-version=v1.13.1", getVerboseArg()},
+		}},
+		{"v1.14.0", false, [][]string{
+			{"node", "--kubelet-version=v1.13.1", getVerboseArg()},
+		}},
+		{"v1.14.0", true, [][]string{
+			{"node", "--kubelet-version=v1.13.1", getVerboseArg()},
+		}},
+	}
+
+	for _, test := range tests {
+		args := upgradeNodeArgs(utilversion.MustParseSemantic(test.kubeadmVersion), "v1.13.1", test.controlPlane)
+		if !reflect.DeepEqual(args, test.expected) {
+			t.Fatalf("unexpected args with kubeadm %s (control plane: %v): %q", test.kubeadmVersion, test.controlPlane, args)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package kubeadm
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/golang/glog"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// GetVersion returns the version of the kubeadm installed in this node
+func GetVersion(kubicCfg *config.KubicInitConfiguration) (*utilversion.Version, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(kubicCfg.Paths.Kubeadm, "version", "-o", "short")
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the kubeadm version: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	version, err := utilversion.ParseSemantic(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the kubeadm version: %v", err)
+	}
+	return version, nil
+}
+
+// getKubernetesVersion returns the Kubernetes version to deploy in this node: the
+// version of the kubeadm installed, or config.DefaultKubernetesVersion when it
+// cannot be obtained
+func getKubernetesVersion(kubicCfg *config.KubicInitConfiguration) string {
+	version, err := GetVersion(kubicCfg)
+	if err != nil {
+		glog.Warningf("[kubic] %v: assuming Kubernetes %s", err, config.DefaultKubernetesVersion)
+		return config.DefaultKubernetesVersion
+	}
+
+	// the build metadata is not valid in the images tags
+	res := fmt.Sprintf("v%d.%d.%d", version.Major(), version.Minor(), version.Patch())
+	if len(version.PreRelease()) > 0 {
+		res += "-" + version.PreRelease()
+	}
+	return res
+}
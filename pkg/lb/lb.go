@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package lb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/golang/glog"
+	clientset "k8s.io/client-go/kubernetes"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+
+	kubiccluster "github.com/kubic-project/kubic-init/pkg/cluster"
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+	"github.com/kubic-project/kubic-init/pkg/util"
+)
+
+const (
+	// ManifestName is the name of the static pod manifest for the load balancer
+	ManifestName = "kubic-apiserver-lb.yaml"
+
+	keepalivedConfName = "keepalived.conf"
+	haproxyConfName    = "haproxy.cfg"
+	checkHAProxyName   = "check-haproxy.sh"
+)
+
+func init() {
+	// remove the load balancer configuration and the virtual IP when resetting a node
+	reset.Register(reset.Step{
+		Name:        "load-balancer",
+		Description: "remove the load balancer configuration and the virtual IP",
+		Enabled: func(cfg *config.KubicInitConfiguration) bool {
+			return len(cfg.Network.LoadBalancer.VirtualIP) > 0
+		},
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			return []string{config.DefaultLoadBalancerConfDir}
+		},
+		Run: removeVirtualIP,
+	})
+}
+
+// removeVirtualIP removes the virtual IP from the interface (keepalived does not
+// remove it when killed)
+func removeVirtualIP(cfg *config.KubicInitConfiguration) error {
+	lb := cfg.Network.LoadBalancer
+	iface, err := net.InterfaceByName(lb.Interface)
+	if err != nil {
+		glog.V(3).Infof("[kubic] interface %s not found: %v", lb.Interface, err)
+		return nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(net.ParseIP(lb.VirtualIP)) {
+			return util.RunCommand("ip", "addr", "del", ipNet.String(), "dev", lb.Interface)
+		}
+	}
+	return nil
+}
+
+// APIServerBackend returns the backend for the API server running at `address`
+func APIServerBackend(address string) string {
+	return net.JoinHostPort(address, strconv.Itoa(config.DefaultAPIServerPort))
+}
+
+// Render returns the files needed for running the load balancer in a master,
+// indexed by their paths, forwarding the connections to the `backends`
+func Render(cfg *config.KubicInitConfiguration, backends []string) (map[string][]byte, error) {
+	lb := cfg.Network.LoadBalancer
+
+	backends = util.RemoveDuplicates(backends)
+	sort.Strings(backends)
+
+	// keepalived runs the check with the configuration directory mounted at /etc/kubic/lb
+	keepalivedConf, err := kubeadmutil.ParseTemplate(KeepalivedConf, struct {
+		VirtualIP   string
+		Interface   string
+		RouterID    int
+		CheckScript string
+	}{
+		lb.VirtualIP,
+		lb.Interface,
+		lb.RouterID,
+		filepath.Join("/etc/kubic/lb", checkHAProxyName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing keepalived configuration template: %v", err)
+	}
+
+	checkScript, err := kubeadmutil.ParseTemplate(CheckHAProxyScript, struct {
+		Port int
+	}{
+		lb.Port,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing haproxy check script template: %v", err)
+	}
+
+	haproxyConf, err := kubeadmutil.ParseTemplate(HAProxyConf, struct {
+		Port     int
+		Backends []string
+	}{
+		lb.Port,
+		backends,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing haproxy configuration template: %v", err)
+	}
+
+	hash := sha256.Sum256(bytes.Join([][]byte{keepalivedConf, checkScript, haproxyConf}, nil))
+	pod, err := kubeadmutil.ParseTemplate(LoadBalancerPod, struct {
+		Image      string
+		ProxyImage string
+		ConfDir    string
+		ConfigHash string
+	}{
+		lb.Image,
+		lb.ProxyImage,
+		config.DefaultLoadBalancerConfDir,
+		fmt.Sprintf("%x", hash[:8]),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing load balancer pod template: %v", err)
+	}
+
+	return map[string][]byte{
+		filepath.Join(config.DefaultLoadBalancerConfDir, keepalivedConfName):  keepalivedConf,
+		filepath.Join(config.DefaultLoadBalancerConfDir, checkHAProxyName):    checkScript,
+		filepath.Join(config.DefaultLoadBalancerConfDir, haproxyConfName):     haproxyConf,
+		filepath.Join(kubeadmconstants.GetStaticPodDirectory(), ManifestName): pod,
+	}, nil
+}
+
+// Sync writes the files for the load balancer (only the files that have changed),
+// returning true when something has been changed
+func Sync(cfg *config.KubicInitConfiguration, backends []string) (bool, error) {
+	files, err := Render(cfg, backends)
+	if err != nil {
+		return false, err
+	}
+
+	// write the static pod last, so the kubelet finds the configuration files
+	manifest := filepath.Join(kubeadmconstants.GetStaticPodDirectory(), ManifestName)
+	paths := []string{}
+	for path := range files {
+		if path != manifest {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	paths = append(paths, manifest)
+
+	changed := false
+	for _, path := range paths {
+		current, err := ioutil.ReadFile(path)
+		if err == nil && bytes.Equal(current, files[path]) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return changed, err
+		}
+		if err := ioutil.WriteFile(path, files[path], 0644); err != nil {
+			return changed, fmt.Errorf("could not write %s: %v", path, err)
+		}
+		glog.V(3).Infof("[kubic] load balancer: %s updated", path)
+		changed = true
+	}
+	return changed, nil
+}
+
+// Reconcile keeps the load balancer backends in sync with the control-plane nodes
+// registered in the cluster
+func Reconcile(cfg *config.KubicInitConfiguration, client clientset.Interface) error {
+	addresses, err := kubiccluster.GetControlPlaneAddresses(client)
+	if err != nil {
+		return fmt.Errorf("could not get the control-plane nodes: %v", err)
+	}
+	if len(addresses) == 0 {
+		// this should not happen, as we are running in a master...
+		glog.V(1).Infof("[kubic] WARNING: no control-plane nodes found: keeping the current load balancer backends")
+		return nil
+	}
+
+	backends := []string{}
+	for _, address := range addresses {
+		backends = append(backends, APIServerBackend(address))
+	}
+
+	changed, err := Sync(cfg, backends)
+	if err != nil {
+		return err
+	}
+	if changed {
+		glog.V(1).Infof("[kubic] load balancer backends updated: %v", backends)
+	}
+	return nil
+}
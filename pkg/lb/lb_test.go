@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package lb
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+func TestRender(t *testing.T) {
+	cfg, err := config.BytesToKubicInitConfig([]byte{}, false)
+	if err != nil {
+		t.Fatalf("could not get a default configuration: %v", err)
+	}
+	cfg.Network.LoadBalancer.VirtualIP = "10.0.0.100"
+	cfg.Network.LoadBalancer.Interface = "eth0"
+
+	manifest := filepath.Join(kubeadmconstants.GetStaticPodDirectory(), ManifestName)
+	haproxy := filepath.Join(config.DefaultLoadBalancerConfDir, haproxyConfName)
+
+	files1, err := Render(cfg, []string{"10.0.0.2:6443", "10.0.0.1:6443", "10.0.0.2:6443"})
+	if err != nil {
+		t.Fatalf("could not render the load balancer: %v", err)
+	}
+	t.Logf("haproxy configuration:\n%s", files1[haproxy])
+	if strings.Count(string(files1[haproxy]), " check ") != 2 {
+		t.Fatalf("duplicate backends were not removed")
+	}
+
+	// keepalived must give up the virtual IP when haproxy is down
+	keepalived := string(files1[filepath.Join(config.DefaultLoadBalancerConfDir, keepalivedConfName)])
+	if !strings.Contains(keepalived, "track_script") || !strings.Contains(keepalived, checkHAProxyName) {
+		t.Fatalf("haproxy is not tracked by keepalived:\n%s", keepalived)
+	}
+	check := string(files1[filepath.Join(config.DefaultLoadBalancerConfDir, checkHAProxyName)])
+	if !strings.Contains(check, fmt.Sprintf("/dev/tcp/127.0.0.1/%d", cfg.Network.LoadBalancer.Port)) {
+		t.Fatalf("unexpected haproxy check script:\n%s", check)
+	}
+
+	files2, err := Render(cfg, []string{"10.0.0.1:6443", "10.0.0.2:6443", "10.0.0.3:6443"})
+	if err != nil {
+		t.Fatalf("could not render the load balancer: %v", err)
+	}
+	if string(files1[manifest]) == string(files2[manifest]) {
+		t.Fatalf("the static pod did not change when the backends changed")
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package lb
+
+const (
+	// KeepalivedConf is the configuration for keepalived, that moves the virtual IP between masters
+	KeepalivedConf = `# generated by kubic-init: do not edit
+global_defs {
+  router_id kubic_{{ .RouterID }}
+  enable_script_security
+  script_user root
+}
+
+# give up the virtual IP when haproxy does not accept connections
+vrrp_script kubic_haproxy {
+  script "/bin/bash {{ .CheckScript }}"
+  interval 2
+  timeout 2
+  fall 3
+  rise 2
+}
+
+vrrp_instance kubic_apiserver {
+  state BACKUP
+  interface {{ .Interface }}
+  virtual_router_id {{ .RouterID }}
+  priority 100
+  advert_int 1
+  nopreempt
+  virtual_ipaddress {
+    {{ .VirtualIP }}
+  }
+  track_script {
+    kubic_haproxy
+  }
+}
+`
+
+	// CheckHAProxyScript is the script used by keepalived for checking haproxy is
+	// accepting connections in the load balancer port
+	CheckHAProxyScript = `#!/bin/bash
+# generated by kubic-init: do not edit
+exec 3<>/dev/tcp/127.0.0.1/{{ .Port }}
+`
+
+	// HAProxyConf is the configuration for haproxy, that forwards the connections
+	// to the virtual IP to all the API servers
+	HAProxyConf = `# generated by kubic-init: do not edit
+global
+  log /dev/log local0 info
+  maxconn 4096
+
+defaults
+  mode tcp
+  log global
+  option tcplog
+  timeout connect 5s
+  timeout client 1h
+  timeout server 1h
+
+frontend kubic-apiserver
+  bind :::{{ .Port }} v4v6
+  default_backend kubic-apiservers
+
+backend kubic-apiservers
+  balance roundrobin
+  option httpchk GET /healthz
+  http-check expect status 200
+{{- range $i, $backend := .Backends }}
+  server apiserver-{{ $i }} {{ $backend }} check check-ssl verify none inter 3s fall 3 rise 2
+{{- end }}
+`
+
+	// LoadBalancerPod is the static pod with keepalived and haproxy
+	LoadBalancerPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: kubic-apiserver-lb
+  namespace: kube-system
+  labels:
+    tier: control-plane
+    component: kubic-apiserver-lb
+  annotations:
+    # changes in the configuration files must restart the pod
+    kubic.suse.com/config-hash: "{{ .ConfigHash }}"
+spec:
+  hostNetwork: true
+  priorityClassName: system-cluster-critical
+  containers:
+  - name: keepalived
+    image: {{ .Image }}
+    command:
+    - keepalived
+    - --dont-fork
+    - --log-console
+    - --use-file=/etc/kubic/lb/keepalived.conf
+    securityContext:
+      capabilities:
+        add:
+        - NET_ADMIN
+        - NET_BROADCAST
+        - NET_RAW
+    volumeMounts:
+    - name: config
+      mountPath: /etc/kubic/lb
+      readOnly: true
+  - name: haproxy
+    image: {{ .ProxyImage }}
+    command:
+    - haproxy
+    - -f
+    - /etc/kubic/lb/haproxy.cfg
+    - -db
+    volumeMounts:
+    - name: config
+      mountPath: /etc/kubic/lb
+      readOnly: true
+    - name: log
+      mountPath: /dev/log
+  volumes:
+  - name: config
+    hostPath:
+      path: {{ .ConfDir }}
+      type: Directory
+  - name: log
+    hostPath:
+      path: /dev/log
+      type: Socket
+`
+)
@@ -34,19 +34,21 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
 
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
 	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
 	"github.com/kubic-project/kubic-init/pkg/util"
 )
 
-type crdsSet map[string]*apiextensionsv1beta1.CustomResourceDefinition
+// CRDsSet is a set of CRDs, indexed by name
+type CRDsSet map[string]*apiextensionsv1beta1.CustomResourceDefinition
 
 // CRDInstallOptions are the options for installing CRDs
 type CRDInstallOptions struct {
 	// Paths is the path to the directory containing CRDs
 	Paths []string
 
-	// CRDs is a map of CRDs to install
-	CRDs crdsSet
+	// CRDs is a map of CRDs to install (the CRDs found in Paths will be added)
+	CRDs CRDsSet
 
 	// ErrorIfPathMissing will cause an error if a Path does not exist
 	ErrorIfPathMissing bool
@@ -62,7 +64,7 @@ const defaultPollInterval = 100 * time.Millisecond
 const defaultMaxWait = 10 * time.Second
 
 // InstallCRDs installs a collection of CRDs into a cluster by reading the crd yaml files from a directory
-func InstallCRDs(kubicCfg *kubiccfg.KubicInitConfiguration, restCfg *rest.Config, options CRDInstallOptions) error {
+func InstallCRDs(kubicCfg *kubiccfg.KubicInitConfiguration, clients *kubicclient.Clients, options CRDInstallOptions) error {
 	defaultCRDOptions(&options)
 
 	// Read the CRD yamls into options.CRDs
@@ -71,12 +73,15 @@ func InstallCRDs(kubicCfg *kubiccfg.KubicInitConfiguration, restCfg *rest.Config
 	}
 
 	// Create the CRDs in the apiserver
-	if err := CreateCRDs(restCfg, options.CRDs); err != nil {
+	if err := CreateCRDs(clients.APIExtensions, options.CRDs); err != nil {
 		return err
 	}
 
 	// Wait for the CRDs to appear as Resources in the apiserver
-	if err := WaitForCRDs(restCfg, options.CRDs, options); err != nil {
+	if clients.DryRun {
+		return nil
+	}
+	if err := WaitForCRDs(clients.Config, options.CRDs, options); err != nil {
 		return err
 	}
 
@@ -85,7 +90,9 @@ func InstallCRDs(kubicCfg *kubiccfg.KubicInitConfiguration, restCfg *rest.Config
 
 // readCRDFiles reads the directories of CRDs in options.Paths and adds the CRD structs to options.CRDs
 func readCRDFiles(options *CRDInstallOptions) error {
-	options.CRDs = crdsSet{}
+	if options.CRDs == nil {
+		options.CRDs = CRDsSet{}
+	}
 	if len(options.Paths) > 0 {
 		for _, path := range util.RemoveDuplicates(options.Paths) {
 			if _, err := os.Stat(path); !options.ErrorIfPathMissing && os.IsNotExist(err) {
@@ -114,7 +121,7 @@ func defaultCRDOptions(o *CRDInstallOptions) {
 }
 
 // WaitForCRDs waits for the CRDs to appear in discovery
-func WaitForCRDs(config *rest.Config, crds crdsSet, options CRDInstallOptions) error {
+func WaitForCRDs(config *rest.Config, crds CRDsSet, options CRDInstallOptions) error {
 	// Add each CRD to a map of GroupVersion to Resource
 	waitingFor := map[schema.GroupVersion]*sets.String{}
 	for _, crd := range crds {
@@ -178,12 +185,7 @@ func (p *poller) poll() (done bool, err error) {
 }
 
 // CreateCRDs creates the CRDs
-func CreateCRDs(restCfg *rest.Config, crds crdsSet) error {
-	cs, err := clientset.NewForConfig(restCfg)
-	if err != nil {
-		return err
-	}
-
+func CreateCRDs(cs clientset.Interface, crds CRDsSet) error {
 	// Create each CRD
 	for name, crd := range crds {
 		glog.V(5).Infof("[kubic] creating CRD '%s'", name)
@@ -212,6 +214,15 @@ func CreateCRDs(restCfg *rest.Config, crds crdsSet) error {
 	return nil
 }
 
+// NewCRDsSet creates a set of CRDs that can be used in the CRDInstallOptions
+func NewCRDsSet(crds ...*apiextensionsv1beta1.CustomResourceDefinition) CRDsSet {
+	res := CRDsSet{}
+	for _, crd := range crds {
+		res[util.NamespacedObjToString(crd)] = crd
+	}
+	return res
+}
+
 // readCRDs reads the CRDs from files and Unmarshals them into structs
 func readCRDs(path string) ([]*apiextensionsv1beta1.CustomResourceDefinition, error) {
 	// Get the CRD files
@@ -24,8 +24,8 @@ import (
 	"path/filepath"
 
 	"github.com/golang/glog"
-	"k8s.io/client-go/rest"
 
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
 	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
 )
 
@@ -52,7 +52,7 @@ func loadFilesIn(directory string, glob string, descr string) ([]*bytes.Buffer,
 }
 
 // InstallAllAssets tries to install all the assets: CRDs and RBACs
-func InstallAllAssets(restCfg *rest.Config, kubicCfg *kubiccfg.KubicInitConfiguration, manifDir, crdsDir, rbacDir string) error {
+func InstallAllAssets(clients *kubicclient.Clients, kubicCfg *kubiccfg.KubicInitConfiguration, manifDir, crdsDir, rbacDir string) error {
 	dirs := []string{}
 
 	glog.V(1).Infof("[kubic] installing all the assets...")
@@ -62,7 +62,7 @@ func InstallAllAssets(restCfg *rest.Config, kubicCfg *kubiccfg.KubicInitConfigur
 	}
 	dirs = append(kubiccfg.DefaultRBACDirs, rbacDir)
 	glog.V(1).Infof("[kubic] looking for RBACs in %v", dirs)
-	if err := InstallRBAC(kubicCfg, restCfg, RBACInstallOptions{Paths: dirs}); err != nil {
+	if err := InstallRBAC(kubicCfg, clients, RBACInstallOptions{Paths: dirs}); err != nil {
 		return err
 	}
 
@@ -71,7 +71,7 @@ func InstallAllAssets(restCfg *rest.Config, kubicCfg *kubiccfg.KubicInitConfigur
 	}
 	dirs = append(kubiccfg.DefaultCRDsDirs, crdsDir)
 	glog.V(1).Infof("[kubic] looking for CRDs in %v", dirs)
-	if err := InstallCRDs(kubicCfg, restCfg, CRDInstallOptions{Paths: dirs}); err != nil {
+	if err := InstallCRDs(kubicCfg, clients, CRDInstallOptions{Paths: dirs}); err != nil {
 		return err
 	}
 
@@ -80,7 +80,7 @@ func InstallAllAssets(restCfg *rest.Config, kubicCfg *kubiccfg.KubicInitConfigur
 	}
 	dirs = append(kubiccfg.DefaultManifestsDirs, manifDir)
 	glog.V(1).Infof("[kubic] looking for manifests in %v", dirs)
-	if err := InstallManifests(kubicCfg, restCfg, ManifestsInstallOptions{Paths: dirs}); err != nil {
+	if err := InstallManifests(kubicCfg, clients, ManifestsInstallOptions{Paths: dirs}); err != nil {
 		return err
 	}
 
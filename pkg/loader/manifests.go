@@ -27,7 +27,6 @@ import (
 	"github.com/golang/glog"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/yaml"
-	"k8s.io/client-go/rest"
 
 	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
 	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
@@ -129,7 +128,7 @@ func getUnstructuredFromURL(kubicCfg *kubiccfg.KubicInitConfiguration, url strin
 
 // InstallManifests installs all the manifests found in the manifests directory
 // It will do a best-effort job, ignoring errors
-func InstallManifests(kubicCfg *kubiccfg.KubicInitConfiguration, config *rest.Config, options ManifestsInstallOptions) error {
+func InstallManifests(kubicCfg *kubiccfg.KubicInitConfiguration, clients *kubicclient.Clients, options ManifestsInstallOptions) error {
 	for _, path := range util.RemoveDuplicates(options.Paths) {
 		if _, err := os.Stat(path); !options.ErrorIfPathMissing && os.IsNotExist(err) {
 			continue
@@ -142,7 +141,7 @@ func InstallManifests(kubicCfg *kubiccfg.KubicInitConfiguration, config *rest.Co
 		}
 		for _, fileBuffer := range filesBuffers {
 			for _, unstr := range getUnstructuredInYAMLFile(kubicCfg, fileBuffer.String()) {
-				err = kubicclient.CreateOrUpdateFromUnstructured(config, unstr)
+				err = kubicclient.CreateOrUpdateFromUnstructured(clients, unstr)
 				if err != nil {
 					glog.V(3).Infof("[kubic] ERROR: could not load manifest: ignored")
 				}
@@ -156,7 +155,7 @@ func InstallManifests(kubicCfg *kubiccfg.KubicInitConfiguration, config *rest.Co
 		}
 		for _, urlBuffer := range urlsBuffers {
 			for _, unstr := range getUnstructuredFromURL(kubicCfg, urlBuffer.String()) {
-				err = kubicclient.CreateOrUpdateFromUnstructured(config, unstr)
+				err = kubicclient.CreateOrUpdateFromUnstructured(clients, unstr)
 				if err != nil {
 					glog.V(3).Infof("[kubic] ERROR: could not load manifest: ignored")
 				}
@@ -24,9 +24,7 @@ import (
 	rbac "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kuberuntime "k8s.io/apimachinery/pkg/runtime"
-	clientset "k8s.io/client-go/kubernetes"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
 
 	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
 	kubiccfg "github.com/kubic-project/kubic-init/pkg/config"
@@ -54,13 +52,8 @@ type RBACInstallOptions struct {
 }
 
 // necessary until https://github.com/kubernetes-sigs/controller-tools/pull/77 is merged
-func InstallRBAC(kubicCfg *kubiccfg.KubicInitConfiguration, config *rest.Config, options RBACInstallOptions) error {
-
-	cs, err := clientset.NewForConfig(config)
-	if err != nil {
-		return err
-	}
-	restClient := cs.RESTClient()
+func InstallRBAC(kubicCfg *kubiccfg.KubicInitConfiguration, clients *kubicclient.Clients, options RBACInstallOptions) error {
+	cs := clients.Kubernetes
 
 	for _, path := range kubicutil.RemoveDuplicates(options.Paths) {
 		if _, err := os.Stat(path); !options.ErrorIfPathMissing && os.IsNotExist(err) {
@@ -82,7 +75,7 @@ func InstallRBAC(kubicCfg *kubiccfg.KubicInitConfiguration, config *rest.Config,
 			if err = apiclient.CreateOrUpdateClusterRole(cs, role); err != nil {
 				return fmt.Errorf("Failed to create new Role: %v", err)
 			}
-			if err := kubicclient.WaitForObject(restClient, role); err != nil {
+			if err := waitForObject(clients, role); err != nil {
 				return err
 			}
 		}
@@ -102,7 +95,7 @@ func InstallRBAC(kubicCfg *kubiccfg.KubicInitConfiguration, config *rest.Config,
 			if err = apiclient.CreateOrUpdateClusterRoleBinding(cs, roleBinding); err != nil {
 				return fmt.Errorf("Failed to create new Role bindings: %v", err)
 			}
-			if err := kubicclient.WaitForObject(restClient, roleBinding); err != nil {
+			if err := waitForObject(clients, roleBinding); err != nil {
 				return err
 			}
 		}
@@ -110,3 +103,12 @@ func InstallRBAC(kubicCfg *kubiccfg.KubicInitConfiguration, config *rest.Config,
 
 	return nil
 }
+
+// waitForObject waits for an object to be ready in the apiserver
+// (there is nothing to wait for in dry-run mode)
+func waitForObject(clients *kubicclient.Clients, obj metav1.Common) error {
+	if clients.DryRun {
+		return nil
+	}
+	return kubicclient.WaitForObject(clients.Kubernetes.Discovery().RESTClient(), obj)
+}
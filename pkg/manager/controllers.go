@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package manager
+
+import (
+	"sort"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// A Controller is something that runs in the manager until it is stopped
+type Controller interface {
+	// Name is the name of the controller (used for logging)
+	Name() string
+
+	// Run runs the controller until `stop` is closed
+	Run(stop <-chan struct{}) error
+}
+
+// A ControllerFactory creates a controller
+type ControllerFactory func(*config.KubicInitConfiguration, *kubicclient.Clients) (Controller, error)
+
+type ControllersRegistry map[string]ControllerFactory
+
+func (registry ControllersRegistry) Register(name string, factory ControllerFactory) {
+	registry[name] = factory
+}
+
+// Names returns the (sorted) list of registered controllers
+func (registry ControllersRegistry) Names() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Global Registry
+// Controllers self-register here (in their init()) for being started by the manager
+var Registry = ControllersRegistry{}
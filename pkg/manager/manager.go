@@ -0,0 +1,205 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// A ReconcileFunc is a function that is run periodically for bringing
+// some kubic-owned resources to their desired state
+type ReconcileFunc func() error
+
+// Options are the options for the manager
+type Options struct {
+	// HealthAddress is the address for the health/readiness endpoints
+	HealthAddress string
+
+	// ReconcileInterval is the time between reconciliations
+	ReconcileInterval time.Duration
+}
+
+type reconciler struct {
+	name string
+	f    ReconcileFunc
+}
+
+// Manager is the long-running process that keeps the kubic-owned
+// resources in their desired state once the node has been bootstrapped
+type Manager struct {
+	kubicCfg *config.KubicInitConfiguration
+	clients  *kubicclient.Clients
+	options  Options
+
+	reconcilers []reconciler
+	controllers []Controller
+
+	sync.RWMutex
+	ready bool
+}
+
+// New creates a new manager. `clients` can be nil when this node
+// cannot talk to the API server (ie, in a regular node).
+func New(kubicCfg *config.KubicInitConfiguration, clients *kubicclient.Clients, options Options) *Manager {
+	if len(options.HealthAddress) == 0 {
+		options.HealthAddress = config.DefaultManagerHealthAddress
+	}
+	if options.ReconcileInterval == 0 {
+		options.ReconcileInterval = config.DefaultManagerReconcileInterval
+	}
+
+	return &Manager{
+		kubicCfg: kubicCfg,
+		clients:  clients,
+		options:  options,
+	}
+}
+
+// AddReconciler adds a function that will be run periodically
+func (m *Manager) AddReconciler(name string, f ReconcileFunc) {
+	m.reconcilers = append(m.reconcilers, reconciler{name: name, f: f})
+}
+
+// AddController adds a controller that will be run in the manager
+func (m *Manager) AddController(c Controller) {
+	m.controllers = append(m.controllers, c)
+}
+
+// Run runs the manager until `stop` is closed. It fails when the health endpoints
+// cannot be served (as the probes would consider the manager dead).
+func (m *Manager) Run(stop <-chan struct{}) error {
+	listener, err := net.Listen("tcp", m.options.HealthAddress)
+	if err != nil {
+		return fmt.Errorf("could not listen for the health endpoints at %s: %v", m.options.HealthAddress, err)
+	}
+
+	// instantiate all the controllers in the registry
+	if m.clients != nil {
+		for _, name := range Registry.Names() {
+			c, err := Registry[name](m.kubicCfg, m.clients)
+			if err != nil {
+				listener.Close()
+				return fmt.Errorf("could not create controller %s: %v", name, err)
+			}
+			m.AddController(c)
+		}
+	} else if len(Registry) > 0 {
+		glog.V(1).Infof("[kubic] WARNING: no API server access: controllers will not be started")
+	}
+
+	server := &http.Server{
+		Addr:    m.options.HealthAddress,
+		Handler: m.Handler(),
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		glog.V(1).Infof("[kubic] health endpoints listening at %s", listener.Addr())
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+		}
+	}()
+
+	// the manager is stopped when `stop` is closed or when the health endpoints fail
+	var failure error
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+		case err := <-serveErr:
+			glog.V(1).Infof("[kubic] ERROR: health endpoints failed: %v", err)
+			failure = fmt.Errorf("health endpoints failed: %v", err)
+		}
+		close(done)
+	}()
+
+	wg := sync.WaitGroup{}
+	for _, c := range m.controllers {
+		wg.Add(1)
+		go func(c Controller) {
+			defer wg.Done()
+			glog.V(1).Infof("[kubic] starting controller %s", c.Name())
+			if err := c.Run(done); err != nil {
+				glog.V(1).Infof("[kubic] ERROR: controller %s failed: %v", c.Name(), err)
+			}
+		}(c)
+	}
+
+	glog.V(1).Infof("[kubic] manager running: reconciling every %s", m.options.ReconcileInterval)
+	wait.Until(m.reconcile, m.options.ReconcileInterval, done)
+
+	glog.V(1).Infof("[kubic] shutting down the manager...")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		glog.V(1).Infof("[kubic] WARNING: could not shutdown the health endpoints: %v", err)
+	}
+
+	wg.Wait()
+	glog.V(1).Infof("[kubic] manager stopped")
+	return failure
+}
+
+// reconcile runs all the reconcilers, updating the readiness status
+func (m *Manager) reconcile() {
+	ok := true
+	for _, r := range m.reconcilers {
+		glog.V(3).Infof("[kubic] reconciling %s", r.name)
+		if err := r.f(); err != nil {
+			glog.V(1).Infof("[kubic] ERROR: when reconciling %s: %v", r.name, err)
+			ok = false
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.ready = ok
+}
+
+// IsReady returns true if the last reconciliation was successful
+func (m *Manager) IsReady() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.ready
+}
+
+// Handler returns the handler for the health ("/healthz") and readiness ("/readyz") endpoints
+func (m *Manager) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !m.IsReady() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package manager
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testController struct {
+	stopped chan struct{}
+}
+
+func (c *testController) Name() string { return "test" }
+
+func (c *testController) Run(stop <-chan struct{}) error {
+	<-stop
+	close(c.stopped)
+	return nil
+}
+
+func TestManager(t *testing.T) {
+	failing := true
+	reconciled := make(chan struct{}, 10)
+
+	m := New(nil, nil, Options{HealthAddress: "127.0.0.1:0", ReconcileInterval: 10 * time.Millisecond})
+	m.AddReconciler("test", func() error {
+		defer func() {
+			select {
+			case reconciled <- struct{}{}:
+			default:
+			}
+		}()
+		if failing {
+			return fmt.Errorf("some error")
+		}
+		return nil
+	})
+
+	getStatus := func(path string) int {
+		rec := httptest.NewRecorder()
+		m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	if code := getStatus("/healthz"); code != http.StatusOK {
+		t.Fatalf("unexpected health status: %d", code)
+	}
+
+	m.reconcile()
+	<-reconciled
+	if code := getStatus("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("ready after a failed reconciliation: %d", code)
+	}
+
+	failing = false
+	m.reconcile()
+	<-reconciled
+	if code := getStatus("/readyz"); code != http.StatusOK {
+		t.Fatalf("not ready after a successful reconciliation: %d", code)
+	}
+
+	// run the manager with a controller, and stop it
+	c := &testController{stopped: make(chan struct{})}
+	m.AddController(c)
+
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- m.Run(stop) }()
+
+	<-reconciled
+	close(stop)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("manager failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("manager did not stop")
+	}
+
+	select {
+	case <-c.stopped:
+	default:
+		t.Fatalf("controller was not stopped")
+	}
+}
+
+func TestManagerHealthAddressInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer listener.Close()
+
+	m := New(nil, nil, Options{HealthAddress: listener.Addr().String(), ReconcileInterval: 10 * time.Millisecond})
+	stop := make(chan struct{})
+	defer close(stop)
+
+	done := make(chan error)
+	go func() { done <- m.Run(stop) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("the manager did not fail with the health address in use")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the manager did not fail with the health address in use")
+	}
+}
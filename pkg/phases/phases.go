@@ -0,0 +1,179 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package phases
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// A Phase is a named step in the bootstrap process
+type Phase struct {
+	Name string
+	Run  func() error
+}
+
+// State is the progress of the bootstrap, as persisted in the state file
+type State struct {
+	// Completed is the list of phases completed successfully
+	Completed []string `json:"completed"`
+
+	// LastUpdate is the time of the last update of the state
+	LastUpdate time.Time `json:"lastUpdate"`
+}
+
+// Runner runs a list of phases, persisting the progress in a state file
+// so a failed bootstrap can be resumed from the first incomplete phase
+type Runner struct {
+	phases []Phase
+
+	// StateFile is the file where the progress is saved (no progress
+	// is loaded or saved when empty, ie, in dry-run mode)
+	StateFile string
+
+	// Skip is the list of phases that will not be run
+	Skip []string
+
+	// Only is the list of phases that will be run (all when empty)
+	Only []string
+}
+
+// NewRunner creates a new runner for some phases
+func NewRunner(stateFile string, phases ...Phase) *Runner {
+	return &Runner{
+		phases:    phases,
+		StateFile: stateFile,
+	}
+}
+
+// Names returns the names of the phases, in order
+func (r *Runner) Names() []string {
+	names := []string{}
+	for _, phase := range r.phases {
+		names = append(names, phase.Name)
+	}
+	return names
+}
+
+// Run runs all the phases that have not been completed yet
+func (r *Runner) Run() error {
+	known := sets.NewString(r.Names()...)
+	for _, name := range append(r.Skip, r.Only...) {
+		if !known.Has(name) {
+			return fmt.Errorf("unknown phase %q: must be one of %s", name, strings.Join(r.Names(), ", "))
+		}
+	}
+	skip := sets.NewString(r.Skip...)
+	only := sets.NewString(r.Only...)
+
+	state, err := r.loadState()
+	if err != nil {
+		return err
+	}
+	completed := sets.NewString(state.Completed...)
+
+	for _, phase := range r.phases {
+		switch {
+		case only.Len() > 0 && !only.Has(phase.Name):
+			glog.V(1).Infof("[kubic] phase %s: not in the list of phases to run", phase.Name)
+			continue
+		case skip.Has(phase.Name):
+			glog.V(1).Infof("[kubic] phase %s: skipped", phase.Name)
+			continue
+		case completed.Has(phase.Name) && only.Len() == 0:
+			// note well: phases explicitly requested are run again
+			glog.V(1).Infof("[kubic] phase %s: already completed", phase.Name)
+			continue
+		}
+
+		glog.V(1).Infof("[kubic] phase %s: running", phase.Name)
+		if err := phase.Run(); err != nil {
+			return fmt.Errorf("phase %s failed: %v", phase.Name, err)
+		}
+		glog.V(1).Infof("[kubic] phase %s: completed", phase.Name)
+
+		if !completed.Has(phase.Name) {
+			completed.Insert(phase.Name)
+			state.Completed = append(state.Completed, phase.Name)
+		}
+		if err := r.saveState(state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadState loads the state file, returning an empty state when it does not exist
+func (r *Runner) loadState() (*State, error) {
+	state := &State{Completed: []string{}}
+	if len(r.StateFile) == 0 {
+		return state, nil
+	}
+
+	b, err := ioutil.ReadFile(r.StateFile)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read the bootstrap state from %s: %v", r.StateFile, err)
+	}
+
+	if err := yaml.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("could not parse the bootstrap state in %s: %v", r.StateFile, err)
+	}
+	glog.V(3).Infof("[kubic] bootstrap state loaded from %s: completed phases: %v", r.StateFile, state.Completed)
+	return state, nil
+}
+
+// saveState saves the state file (atomically)
+func (r *Runner) saveState(state *State) error {
+	if len(r.StateFile) == 0 {
+		return nil
+	}
+
+	state.LastUpdate = time.Now()
+	b, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.StateFile), 0700); err != nil {
+		return err
+	}
+	tmp := r.StateFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("could not save the bootstrap state: %v", err)
+	}
+	return os.Rename(tmp, r.StateFile)
+}
+
+// ClearState removes the state file, so all the phases will be run again
+func ClearState(stateFile string) error {
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
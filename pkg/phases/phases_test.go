@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package phases
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunnerResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubic-phases")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state", "bootstrap.yaml")
+
+	run := []string{}
+	failing := true
+	newRunner := func() *Runner {
+		phase := func(name string) Phase {
+			return Phase{Name: name, Run: func() error {
+				if name == "cni" && failing {
+					return fmt.Errorf("some error")
+				}
+				run = append(run, name)
+				return nil
+			}}
+		}
+		return NewRunner(stateFile, phase("kubeadm"), phase("upload-config"), phase("cni"), phase("assets"))
+	}
+
+	if err := newRunner().Run(); err == nil {
+		t.Fatalf("the failing phase did not make the run fail")
+	}
+	if strings.Join(run, ",") != "kubeadm,upload-config" {
+		t.Fatalf("unexpected phases run: %v", run)
+	}
+
+	// the second run must start from the failed phase
+	run = []string{}
+	failing = false
+	r := newRunner()
+	r.Skip = []string{"assets"}
+	if err := r.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(run, ",") != "cni" {
+		t.Fatalf("unexpected phases run when resuming: %v", run)
+	}
+
+	// phases explicitly requested are run again
+	run = []string{}
+	r = newRunner()
+	r.Only = []string{"upload-config", "assets"}
+	if err := r.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(run, ",") != "upload-config,assets" {
+		t.Fatalf("unexpected phases run with a list of phases: %v", run)
+	}
+
+	// after clearing the state, everything is run again
+	if err := ClearState(stateFile); err != nil {
+		t.Fatalf("could not clear the state: %v", err)
+	}
+	run = []string{}
+	if err := newRunner().Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(run) != 4 {
+		t.Fatalf("unexpected phases run after clearing the state: %v", run)
+	}
+
+	r = newRunner()
+	r.Skip = []string{"something"}
+	if err := r.Run(); err == nil {
+		t.Fatalf("unknown phase not detected")
+	}
+}
@@ -0,0 +1,330 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package reset
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/kubeadm"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+// suffix for the directories where the kept paths are moved while running "kubeadm reset"
+const keepSuffix = ".kubic-keep"
+
+// the directories cleaned by "kubeadm reset" (replaced in tests)
+var kubeadmDirs = []string{
+	"/etc/kubernetes",
+	"/etc/cni/net.d",
+	"/var/lib/kubelet",
+	"/var/lib/etcd",
+	"/var/lib/dockershim",
+	"/var/run/kubernetes",
+}
+
+// A Step is a named cleanup step, removing something from the host
+// when the node is reset
+type Step struct {
+	// Name is the name of the step (used in --keep)
+	Name string
+
+	// Description is a short description of what the step does (ie, "remove the CNI configuration files")
+	Description string
+
+	// Enabled returns true when the step applies to a configuration (ie, the CNI driver
+	// in use). The step is always run when nil.
+	Enabled func(*config.KubicInitConfiguration) bool
+
+	// Paths returns the files and directories removed by the step. When the step
+	// is kept, these paths are also preserved from "kubeadm reset".
+	Paths func(*config.KubicInitConfiguration) []string
+
+	// Run performs any other cleanup (ie, removing network interfaces). Optional.
+	Run func(*config.KubicInitConfiguration) error
+
+	// OptIn is true for the steps that are only run when requested (in `Options.Include`),
+	// as they could remove things not created by kubic-init
+	OptIn bool
+}
+
+// the registered steps, indexed by name
+var steps = map[string]Step{}
+
+// the "kubeadm reset" run before the cleanup steps (replaced in tests)
+var kubeadmReset = func(cfg *config.KubicInitConfiguration) error {
+	return kubeadm.NewReset(cfg)
+}
+
+// Register registers a cleanup step that will be run when resetting a node
+func Register(step Step) {
+	if _, found := steps[step.Name]; found {
+		panic(fmt.Sprintf("reset step %q registered twice", step.Name))
+	}
+	steps[step.Name] = step
+}
+
+// Names returns the (sorted) names of the registered steps
+func Names() []string {
+	names := []string{}
+	for name := range steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OptInNames returns the (sorted) names of the registered opt-in steps
+func OptInNames() []string {
+	names := []string{}
+	for _, name := range Names() {
+		if steps[name].OptIn {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Options are the options for resetting a node
+type Options struct {
+	// Keep is the list of steps that will not be run (and whose paths will be preserved)
+	Keep []string
+
+	// Include is the list of opt-in steps that will be run
+	Include []string
+
+	// DryRun is true when only the steps to perform should be printed
+	DryRun bool
+
+	// Out is the output for the dry-run mode and the progress messages
+	Out io.Writer
+}
+
+// pipeline is a reset in progress
+type pipeline struct {
+	Options
+
+	cfg *config.KubicInitConfiguration
+}
+
+// Run reverts the changes made to this host by kubic-init: "kubeadm reset" is run
+// and then all the cleanup steps that apply to the configuration, except for the
+// steps in `options.Keep` (and the opt-in steps not in `options.Include`). The paths of the kept steps are moved aside while running
+// "kubeadm reset", so they are not removed by kubeadm.
+// All the cleanup steps are run, even when some of them fail.
+func Run(cfg *config.KubicInitConfiguration, options Options) error {
+	registered := sets.NewString(Names()...)
+	for _, name := range append(append([]string{}, options.Keep...), options.Include...) {
+		if !registered.Has(name) {
+			return fmt.Errorf("unknown reset step %q: registered steps: %s", name, strings.Join(Names(), ", "))
+		}
+	}
+	for _, name := range options.Include {
+		if !steps[name].OptIn {
+			return fmt.Errorf("reset step %q is always run: it cannot be included", name)
+		}
+	}
+
+	p := pipeline{
+		Options: options,
+		cfg:     cfg,
+	}
+	return p.run()
+}
+
+// stepsToRun returns the steps that apply to the configuration (in order), split
+// in the steps to run and the steps to keep
+func (p *pipeline) stepsToRun() ([]Step, []Step) {
+	keep := sets.NewString(p.Keep...)
+	include := sets.NewString(p.Include...)
+	run, kept := []Step{}, []Step{}
+	for _, name := range Names() {
+		step := steps[name]
+		if step.Enabled != nil && !step.Enabled(p.cfg) {
+			continue
+		}
+		if step.OptIn && !include.Has(name) {
+			continue
+		}
+		if keep.Has(name) {
+			kept = append(kept, step)
+		} else {
+			run = append(run, step)
+		}
+	}
+	return run, kept
+}
+
+func (p *pipeline) run() error {
+	run, kept := p.stepsToRun()
+
+	if p.DryRun {
+		for _, step := range kept {
+			for _, path := range p.paths(step) {
+				fmt.Fprintf(p.Out, "[dry-run] would keep %s (%s)\n", path, step.Name)
+			}
+		}
+		fmt.Fprintf(p.Out, "[dry-run] would run \"kubeadm reset\"\n")
+		for _, step := range run {
+			fmt.Fprintf(p.Out, "[dry-run] would %s (%s)\n", step.Description, step.Name)
+			for _, path := range p.paths(step) {
+				fmt.Fprintf(p.Out, "[dry-run]   would remove %s\n", path)
+			}
+		}
+		return nil
+	}
+
+	preserved, err := p.preserve(kept)
+	if err != nil {
+		return err
+	}
+
+	p.printf("running \"kubeadm reset\"...")
+	err = kubeadmReset(p.cfg)
+	if restoreErr := p.restore(preserved); restoreErr != nil {
+		return restoreErr
+	}
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, step := range run {
+		p.printf("%s...", step.Description)
+		if err := p.runStep(step); err != nil {
+			errs = append(errs, fmt.Errorf("could not %s: %v", step.Description, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// paths returns the paths removed by a step
+func (p *pipeline) paths(step Step) []string {
+	if step.Paths == nil {
+		return nil
+	}
+	return step.Paths(p.cfg)
+}
+
+// runStep runs a cleanup step, removing its paths
+func (p *pipeline) runStep(step Step) error {
+	for _, path := range p.paths(step) {
+		glog.V(3).Infof("[kubic] removing %s", path)
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	if step.Run != nil {
+		return step.Run(p.cfg)
+	}
+	return nil
+}
+
+// keepPath returns the path where a kept `path` is moved while running "kubeadm reset".
+// Paths inside the directories cleaned by kubeadm are moved next to these directories
+// (ie, "/etc/cni/net.d/10-flannel.conflist" to "/etc/cni/.net.d.kubic-keep/10-flannel.conflist").
+func keepPath(path string) (string, string) {
+	top := path
+	for _, dir := range kubeadmDirs {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			top = dir
+			break
+		}
+	}
+	root := filepath.Join(filepath.Dir(top), "."+filepath.Base(top)+keepSuffix)
+	rel, _ := filepath.Rel(top, path)
+	return filepath.Join(root, rel), root
+}
+
+// preserve moves aside the (existing) paths of the kept steps, returning the paths moved
+func (p *pipeline) preserve(kept []Step) ([]string, error) {
+	preserved := []string{}
+	for _, step := range kept {
+		for _, path := range p.paths(step) {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				continue
+			}
+			p.printf("keeping %s", path)
+			target, _ := keepPath(path)
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				p.restore(preserved)
+				return nil, fmt.Errorf("could not preserve %s: %v", path, err)
+			}
+			if err := os.Rename(path, target); err != nil {
+				p.restore(preserved)
+				return nil, fmt.Errorf("could not preserve %s: %v", path, err)
+			}
+			preserved = append(preserved, path)
+		}
+	}
+	return preserved, nil
+}
+
+// restore moves back the paths preserved, replacing anything left by "kubeadm reset"
+func (p *pipeline) restore(preserved []string) error {
+	roots := sets.NewString()
+	for _, path := range preserved {
+		target, root := keepPath(path)
+		roots.Insert(root)
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("could not restore %s: %v", path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("could not restore %s: %v", path, err)
+		}
+		if err := os.Rename(target, path); err != nil {
+			return fmt.Errorf("could not restore %s (it has been left at %s): %v", path, target, err)
+		}
+	}
+	for _, root := range roots.List() {
+		if err := os.RemoveAll(root); err != nil {
+			glog.Warningf("[kubic] could not remove %s: %v", root, err)
+		}
+	}
+	return nil
+}
+
+func (p *pipeline) printf(format string, args ...interface{}) {
+	glog.V(1).Infof("[kubic] "+format, args...)
+	if p.Out != nil {
+		fmt.Fprintf(p.Out, "[reset] "+format+"\n", args...)
+	}
+}
+
+// DeleteInterfaces deletes some network interfaces (ignoring the interfaces that do not exist)
+func DeleteInterfaces(names ...string) error {
+	for _, name := range names {
+		if _, err := net.InterfaceByName(name); err != nil {
+			continue
+		}
+		if err := kubicutil.RunCommand("ip", "link", "delete", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
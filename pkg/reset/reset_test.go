@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package reset
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestRunKeep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubic-reset")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a directory cleaned by our fake "kubeadm reset"
+	kubeadmDir := filepath.Join(dir, "kubernetes")
+	confFile := filepath.Join(kubeadmDir, "conf", "some.conf")
+	dataDir := filepath.Join(dir, "data")
+	for _, path := range []string{filepath.Dir(confFile), dataDir} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("could not create %s: %v", path, err)
+		}
+	}
+	if err := ioutil.WriteFile(confFile, []byte("some config"), 0644); err != nil {
+		t.Fatalf("could not write %s: %v", confFile, err)
+	}
+
+	savedSteps, savedDirs, savedReset := steps, kubeadmDirs, kubeadmReset
+	defer func() { steps, kubeadmDirs, kubeadmReset = savedSteps, savedDirs, savedReset }()
+
+	steps = map[string]Step{}
+	kubeadmDirs = []string{kubeadmDir}
+	kubeadmReset = func(*config.KubicInitConfiguration) error {
+		return os.RemoveAll(kubeadmDir)
+	}
+
+	run := []string{}
+	step := func(name string, enabled bool, paths ...string) Step {
+		return Step{
+			Name:        name,
+			Description: "clean " + name,
+			Enabled:     func(*config.KubicInitConfiguration) bool { return enabled },
+			Paths:       func(*config.KubicInitConfiguration) []string { return paths },
+			Run: func(*config.KubicInitConfiguration) error {
+				run = append(run, name)
+				return nil
+			},
+		}
+	}
+	Register(step("conf", true, confFile))
+	Register(step("data", true, dataDir))
+	Register(step("other", false))
+	optIn := step("opt-in", true)
+	optIn.OptIn = true
+	Register(optIn)
+
+	cfg := &config.KubicInitConfiguration{}
+
+	if err := Run(cfg, Options{Keep: []string{"unknown"}}); err == nil {
+		t.Fatalf("unknown steps in --keep should be an error")
+	}
+
+	if err := Run(cfg, Options{Include: []string{"data"}}); err == nil {
+		t.Fatalf("including steps that are not opt-in should be an error")
+	}
+
+	out := &bytes.Buffer{}
+	if err := Run(cfg, Options{Keep: []string{"conf"}, DryRun: true, Out: out}); err != nil {
+		t.Fatalf("could not run in dry-run mode: %v", err)
+	}
+	t.Logf("dry-run output:\n%s", out.String())
+	if len(run) > 0 || !exists(confFile) || !exists(dataDir) || !strings.Contains(out.String(), "would keep "+confFile) {
+		t.Fatalf("unexpected dry-run: steps run: %v", run)
+	}
+
+	if err := Run(cfg, Options{Keep: []string{"conf"}, Out: out}); err != nil {
+		t.Fatalf("could not reset: %v", err)
+	}
+	if strings.Join(run, ",") != "data" {
+		t.Fatalf("unexpected steps run: %v", run)
+	}
+	if content, err := ioutil.ReadFile(confFile); err != nil || string(content) != "some config" {
+		t.Fatalf("%s has not been kept: %v", confFile, err)
+	}
+	if exists(dataDir) {
+		t.Fatalf("%s has not been removed", dataDir)
+	}
+	if _, root := keepPath(confFile); exists(root) {
+		t.Fatalf("%s has not been removed", root)
+	}
+
+	run = []string{}
+	if err := Run(cfg, Options{Keep: []string{"conf"}, Include: []string{"opt-in"}, Out: out}); err != nil {
+		t.Fatalf("could not reset: %v", err)
+	}
+	if strings.Join(run, ",") != "data,opt-in" {
+		t.Fatalf("unexpected steps run with the opt-in step included: %v", run)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package upgrade
+
+import (
+	"fmt"
+	"sort"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// the max number of minor versions a kubelet can be behind the control plane
+// (see https://kubernetes.io/docs/setup/version-skew-policy/)
+const maxKubeletSkew = 2
+
+// minorsBehind returns the number of minor versions `v` is behind `other`
+// (negative when `v` is newer). Both versions must have the same major version.
+func minorsBehind(v, other *utilversion.Version) int {
+	return int(other.Minor()) - int(v.Minor())
+}
+
+// checkVersionSkew checks an upgrade of the control plane to `target` follows
+// the version skew policy, given the current versions of the control plane
+// and of the kubelets (indexed by node name)
+func checkVersionSkew(controlPlane *utilversion.Version, kubelets map[string]*utilversion.Version, target *utilversion.Version) error {
+	errs := []error{}
+	switch {
+	case target.Major() != controlPlane.Major():
+		errs = append(errs, fmt.Errorf("upgrades between major versions (v%s to v%s) are not supported", controlPlane, target))
+	case !target.AtLeast(controlPlane):
+		errs = append(errs, fmt.Errorf("downgrades (v%s to v%s) are not supported", controlPlane, target))
+	case minorsBehind(controlPlane, target) > 1:
+		errs = append(errs, fmt.Errorf("the control plane can only be upgraded one minor version at a time: upgrade from v%s to v%d.%d first",
+			controlPlane, controlPlane.Major(), controlPlane.Minor()+1))
+	}
+
+	names := []string{}
+	for name := range kubelets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kubelet := kubelets[name]
+		switch {
+		case kubelet.Major() != target.Major() || minorsBehind(kubelet, target) > maxKubeletSkew:
+			errs = append(errs, fmt.Errorf("the kubelet in %s (v%s) is too old for a v%s control plane: upgrade it first", name, kubelet, target))
+		case !target.AtLeast(kubelet):
+			errs = append(errs, fmt.Errorf("the kubelet in %s (v%s) is newer than v%s", name, kubelet, target))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package upgrade
+
+import (
+	"testing"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestCheckVersionSkew(t *testing.T) {
+	v := func(s string) *utilversion.Version {
+		res, err := utilversion.ParseSemantic(s)
+		if err != nil {
+			t.Fatalf("could not parse %s: %v", s, err)
+		}
+		return res
+	}
+
+	tests := []struct {
+		controlPlane string
+		kubelets     map[string]string
+		target       string
+		valid        bool
+	}{
+		{"v1.12.2", map[string]string{"node-1": "v1.12.2"}, "v1.13.1", true},
+		{"v1.12.2", map[string]string{"node-1": "v1.12.2"}, "v1.12.5", true},
+		// resuming an upgrade, once the control plane has been upgraded
+		{"v1.13.1", map[string]string{"node-1": "v1.12.2", "node-2": "v1.13.1"}, "v1.13.1", true},
+		{"v1.12.2", map[string]string{}, "v1.11.0", false},
+		{"v1.12.2", map[string]string{}, "v1.14.0", false},
+		{"v1.12.2", map[string]string{}, "v2.0.0", false},
+		{"v1.12.2", map[string]string{"node-1": "v1.10.3"}, "v1.13.1", false},
+		{"v1.12.2", map[string]string{"node-1": "v1.14.0"}, "v1.13.1", false},
+		// pre-releases are compared by their numeric identifiers
+		{"v1.13.0-beta.2", map[string]string{"node-1": "v1.13.0-beta.2"}, "v1.13.0-beta.10", true},
+		{"v1.13.0-rc.1", map[string]string{}, "v1.13.0-beta.10", false},
+		{"v1.13.0-rc.1", map[string]string{}, "v1.13.0", true},
+	}
+
+	for _, test := range tests {
+		kubelets := map[string]*utilversion.Version{}
+		for name, version := range test.kubelets {
+			kubelets[name] = v(version)
+		}
+		err := checkVersionSkew(v(test.controlPlane), kubelets, v(test.target))
+		t.Logf("%s -> %s (kubelets %v): %v", test.controlPlane, test.target, test.kubelets, err)
+		if (err == nil) != test.valid {
+			t.Fatalf("unexpected result for %s -> %s", test.controlPlane, test.target)
+		}
+	}
+}
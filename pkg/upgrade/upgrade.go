@@ -0,0 +1,461 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package upgrade
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	clientset "k8s.io/client-go/kubernetes"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	kubiccluster "github.com/kubic-project/kubic-init/pkg/cluster"
+	"github.com/kubic-project/kubic-init/pkg/config"
+	"github.com/kubic-project/kubic-init/pkg/kubeadm"
+	"github.com/kubic-project/kubic-init/pkg/phases"
+	"github.com/kubic-project/kubic-init/pkg/reset"
+	kubicutil "github.com/kubic-project/kubic-init/pkg/util"
+)
+
+const (
+	// DefaultUpgradeTimeout is the default max time for each step of the upgrade
+	DefaultUpgradeTimeout = 10 * time.Minute
+
+	// the annotation for the nodes cordoned by the upgrade, so they are
+	// uncordoned once upgraded (even when the upgrade is resumed)
+	cordonedAnnotation = "kubic-init/upgrade-cordoned"
+
+	// the name of the Job used for upgrading the nodes
+	upgradeJobApp = "kubic-upgrade"
+)
+
+// the paths in the host used by "kubic-init upgrade node" (as in the kubic-init service)
+var upgradeNodeHostPaths = []string{
+	"/etc/kubic",
+	"/etc/kubernetes",
+	"/usr/bin/kubelet",
+	"/var/lib/kubelet",
+	"/var/lib/etcd",
+	"/var/run/dbus",
+	"/usr/lib/systemd",
+	"/run/systemd",
+	"/var/run/crio",
+}
+
+// the upgrade of the control plane in this node and the Job run in the other nodes (replaced in tests)
+var (
+	kubeadmUpgradeApply = kubeadm.UpgradeApply
+	runNodeJob          = kubiccluster.RunNodeJob
+)
+
+func init() {
+	// forget about any upgrade in progress when resetting a node
+	reset.Register(reset.Step{
+		Name:        "upgrade-state",
+		Description: "forget the upgrades progress",
+		Paths: func(cfg *config.KubicInitConfiguration) []string {
+			paths, _ := filepath.Glob(StateFile("*"))
+			return paths
+		},
+	})
+}
+
+// StateFile returns the file where the progress of an upgrade to `version` is saved
+func StateFile(version string) string {
+	return filepath.Join(filepath.Dir(config.DefaultKubicBootstrapStateFile), fmt.Sprintf("upgrade-%s.yaml", version))
+}
+
+// Options are the options for upgrading the cluster
+type Options struct {
+	// Version is the Kubernetes version to upgrade to (ie, "v1.13.1")
+	Version string
+
+	// Image is the image used for upgrading the nodes
+	Image string
+
+	// DryRun is true when only the steps to perform should be printed
+	DryRun bool
+
+	// Timeout is the max time for each step of the upgrade
+	Timeout time.Duration
+
+	// Out is the output for the dry-run mode and the progress messages
+	Out io.Writer
+}
+
+// nodeVersion is the version of the kubelet in a node
+type nodeVersion struct {
+	name    string
+	master  bool
+	kubelet *utilversion.Version
+}
+
+// clusterVersions are the versions of the control plane and the kubelets in the cluster
+type clusterVersions struct {
+	controlPlane *utilversion.Version
+	nodes        []nodeVersion
+}
+
+// getClusterVersions returns the current versions in the cluster (with the
+// masters before the workers, sorted by name)
+func getClusterVersions(client clientset.Interface) (*clusterVersions, error) {
+	info, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the version of the control plane: %v", err)
+	}
+	controlPlane, err := utilversion.ParseSemantic(info.GitVersion)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the version of the control plane: %v", err)
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &clusterVersions{controlPlane: controlPlane}
+	for _, node := range nodes.Items {
+		kubelet, err := utilversion.ParseSemantic(node.Status.NodeInfo.KubeletVersion)
+		if err != nil {
+			return nil, fmt.Errorf("could not get the kubelet version in %s: %v", node.Name, err)
+		}
+		_, isMaster := node.Labels[kubeadmconstants.LabelNodeRoleMaster]
+		res.nodes = append(res.nodes, nodeVersion{name: node.Name, master: isMaster, kubelet: kubelet})
+	}
+	sort.Slice(res.nodes, func(i, j int) bool {
+		if res.nodes[i].master != res.nodes[j].master {
+			return res.nodes[i].master
+		}
+		return res.nodes[i].name < res.nodes[j].name
+	})
+	return res, nil
+}
+
+// kubelets returns the versions of the kubelets, indexed by node name
+func (v *clusterVersions) kubelets() map[string]*utilversion.Version {
+	res := map[string]*utilversion.Version{}
+	for _, node := range v.nodes {
+		res[node.name] = node.kubelet
+	}
+	return res
+}
+
+// Plan prints the current versions in the cluster and checks an upgrade to
+// `version` (when provided) is possible, showing the versions available
+// with "kubeadm upgrade plan".
+func Plan(clients *kubicclient.Clients, version string, out io.Writer) error {
+	versions, err := getClusterVersions(clients.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Control plane: v%s\n\n", versions.controlPlane)
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "NODE\tROLE\tKUBELET")
+	for _, node := range versions.nodes {
+		role := "worker"
+		if node.master {
+			role = "master"
+		}
+		fmt.Fprintf(w, "%s\t%s\tv%s\n", node.name, role, node.kubelet)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+
+	if len(version) > 0 {
+		target, err := utilversion.ParseSemantic(version)
+		if err != nil {
+			return err
+		}
+		version = "v" + target.String()
+		if err := checkVersionSkew(versions.controlPlane, versions.kubelets(), target); err != nil {
+			return fmt.Errorf("the cluster cannot be upgraded to %s: %v", version, err)
+		}
+		fmt.Fprintf(out, "The cluster can be upgraded to %s with \"kubic-init upgrade apply --version=%s\" in the seeder.\n\n", version, version)
+	}
+
+	cfg, err := config.FromConfigMap(clients.Kubernetes, config.DefaultKubicInitConfigmap)
+	if err != nil {
+		return fmt.Errorf("could not read the cluster configuration: %v", err)
+	}
+	return kubeadm.UpgradePlan(cfg, version, out)
+}
+
+// upgrade is an upgrade in progress
+type upgrade struct {
+	Options
+
+	clients  *kubicclient.Clients
+	cfg      *config.KubicInitConfiguration
+	target   *utilversion.Version
+	versions *clusterVersions
+
+	// stateFile is the file where the progress is saved
+	stateFile string
+
+	// local is the name of this node (where the control plane is upgraded with "kubeadm upgrade apply")
+	local string
+}
+
+// Apply upgrades the cluster to a new Kubernetes version: the control plane is upgraded
+// in this node with "kubeadm upgrade apply" and then the other nodes are upgraded one by
+// one (cordon, drain, "kubeadm upgrade node" and kubelet restart with a Job, uncordon),
+// the masters before the workers.
+// The progress is saved, so an interrupted upgrade is resumed when run again.
+func Apply(clients *kubicclient.Clients, options Options) error {
+	if options.Timeout == 0 {
+		options.Timeout = DefaultUpgradeTimeout
+	}
+	if len(options.Image) == 0 {
+		options.Image = config.DefaultKubicInitImage
+	}
+
+	target, err := utilversion.ParseSemantic(options.Version)
+	if err != nil {
+		return err
+	}
+	options.Version = "v" + target.String()
+
+	versions, err := getClusterVersions(clients.Kubernetes)
+	if err != nil {
+		return err
+	}
+	if err := checkVersionSkew(versions.controlPlane, versions.kubelets(), target); err != nil {
+		return fmt.Errorf("the cluster cannot be upgraded to %s: %v", options.Version, err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	local := strings.ToLower(hostname)
+	isMaster := false
+	for _, node := range versions.nodes {
+		if node.name == local {
+			isMaster = node.master
+		}
+	}
+	if !isMaster {
+		return fmt.Errorf("the upgrade must be run in the seeder (or another control-plane node)")
+	}
+
+	cfg, err := config.FromConfigMap(clients.Kubernetes, config.DefaultKubicInitConfigmap)
+	if err != nil {
+		return fmt.Errorf("could not read the cluster configuration: %v", err)
+	}
+
+	u := upgrade{
+		Options:   options,
+		clients:   clients,
+		cfg:       cfg,
+		target:    target,
+		versions:  versions,
+		stateFile: StateFile(options.Version),
+		local:     local,
+	}
+	return u.run()
+}
+
+func (u *upgrade) run() error {
+	stateFile := u.stateFile
+	if u.DryRun {
+		stateFile = ""
+	}
+
+	upgradePhases := []phases.Phase{
+		{
+			Name: "control-plane",
+			Run: func() error {
+				return u.step(fmt.Sprintf("upgrade the control plane in %s to %s", u.local, u.Version), u.upgradeControlPlane)
+			},
+		},
+	}
+	for _, node := range u.versions.nodes {
+		if node.name == u.local {
+			continue
+		}
+		name, master := node.name, node.master
+		upgradePhases = append(upgradePhases, phases.Phase{
+			Name: "node-" + name,
+			Run: func() error {
+				return u.step(fmt.Sprintf("upgrade node %s (cordon, drain, upgrade, uncordon)", name), func() error {
+					return u.upgradeNode(name, master)
+				})
+			},
+		})
+	}
+
+	u.printf("upgrading from v%s to %s (%d nodes)", u.versions.controlPlane, u.Version, len(u.versions.nodes))
+	if err := phases.NewRunner(stateFile, upgradePhases...).Run(); err != nil {
+		return fmt.Errorf("upgrade interrupted (run it again for resuming it): %v", err)
+	}
+	u.printf("upgrade to %s finished", u.Version)
+	return nil
+}
+
+// step runs a step of the upgrade (or just prints it in dry-run mode)
+func (u *upgrade) step(descr string, f func() error) error {
+	if u.DryRun {
+		fmt.Fprintf(u.Out, "[dry-run] would %s\n", descr)
+		return nil
+	}
+
+	u.printf("%s...", descr)
+	if err := f(); err != nil {
+		return fmt.Errorf("could not %s: %v", descr, err)
+	}
+	return nil
+}
+
+func (u *upgrade) printf(format string, args ...interface{}) {
+	glog.V(1).Infof("[kubic] "+format, args...)
+	if u.Out != nil && !u.DryRun {
+		fmt.Fprintf(u.Out, "[upgrade] "+format+"\n", args...)
+	}
+}
+
+// upgradeControlPlane upgrades the control plane (and the kubelet configuration) in this node
+func (u *upgrade) upgradeControlPlane() error {
+	if err := kubeadmUpgradeApply(u.cfg, u.Version); err != nil {
+		return err
+	}
+	if err := restartKubelet(); err != nil {
+		return err
+	}
+	if err := kubiccluster.WaitForNodeReady(u.clients.Kubernetes, u.local, u.Timeout); err != nil {
+		return err
+	}
+	u.checkKubelet(u.local)
+	return nil
+}
+
+// upgradeNode upgrades a node with a Job running "kubic-init upgrade node" in the node,
+// draining it before. Nodes are left cordoned when something goes wrong.
+func (u *upgrade) upgradeNode(name string, master bool) error {
+	client := u.clients.Kubernetes
+	if err := u.cordon(name); err != nil {
+		return err
+	}
+
+	evicted, err := kubiccluster.DrainNode(client, name, u.Timeout)
+	if err != nil {
+		return fmt.Errorf("%v (node %s has been left cordoned)", err, name)
+	}
+	glog.V(3).Infof("[kubic] %d pods evicted from %s", evicted, name)
+
+	err = runNodeJob(client, kubiccluster.NodeJob{
+		App:   upgradeJobApp,
+		Node:  name,
+		Image: u.Image,
+		Command: []string{"kubic-init", "upgrade", "node",
+			"--version=" + u.Version,
+			"--config=" + config.DefaultKubicInitConfig,
+			fmt.Sprintf("--control-plane=%t", master),
+		},
+		HostPaths: upgradeNodeHostPaths,
+	}, u.Timeout)
+	if err != nil {
+		return fmt.Errorf("%v (node %s has been left cordoned)", err, name)
+	}
+
+	if err := kubiccluster.WaitForNodeReady(client, name, u.Timeout); err != nil {
+		return fmt.Errorf("%v (node %s has been left cordoned)", err, name)
+	}
+	u.checkKubelet(name)
+
+	return u.uncordon(name)
+}
+
+// cordon cordons a node, remembering (in an annotation) it must be uncordoned once upgraded.
+// Nodes that were already cordoned by the operator are left cordoned.
+func (u *upgrade) cordon(name string) error {
+	nodes := u.clients.Kubernetes.CoreV1().Nodes()
+	node, err := nodes.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if node.Spec.Unschedulable {
+		return nil
+	}
+
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[cordonedAnnotation] = "true"
+	node.Spec.Unschedulable = true
+	_, err = nodes.Update(node)
+	return err
+}
+
+// uncordon uncordons a node cordoned by the upgrade
+func (u *upgrade) uncordon(name string) error {
+	nodes := u.clients.Kubernetes.CoreV1().Nodes()
+	node, err := nodes.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, found := node.Annotations[cordonedAnnotation]; !found {
+		return nil
+	}
+
+	delete(node.Annotations, cordonedAnnotation)
+	node.Spec.Unschedulable = false
+	_, err = nodes.Update(node)
+	return err
+}
+
+// checkKubelet warns when the kubelet in a node is not running the new version
+// (the kubelet binary is not upgraded by kubeadm: it must be upgraded in the host)
+func (u *upgrade) checkKubelet(name string) {
+	node, err := u.clients.Kubernetes.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	if err != nil {
+		glog.Warningf("[kubic] could not get node %s: %v", name, err)
+		return
+	}
+	if cmp, err := u.target.Compare(node.Status.NodeInfo.KubeletVersion); err == nil && cmp != 0 {
+		u.printf("WARNING: the kubelet in %s is still %s: the kubelet package must be upgraded in the host", name, node.Status.NodeInfo.KubeletVersion)
+	}
+}
+
+// restartKubelet restarts the kubelet in this node, so it uses the new configuration (replaced in tests)
+var restartKubelet = func() error {
+	return kubicutil.RunCommand("systemctl", "restart", "kubelet")
+}
+
+// UpgradeLocalNode upgrades this node to `version` with "kubeadm upgrade node" (the
+// kubelet configuration and, in control-plane nodes, the control plane) and restarts the kubelet
+func UpgradeLocalNode(cfg *config.KubicInitConfiguration, version string, controlPlane bool) error {
+	target, err := utilversion.ParseSemantic(version)
+	if err != nil {
+		return err
+	}
+	if err := kubeadm.UpgradeNode(cfg, "v"+target.String(), controlPlane); err != nil {
+		return err
+	}
+	return restartKubelet()
+}
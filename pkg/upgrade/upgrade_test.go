@@ -0,0 +1,255 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package upgrade
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
+
+	kubicclient "github.com/kubic-project/kubic-init/pkg/client"
+	kubiccluster "github.com/kubic-project/kubic-init/pkg/cluster"
+	"github.com/kubic-project/kubic-init/pkg/config"
+)
+
+// newNode returns a ready node running a v1.12.2 kubelet
+func newNode(name string, master bool, unschedulable bool, annotations map[string]string) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{},
+			Annotations: annotations,
+		},
+		Spec: corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.12.2"},
+		},
+	}
+	if master {
+		node.Labels[kubeadmconstants.LabelNodeRoleMaster] = ""
+	}
+	return node
+}
+
+// newTestUpgrade returns an upgrade to v1.13.1 of the nodes in `client` (as getClusterVersions
+// would find them), run in "master-1"
+func newTestUpgrade(client *fake.Clientset, stateFile string) *upgrade {
+	versions := &clusterVersions{controlPlane: utilversion.MustParseSemantic("v1.12.2")}
+	nodes, _ := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	for _, node := range nodes.Items {
+		_, isMaster := node.Labels[kubeadmconstants.LabelNodeRoleMaster]
+		versions.nodes = append(versions.nodes, nodeVersion{
+			name:    node.Name,
+			master:  isMaster,
+			kubelet: utilversion.MustParseSemantic(node.Status.NodeInfo.KubeletVersion),
+		})
+	}
+	sort.Slice(versions.nodes, func(i, j int) bool {
+		if versions.nodes[i].master != versions.nodes[j].master {
+			return versions.nodes[i].master
+		}
+		return versions.nodes[i].name < versions.nodes[j].name
+	})
+
+	return &upgrade{
+		Options: Options{
+			Version: "v1.13.1",
+			Image:   "kubic-init:test",
+			Timeout: time.Minute,
+			Out:     ioutil.Discard,
+		},
+		clients:   &kubicclient.Clients{Kubernetes: client},
+		cfg:       &config.KubicInitConfiguration{},
+		target:    utilversion.MustParseSemantic("v1.13.1"),
+		versions:  versions,
+		stateFile: stateFile,
+		local:     "master-1",
+	}
+}
+
+// getNode returns a node, failing the test when it cannot be obtained
+func getNode(t *testing.T, client *fake.Clientset, name string) *corev1.Node {
+	node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get node %s: %v", name, err)
+	}
+	return node
+}
+
+// fakeNodeUpgrades replaces the commands run in the nodes, recording the upgrades
+// performed (and failing the Job in the nodes in `failing`)
+func fakeNodeUpgrades(upgraded *[]string, failing map[string]bool) func() {
+	savedApply, savedJob, savedRestart := kubeadmUpgradeApply, runNodeJob, restartKubelet
+	kubeadmUpgradeApply = func(cfg *config.KubicInitConfiguration, version string, args ...string) error {
+		*upgraded = append(*upgraded, "control-plane")
+		return nil
+	}
+	runNodeJob = func(client clientset.Interface, j kubiccluster.NodeJob, timeout time.Duration) error {
+		if failing[j.Node] {
+			return fmt.Errorf("the %s job failed in %s", j.App, j.Node)
+		}
+		*upgraded = append(*upgraded, fmt.Sprintf("%s %s", j.Node, strings.Join(j.Command[3:], " ")))
+		return nil
+	}
+	restartKubelet = func() error {
+		return nil
+	}
+	return func() { kubeadmUpgradeApply, runNodeJob, restartKubelet = savedApply, savedJob, savedRestart }
+}
+
+func TestCordon(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNode("worker-1", false, false, nil),
+		// cordoned by the operator before the upgrade
+		newNode("worker-2", false, true, nil),
+		// cordoned by an upgrade that was interrupted
+		newNode("worker-3", false, true, map[string]string{cordonedAnnotation: "true"}),
+	)
+	u := newTestUpgrade(client, "")
+
+	for _, name := range []string{"worker-1", "worker-2", "worker-3"} {
+		if err := u.cordon(name); err != nil {
+			t.Fatalf("could not cordon %s: %v", name, err)
+		}
+		if node := getNode(t, client, name); !node.Spec.Unschedulable {
+			t.Fatalf("%s has not been cordoned", name)
+		}
+	}
+	if _, found := getNode(t, client, "worker-1").Annotations[cordonedAnnotation]; !found {
+		t.Fatalf("worker-1 has not been annotated as cordoned by the upgrade")
+	}
+	if _, found := getNode(t, client, "worker-2").Annotations[cordonedAnnotation]; found {
+		t.Fatalf("worker-2 has been annotated as cordoned by the upgrade, but it was cordoned by the operator")
+	}
+
+	for _, name := range []string{"worker-1", "worker-2", "worker-3"} {
+		if err := u.uncordon(name); err != nil {
+			t.Fatalf("could not uncordon %s: %v", name, err)
+		}
+	}
+	for name, unschedulable := range map[string]bool{"worker-1": false, "worker-2": true, "worker-3": false} {
+		node := getNode(t, client, name)
+		if node.Spec.Unschedulable != unschedulable {
+			t.Fatalf("unexpected unschedulable flag in %s: %t", name, node.Spec.Unschedulable)
+		}
+		if _, found := node.Annotations[cordonedAnnotation]; found {
+			t.Fatalf("the %s annotation has not been removed from %s", cordonedAnnotation, name)
+		}
+	}
+}
+
+func TestUpgradeNode(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNode("master-2", true, false, nil),
+		newNode("worker-1", false, false, nil),
+		newNode("worker-2", false, true, nil),
+	)
+	u := newTestUpgrade(client, "")
+
+	upgraded := []string{}
+	defer fakeNodeUpgrades(&upgraded, map[string]bool{"worker-1": true})()
+
+	if err := u.upgradeNode("master-2", true); err != nil {
+		t.Fatalf("could not upgrade master-2: %v", err)
+	}
+	if strings.Join(upgraded, ",") != "master-2 --version=v1.13.1 --config="+config.DefaultKubicInitConfig+" --control-plane=true" {
+		t.Fatalf("unexpected upgrades: %v", upgraded)
+	}
+	if node := getNode(t, client, "master-2"); node.Spec.Unschedulable {
+		t.Fatalf("master-2 has not been uncordoned after the upgrade")
+	}
+
+	// nodes are left cordoned when the upgrade fails
+	if err := u.upgradeNode("worker-1", false); err == nil || !strings.Contains(err.Error(), "left cordoned") {
+		t.Fatalf("unexpected error when the upgrade job fails: %v", err)
+	}
+	if node := getNode(t, client, "worker-1"); !node.Spec.Unschedulable {
+		t.Fatalf("worker-1 has been uncordoned after a failed upgrade")
+	}
+
+	// nodes cordoned by the operator are left cordoned
+	if err := u.upgradeNode("worker-2", false); err != nil {
+		t.Fatalf("could not upgrade worker-2: %v", err)
+	}
+	if node := getNode(t, client, "worker-2"); !node.Spec.Unschedulable {
+		t.Fatalf("worker-2 has been uncordoned, but it was cordoned by the operator")
+	}
+}
+
+func TestRunResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubic-upgrade")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state", "upgrade-v1.13.1.yaml")
+
+	client := fake.NewSimpleClientset(
+		newNode("master-1", true, false, nil),
+		newNode("master-2", true, false, nil),
+		newNode("worker-1", false, false, nil),
+	)
+
+	upgraded := []string{}
+	restore := fakeNodeUpgrades(&upgraded, map[string]bool{"worker-1": true})
+	defer func() { restore() }()
+
+	if err := newTestUpgrade(client, stateFile).run(); err == nil {
+		t.Fatalf("the failed upgrade of worker-1 did not stop the upgrade")
+	}
+	expected := []string{
+		"control-plane",
+		"master-2 --version=v1.13.1 --config=" + config.DefaultKubicInitConfig + " --control-plane=true",
+	}
+	if strings.Join(upgraded, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected upgrades: %v", upgraded)
+	}
+	if node := getNode(t, client, "worker-1"); !node.Spec.Unschedulable {
+		t.Fatalf("worker-1 has been uncordoned after a failed upgrade")
+	}
+
+	// the upgrade is resumed from the node that failed
+	restore()
+	upgraded = []string{}
+	restore = fakeNodeUpgrades(&upgraded, nil)
+	if err := newTestUpgrade(client, stateFile).run(); err != nil {
+		t.Fatalf("could not resume the upgrade: %v", err)
+	}
+	expected = []string{
+		"worker-1 --version=v1.13.1 --config=" + config.DefaultKubicInitConfig + " --control-plane=false",
+	}
+	if strings.Join(upgraded, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected upgrades when resuming: %v", upgraded)
+	}
+	if node := getNode(t, client, "worker-1"); node.Spec.Unschedulable {
+		t.Fatalf("worker-1 has not been uncordoned after the upgrade")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Address families that can be preferred when getting the IP address of an interface
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// netInterface is the information we need about a network interface
+type netInterface struct {
+	name  string
+	up    bool
+	addrs []net.Addr
+}
+
+// GetInterfaceIP returns the primary IP address of the interface with name `pattern`,
+// where `pattern` can be a glob (ie, "eth*" or "en*"). When it matches more than one
+// interface, the first one (in alphabetical order) that is up and has an address is used.
+// Addresses in the `family` (FamilyIPv4 or FamilyIPv6) are preferred, but an address
+// in the other family is returned when the interface has no address in that family.
+func GetInterfaceIP(pattern string, family string) (net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []netInterface{}
+	for _, iface := range ifaces {
+		// only the interfaces matching the pattern must have their addresses
+		if ok, _ := filepath.Match(pattern, iface.Name); !ok {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("could not get the addresses of %s: %v", iface.Name, err)
+		}
+		candidates = append(candidates, netInterface{
+			name:  iface.Name,
+			up:    iface.Flags&net.FlagUp != 0,
+			addrs: addrs,
+		})
+	}
+
+	return chooseInterfaceIP(candidates, pattern, family)
+}
+
+// chooseInterfaceIP chooses an IP address from the interfaces that match `pattern`
+func chooseInterfaceIP(ifaces []netInterface, pattern string, family string) (net.IP, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid interface pattern %q: %v", pattern, err)
+	}
+
+	matched := []netInterface{}
+	for _, iface := range ifaces {
+		if ok, _ := filepath.Match(pattern, iface.name); ok {
+			matched = append(matched, iface)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no network interface matches %q", pattern)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].name < matched[j].name })
+
+	errs := []string{}
+	for _, iface := range matched {
+		if !iface.up {
+			errs = append(errs, fmt.Sprintf("interface %s is down", iface.name))
+			continue
+		}
+
+		ip := choosePrimaryIP(iface.addrs, family)
+		if ip == nil {
+			errs = append(errs, fmt.Sprintf("interface %s has no usable IP address", iface.name))
+			continue
+		}
+		return ip, nil
+	}
+
+	return nil, fmt.Errorf("could not get an IP address for %q: %s", pattern, strings.Join(errs, ", "))
+}
+
+// choosePrimaryIP returns the first global unicast address, preferring the
+// addresses in `family`. It returns nil when there is no usable address.
+func choosePrimaryIP(addrs []net.Addr, family string) net.IP {
+	var fallback net.IP
+	for _, addr := range addrs {
+		var ip net.IP
+		switch v := addr.(type) {
+		case *net.IPNet:
+			ip = v.IP
+		case *net.IPAddr:
+			ip = v.IP
+		}
+		if ip == nil || !ip.IsGlobalUnicast() {
+			continue
+		}
+
+		isIPv6 := ip.To4() == nil
+		if isIPv6 == (family == FamilyIPv6) {
+			return ip
+		}
+		if fallback == nil {
+			fallback = ip
+		}
+	}
+	return fallback
+}
+
+// GetIPFamily returns the family (FamilyIPv4 or FamilyIPv6) of an IP address
+func GetIPFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return FamilyIPv4
+	}
+	return FamilyIPv6
+}
+
+// GetCIDRFamily returns the family (FamilyIPv4 or FamilyIPv6) of a CIDR (ie, "10.0.0.0/8")
+func GetCIDRFamily(cidr string) (string, error) {
+	ip, _, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+	return GetIPFamily(ip), nil
+}
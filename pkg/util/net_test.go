@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package util
+
+import (
+	"net"
+	"testing"
+)
+
+func TestChooseInterfaceIP(t *testing.T) {
+	addrs := func(cidrs ...string) []net.Addr {
+		res := []net.Addr{}
+		for _, cidr := range cidrs {
+			ip, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				t.Fatalf("invalid CIDR %s: %v", cidr, err)
+			}
+			ipNet.IP = ip
+			res = append(res, ipNet)
+		}
+		return res
+	}
+
+	ifaces := []netInterface{
+		{name: "lo", up: true, addrs: addrs("127.0.0.1/8", "::1/128")},
+		{name: "eth1", up: true, addrs: addrs("fe80::1/64", "2001:db8::10/64", "10.0.0.10/24")},
+		{name: "eth0", up: false, addrs: addrs("192.168.1.10/24")},
+		{name: "ens3", up: true, addrs: addrs("fe80::2/64")},
+	}
+
+	tests := []struct {
+		pattern  string
+		family   string
+		expected string
+	}{
+		{"eth*", FamilyIPv4, "10.0.0.10"},
+		{"eth*", FamilyIPv6, "2001:db8::10"},
+		{"eth1", "", "10.0.0.10"},
+		{"eth0", FamilyIPv4, ""},
+		{"en*", FamilyIPv4, ""},
+		{"lo", FamilyIPv4, ""},
+		{"wlan0", FamilyIPv4, ""},
+		{"eth[", FamilyIPv4, ""},
+	}
+
+	for _, test := range tests {
+		ip, err := chooseInterfaceIP(ifaces, test.pattern, test.family)
+		if len(test.expected) == 0 {
+			if err == nil {
+				t.Fatalf("%s/%s: expected an error, got %s", test.pattern, test.family, ip)
+			}
+			t.Logf("%s/%s: got the expected error: %v", test.pattern, test.family, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s/%s: unexpected error: %v", test.pattern, test.family, err)
+		}
+		if !ip.Equal(net.ParseIP(test.expected)) {
+			t.Fatalf("%s/%s: expected %s, got %s", test.pattern, test.family, test.expected, ip)
+		}
+	}
+}
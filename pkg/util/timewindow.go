@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package util
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// TimeWindow is a period of time in some days of the week
+type TimeWindow struct {
+	days [7]bool
+
+	// start and end of the window, in minutes since midnight
+	start int
+	end   int
+}
+
+// ParseTimeWindow parses a time window like "Mon-Fri 08:00-18:00", "Sat,Sun 10:00-14:00"
+// or "22:00-06:00" (every day). Windows ending before they start cross midnight.
+func ParseTimeWindow(s string) (*TimeWindow, error) {
+	w := &TimeWindow{}
+
+	fields := strings.Fields(s)
+	var hours string
+	switch len(fields) {
+	case 1:
+		hours = fields[0]
+		for i := range w.days {
+			w.days[i] = true
+		}
+	case 2:
+		hours = fields[1]
+		if err := w.parseDays(fields[0]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid time window %q: must be like \"Mon-Fri 08:00-18:00\"", s)
+	}
+
+	startEnd := strings.Split(hours, "-")
+	if len(startEnd) != 2 {
+		return nil, fmt.Errorf("invalid hours %q: must be like \"08:00-18:00\"", hours)
+	}
+	var err error
+	if w.start, err = parseMinutes(startEnd[0]); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseMinutes(startEnd[1]); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// parseDays parses a list of days (or ranges of days), like "Mon-Fri" or "Mon,Wed,Fri"
+func (w *TimeWindow) parseDays(s string) error {
+	for _, r := range strings.Split(s, ",") {
+		firstLast := strings.Split(r, "-")
+		if len(firstLast) > 2 {
+			return fmt.Errorf("invalid days %q", r)
+		}
+		first, found := weekdays[strings.ToLower(firstLast[0])]
+		if !found {
+			return fmt.Errorf("invalid day %q", firstLast[0])
+		}
+		last := first
+		if len(firstLast) == 2 {
+			if last, found = weekdays[strings.ToLower(firstLast[1])]; !found {
+				return fmt.Errorf("invalid day %q", firstLast[1])
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseMinutes parses a "HH:MM" time, returning the minutes since midnight
+func parseMinutes(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: must be like \"08:00\"", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains returns true if `t` is in the time window
+func (w *TimeWindow) Contains(t time.Time) bool {
+	minutes := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return w.days[t.Weekday()] && minutes >= w.start && minutes < w.end
+	}
+
+	// the window crosses midnight: the part after midnight belongs to the previous day
+	if minutes >= w.start {
+		return w.days[t.Weekday()]
+	}
+	return minutes < w.end && w.days[(t.Weekday()+6)%7]
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 SUSE LINUX GmbH, Nuernberg, Germany..
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	// 2018-11-19 is a Monday
+	at := func(day int, hour int, min int) time.Time {
+		return time.Date(2018, 11, 19+day, hour, min, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		window   string
+		t        time.Time
+		expected bool
+	}{
+		{"Mon-Fri 08:00-18:00", at(0, 9, 30), true},
+		{"Mon-Fri 08:00-18:00", at(0, 18, 0), false},
+		{"Mon-Fri 08:00-18:00", at(5, 9, 30), false},
+		{"Sat,Sun 10:00-14:00", at(6, 10, 0), true},
+		{"Fri-Mon 10:00-14:00", at(0, 11, 0), true},
+		{"Fri-Mon 10:00-14:00", at(1, 11, 0), false},
+		{"22:00-06:00", at(2, 23, 0), true},
+		{"22:00-06:00", at(2, 5, 59), true},
+		{"22:00-06:00", at(2, 12, 0), false},
+		// Saturday night belongs to the Friday window
+		{"Fri 22:00-06:00", at(5, 2, 0), true},
+		{"Fri 22:00-06:00", at(4, 2, 0), false},
+	}
+
+	for _, test := range tests {
+		w, err := ParseTimeWindow(test.window)
+		if err != nil {
+			t.Fatalf("could not parse %q: %s", test.window, err)
+		}
+		if res := w.Contains(test.t); res != test.expected {
+			t.Fatalf("%q contains %s: expected %t, got %t", test.window, test.t, test.expected, res)
+		}
+	}
+
+	for _, invalid := range []string{"", "Mon-Fri", "Mon-Fri 08:00", "Someday 08:00-10:00", "8-10", "Mon 08:00-25:00"} {
+		if _, err := ParseTimeWindow(invalid); err == nil {
+			t.Fatalf("no error when parsing %q", invalid)
+		}
+	}
+}